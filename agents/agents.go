@@ -1,8 +1,11 @@
 package agents
 
 import (
+	"context"
+
 	"github.com/jlrosende/go-agents/llm/providers"
 	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/tools"
 	"google.golang.org/grpc"
 
 	mcp_tool "github.com/mark3labs/mcp-go/mcp"
@@ -10,6 +13,9 @@ import (
 	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
 )
 
+// Version of go-agents, reported to the clients of the agents
+var Version = "0.0.2"
+
 type Protocol string
 
 const (
@@ -21,15 +27,22 @@ type Agent interface {
 	Initialize() error
 	AttachLLM(llm providers.LLM)
 	AttachMCPServers(servers map[string]*mcp.MCPServer)
-	Send(message string) (string, error)
-	Generate(message string) ([]mcp_tool.Content, error)
-	Structured(message string, responseStruct any) ([]mcp_tool.Content, error)
+	AddTools(toolset ...tools.Tool)
+	Send(ctx context.Context, message string) (string, error)
+	Generate(ctx context.Context, message string) ([]mcp_tool.Content, error)
+	Structured(ctx context.Context, message string, responseStruct any) ([]mcp_tool.Content, error)
 	GetName() string
+	GetDescription() string
+	GetInputSchema() map[string]any
 	GetModel() string
+	GetServers() []string
 	GetInstructions() string
 	GetRequestParams() *providers.RequestParams
 	Start() error
 	SetProtocol(protocol Protocol)
 	GetClient() pb.A2AServiceClient
 	GetServer() *grpc.Server
+	// Authorize applies the policy of the agent to the principal of ctx, for
+	// the calls in process that skip its server.
+	Authorize(ctx context.Context) (context.Context, error)
 }
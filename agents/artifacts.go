@@ -0,0 +1,24 @@
+package agents
+
+import (
+	"context"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+type artifactKey struct{}
+
+// ArtifactSink receives the calls of the tools of a task, turning their
+// results and the files they write into artifacts of the task.
+type ArtifactSink func(ctx context.Context, tool mcp_tool.Tool, path string, args map[string]any, result *mcp_tool.CallToolResult)
+
+// WithArtifactSink sends the results of the tools called with ctx to sink,
+// nil stops sending them.
+func WithArtifactSink(ctx context.Context, sink ArtifactSink) context.Context {
+	return context.WithValue(ctx, artifactKey{}, sink)
+}
+
+func ArtifactSinkFromContext(ctx context.Context) (ArtifactSink, bool) {
+	sink, ok := ctx.Value(artifactKey{}).(ArtifactSink)
+	return sink, ok && sink != nil
+}
@@ -0,0 +1,199 @@
+// Package auth authenticates the A2A requests with the security schemes
+// declared by the card of an agent, authorizes them with its policy and
+// authenticates the calls of the clients of the agents.
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+)
+
+var (
+	// ErrNoCredentials is returned by the schemes when the request has none
+	// of their credentials, so other schemes can be tried.
+	ErrNoCredentials    = errors.New("no credentials")
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// ANY matches every principal in the rules of a policy.
+const ANY = "*"
+
+// Principal is the identity of the caller of a request.
+type Principal struct {
+	Name   string
+	Scheme string
+	// Patterns of the skills the principal may use, every skill when nil
+	Skills []string
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of the request, nil when the agent
+// does not authenticate its requests.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Allowed reports whether the principal may use the skill, skills are the
+// paths of the tools, server/tool. Requests without principal use every one.
+func (p *Principal) Allowed(skill string) bool {
+	if p == nil || p.Skills == nil {
+		return true
+	}
+
+	return slices.ContainsFunc(p.Skills, func(pattern string) bool {
+		ok, _ := path.Match(pattern, skill)
+		return ok
+	})
+}
+
+// Credentials of a request, the headers or gRPC metadata and the verified
+// certificate chain of the client.
+type Credentials struct {
+	Header       http.Header
+	Certificates []*x509.Certificate
+}
+
+// Authenticator is a security scheme.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credentials *Credentials) (*Principal, error)
+}
+
+// Rule allows principals to use skills, every skill when Skills is empty.
+type Rule struct {
+	Principals []string
+	Skills     []string
+}
+
+// Authorizer authenticates the requests with Schemes, by name. A request
+// must pass every scheme of one of the alternatives of Security, without
+// them the requests are anonymous, unless there are Schemes, then every
+// request fails. Without Policy every principal may use every skill.
+type Authorizer struct {
+	Schemes  map[string]Authenticator
+	Security [][]string
+	Policy   []Rule
+}
+
+// Authorize returns the principal of the request with the skills it may use.
+func (a *Authorizer) Authorize(ctx context.Context, credentials *Credentials) (*Principal, error) {
+	principal, err := a.authenticate(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Permit(principal)
+}
+
+// Permit returns a copy of principal with the skills the policy allows it to
+// use, also for the calls in process that skip the authentication.
+func (a *Authorizer) Permit(principal *Principal) (*Principal, error) {
+	if principal == nil {
+		principal = &Principal{}
+	}
+
+	principal = &Principal{Name: principal.Name, Scheme: principal.Scheme}
+
+	if len(a.Policy) == 0 {
+		return principal, nil
+	}
+
+	matched := false
+	skills := []string{}
+
+	for _, rule := range a.Policy {
+		if !slices.Contains(rule.Principals, ANY) && !slices.Contains(rule.Principals, principal.Name) {
+			continue
+		}
+
+		matched = true
+
+		if len(rule.Skills) == 0 {
+			skills = nil
+			break
+		}
+
+		skills = append(skills, rule.Skills...)
+	}
+
+	if !matched {
+		return nil, fmt.Errorf("%w, %s can not call the agent", ErrPermissionDenied, principal)
+	}
+
+	principal.Skills = skills
+
+	return principal, nil
+}
+
+func (a *Authorizer) authenticate(ctx context.Context, credentials *Credentials) (*Principal, error) {
+	if len(a.Security) == 0 {
+		// Schemes without requirements are a mistake, never open
+		if len(a.Schemes) > 0 {
+			return nil, fmt.Errorf("%w, no security requirements for the schemes", ErrUnauthenticated)
+		}
+
+		return &Principal{}, nil
+	}
+
+	errs := []error{}
+
+	for _, requirement := range a.Security {
+		principal, err := a.requirement(ctx, credentials, requirement)
+		if err == nil {
+			return principal, nil
+		}
+
+		if !errors.Is(err, ErrNoCredentials) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w, missing credentials", ErrUnauthenticated)
+	}
+
+	return nil, fmt.Errorf("%w, %w", ErrUnauthenticated, errors.Join(errs...))
+}
+
+// requirement authenticates the request with every scheme of requirement,
+// the principal is the one of the first scheme.
+func (a *Authorizer) requirement(ctx context.Context, credentials *Credentials, requirement []string) (*Principal, error) {
+	var principal *Principal
+
+	for _, name := range requirement {
+		scheme, ok := a.Schemes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown security scheme %s", name)
+		}
+
+		authenticated, err := scheme.Authenticate(ctx, credentials)
+		if err != nil {
+			return nil, fmt.Errorf("scheme %s, %w", name, err)
+		}
+
+		if principal == nil {
+			principal = authenticated
+		}
+	}
+
+	return principal, nil
+}
+
+func (p *Principal) String() string {
+	if p == nil || p.Name == "" {
+		return "anonymous"
+	}
+
+	return fmt.Sprintf("%s (%s)", p.Name, p.Scheme)
+}
@@ -0,0 +1,162 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jlrosende/go-agents/agents/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signer(t *testing.T) (*auth.JWKS, func(subject string, expires time.Time) string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	jwks, err := auth.ParseJWKS(fmt.Appendf(nil,
+		`{"keys":[{"kid":"k1","kty":"EC","crv":"P-256","x":"%s","y":"%s"}]}`,
+		encode(key.X.FillBytes(make([]byte, 32))), encode(key.Y.FillBytes(make([]byte, 32))),
+	))
+	require.NoError(t, err)
+
+	return jwks, func(subject string, expires time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    "issuer",
+			ExpiresAt: jwt.NewNumericDate(expires),
+		})
+		token.Header["kid"] = "k1"
+
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+
+		return signed
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	jwks, sign := signer(t)
+
+	authorizer := &auth.Authorizer{
+		Schemes: map[string]auth.Authenticator{
+			"key":    &auth.APIKey{Name: "X-API-Key", Keys: map[string]string{"secret": "ci"}},
+			"bearer": &auth.JWT{Keys: jwks, Issuer: "issuer"},
+		},
+		Security: [][]string{{"bearer"}, {"key"}},
+		Policy: []auth.Rule{
+			{Principals: []string{"alice"}},
+			{Principals: []string{"ci"}, Skills: []string{"filesystem/read_*"}},
+		},
+	}
+
+	authorize := func(header http.Header) (*auth.Principal, error) {
+		return authorizer.Authorize(context.Background(), &auth.Credentials{Header: header})
+	}
+
+	t.Run("jwt", func(t *testing.T) {
+		principal, err := authorize(http.Header{"Authorization": {"Bearer " + sign("alice", time.Now().Add(time.Hour))}})
+		require.NoError(t, err)
+
+		assert.Equal(t, "alice", principal.Name)
+		assert.True(t, principal.Allowed("memory/create_entities"))
+
+		_, err = authorize(http.Header{"Authorization": {"Bearer " + sign("alice", time.Now().Add(-time.Hour))}})
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	})
+
+	t.Run("api key with skills", func(t *testing.T) {
+		principal, err := authorize(http.Header{"X-Api-Key": {"secret"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, "ci", principal.Name)
+		assert.True(t, principal.Allowed("filesystem/read_file"))
+		assert.False(t, principal.Allowed("filesystem/write_file"))
+
+		_, err = authorize(http.Header{"X-Api-Key": {"wrong"}})
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		_, err := authorize(http.Header{"Authorization": {"Bearer " + sign("mallory", time.Now().Add(time.Hour))}})
+		assert.ErrorIs(t, err, auth.ErrPermissionDenied)
+
+		_, err = authorize(http.Header{})
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	})
+
+	t.Run("http middleware", func(t *testing.T) {
+		server := httptest.NewServer(auth.Middleware(authorizer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, auth.FromContext(r.Context()))
+		})))
+		defer server.Close()
+
+		for header, code := range map[string]int{
+			"":       http.StatusUnauthorized,
+			"secret": http.StatusOK,
+		} {
+			request, err := http.NewRequest(http.MethodPost, server.URL, nil)
+			require.NoError(t, err)
+
+			request.Header.Set(auth.DEFAULT_API_KEY_HEADER, header)
+
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			response.Body.Close()
+
+			assert.Equal(t, code, response.StatusCode, header)
+		}
+
+		response, err := http.Get(server.URL + "/.well-known/agent-card.json")
+		require.NoError(t, err)
+		response.Body.Close()
+
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	})
+}
+
+func TestPermit(t *testing.T) {
+	authorizer := &auth.Authorizer{
+		Policy: []auth.Rule{{Principals: []string{"ci"}, Skills: []string{"memory/*"}}},
+	}
+
+	caller := &auth.Principal{Name: "ci", Skills: []string{"filesystem/*"}}
+
+	principal, err := authorizer.Permit(caller)
+	require.NoError(t, err)
+
+	assert.True(t, principal.Allowed("memory/read_graph"))
+	assert.False(t, principal.Allowed("filesystem/read_file"))
+	assert.Equal(t, []string{"filesystem/*"}, caller.Skills, "the principal of the caller is kept")
+
+	_, err = authorizer.Permit(&auth.Principal{Name: "mallory"})
+	assert.ErrorIs(t, err, auth.ErrPermissionDenied)
+
+	// Schemes without security requirements fail closed
+	closed := &auth.Authorizer{Schemes: map[string]auth.Authenticator{"key": &auth.APIKey{Name: "X-API-Key", Keys: map[string]string{"secret": "ci"}}}}
+
+	_, err = closed.Authorize(context.Background(), &auth.Credentials{Header: http.Header{"X-Api-Key": {"secret"}}})
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+}
+
+func TestClientCredentials(t *testing.T) {
+	md, err := (&auth.ClientCredentials{APIKey: "secret", Token: "token"}).GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"x-api-key": "secret", "authorization": "Bearer token"}, md)
+
+	assert.True(t, (&auth.ClientCredentials{}).RequireTransportSecurity())
+	assert.False(t, (&auth.ClientCredentials{}).Local().RequireTransportSecurity())
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// DEFAULT_API_KEY_HEADER is the header of the API key of the clients
+// without Header.
+const DEFAULT_API_KEY_HEADER = "X-API-Key"
+
+// ClientCredentials authenticate the calls of the clients of an agent with
+// an API key, a bearer token or both. TokenFile is read on every call, so
+// rotated tokens are used without restarts.
+type ClientCredentials struct {
+	Header    string
+	APIKey    string
+	Token     string
+	TokenFile string
+}
+
+var _ credentials.PerRPCCredentials = (*ClientCredentials)(nil)
+
+func (c *ClientCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	md := map[string]string{}
+
+	if c.APIKey != "" {
+		header := c.Header
+		if header == "" {
+			header = DEFAULT_API_KEY_HEADER
+		}
+
+		md[strings.ToLower(header)] = c.APIKey
+	}
+
+	token := c.Token
+
+	if c.TokenFile != "" {
+		raw, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("error read token file %s, %w", c.TokenFile, err)
+		}
+
+		token = strings.TrimSpace(string(raw))
+	}
+
+	if token != "" {
+		md["authorization"] = "Bearer " + token
+	}
+
+	return md, nil
+}
+
+// RequireTransportSecurity is true, the credentials are only sent over TLS,
+// see Local.
+func (c *ClientCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// Local returns the credentials sent without TLS, for the unix sockets that
+// never leave the machine.
+func (c *ClientCredentials) Local() credentials.PerRPCCredentials {
+	return localCredentials{c}
+}
+
+type localCredentials struct {
+	*ClientCredentials
+}
+
+func (c localCredentials) RequireTransportSecurity() bool {
+	return false
+}
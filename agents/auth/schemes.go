@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Names of the schemes in the principals
+const (
+	SCHEME_API_KEY    = "apiKey"
+	SCHEME_BEARER     = "bearer"
+	SCHEME_MUTUAL_TLS = "mutualTLS"
+)
+
+// APIKey authenticates the requests with a key in the header Name, Keys
+// maps the keys to their principals.
+type APIKey struct {
+	Name string
+	Keys map[string]string
+}
+
+func (s *APIKey) Authenticate(ctx context.Context, credentials *Credentials) (*Principal, error) {
+	key := credentials.Header.Get(s.Name)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	// Compare every key, the time does not tell which one is close
+	name := ""
+	for known, principal := range s.Keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(known)) == 1 {
+			name = principal
+		}
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	return &Principal{Name: name, Scheme: SCHEME_API_KEY}, nil
+}
+
+// JWT authenticates the requests with a bearer JWT signed by a key of Keys,
+// the principal is its subject. Issuer and Audience are checked when set.
+type JWT struct {
+	Keys     *JWKS
+	Issuer   string
+	Audience string
+}
+
+func (s *JWT) Authenticate(ctx context.Context, credentials *Credentials) (*Principal, error) {
+	authorization := credentials.Header.Get("Authorization")
+
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+	}
+
+	if s.Issuer != "" {
+		options = append(options, jwt.WithIssuer(s.Issuer))
+	}
+
+	if s.Audience != "" {
+		options = append(options, jwt.WithAudience(s.Audience))
+	}
+
+	claims := jwt.RegisteredClaims{}
+
+	if _, err := jwt.ParseWithClaims(strings.TrimSpace(token), &claims, s.Keys.Keyfunc, options...); err != nil {
+		return nil, fmt.Errorf("invalid token, %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("invalid token, missing subject")
+	}
+
+	return &Principal{Name: claims.Subject, Scheme: SCHEME_BEARER}, nil
+}
+
+// MutualTLS authenticates the requests with the certificate of the client,
+// verified by the TLS of the server. The principal is its common name.
+type MutualTLS struct{}
+
+func (s *MutualTLS) Authenticate(ctx context.Context, credentials *Credentials) (*Principal, error) {
+	if len(credentials.Certificates) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	cert := credentials.Certificates[0]
+
+	name := cert.Subject.CommonName
+	if name == "" && len(cert.DNSNames) > 0 {
+		name = cert.DNSNames[0]
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("client certificate without common name")
+	}
+
+	return &Principal{Name: name, Scheme: SCHEME_MUTUAL_TLS}, nil
+}
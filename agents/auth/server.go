@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jlrosende/go-agents/agents"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// Methods and paths anyone can call, the card tells the clients how to
+// authenticate.
+var (
+	publicMethods = []string{pb.A2AService_GetAgentCard_FullMethodName}
+	publicPaths   = []string{agents.AGENT_CARD_PATH, agents.AGENT_CARD_PATH_LEGACY, "/v1/card"}
+)
+
+// UnaryServerInterceptor authorizes the unary calls, the handlers get the
+// principal with FromContext.
+func UnaryServerInterceptor(authorizer *Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := AuthorizeCall(ctx, authorizer, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authorizes the streaming calls.
+func StreamServerInterceptor(authorizer *Authorizer) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := AuthorizeCall(stream.Context(), authorizer, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+
+		return handler(srv, wrapped)
+	}
+}
+
+// AuthorizeCall authorizes the gRPC call of method with the metadata and the
+// peer of ctx, returning the context with its principal. Methods may have a
+// route prefix, /{agent}/a2a.v1.A2AService/GetAgentCard.
+func AuthorizeCall(ctx context.Context, authorizer *Authorizer, method string) (context.Context, error) {
+	for _, public := range publicMethods {
+		if strings.HasSuffix(method, public) {
+			return ctx, nil
+		}
+	}
+
+	credentials := &Credentials{Header: http.Header{}}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			credentials.Header.Add(key, value)
+		}
+	}
+
+	credentials.Certificates = peerCertificates(ctx)
+
+	principal, err := authorizer.Authorize(ctx, credentials)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return WithPrincipal(ctx, principal), nil
+}
+
+// peerCertificates returns the verified chain of the client certificate of
+// the connection, nil without mTLS.
+func peerCertificates(ctx context.Context) []*x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil
+	}
+
+	return info.State.VerifiedChains[0]
+}
+
+// Middleware authorizes the requests of the HTTP bindings, the cards are
+// public.
+func Middleware(authorizer *Authorizer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, public := range publicPaths {
+			if r.Method == http.MethodGet && r.URL.Path == public {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		credentials := &Credentials{Header: r.Header}
+
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			credentials.Certificates = r.TLS.VerifiedChains[0]
+		}
+
+		principal, err := authorizer.Authorize(r.Context(), credentials)
+		if err != nil {
+			if errors.Is(err, ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func statusError(err error) error {
+	if errors.Is(err, ErrPermissionDenied) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return status.Error(codes.Unauthenticated, err.Error())
+}
@@ -0,0 +1,48 @@
+package agents
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// Well known paths of the agent card, AGENT_CARD_PATH_LEGACY is the one of
+// the clients of previous versions of the protocol.
+const (
+	AGENT_CARD_PATH        = "/.well-known/agent-card.json"
+	AGENT_CARD_PATH_LEGACY = "/.well-known/agent.json"
+)
+
+// CardHandler serves the card of an agent at the well known paths.
+func CardHandler(card func(ctx context.Context, in *pb.GetAgentCardRequest) (*pb.AgentCard, error)) http.Handler {
+	mux := http.NewServeMux()
+
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		agentCard, err := card(r.Context(), &pb.GetAgentCardRequest{})
+		if err != nil {
+			slog.Error("error get agent card", "error", err)
+			http.Error(w, "error get agent card", http.StatusInternalServerError)
+			return
+		}
+
+		body, err := protojson.Marshal(agentCard)
+		if err != nil {
+			http.Error(w, "error encode agent card", http.StatusInternalServerError)
+			return
+		}
+
+		// Cards are public, browsers of other origins can discover the agent
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+
+	mux.HandleFunc("GET "+AGENT_CARD_PATH, serve)
+	mux.HandleFunc("GET "+AGENT_CARD_PATH_LEGACY, serve)
+
+	return mux
+}
@@ -0,0 +1,210 @@
+// Package gateway serves the REST binding of the A2A service, transcoding
+// the google.api.http annotations of proto/a2a.proto to the gRPC service
+// implementation of an agent. The streaming methods are server sent events.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// MAX_REQUEST_SIZE limits the body of the requests, files included, like the
+// JSON-RPC server does.
+const MAX_REQUEST_SIZE = 32 << 20
+
+// NewHandler serves the REST routes of the service.
+func NewHandler(ctx context.Context, service pb.A2AServiceServer, logger *slog.Logger) (http.Handler, error) {
+	mux := runtime.NewServeMux(runtime.WithErrorHandler(errorHandler))
+
+	if err := pb.RegisterA2AServiceHandlerServer(ctx, mux, service); err != nil {
+		return nil, fmt.Errorf("error register a2a gateway, %w", err)
+	}
+
+	g := &gateway{mux: mux, service: service, logger: logger}
+
+	// The in process handlers do not support streams, routes registered later
+	// take precedence over the generated ones
+	routes := []struct {
+		method  string
+		pattern string
+		handler runtime.HandlerFunc
+	}{
+		{"POST", "/v1/message:stream", g.messageStream},
+		{"GET", "/v1/{name=tasks/*}:subscribe", g.subscribe},
+		// The annotation of the create method uses task/*, the resource names
+		// of the tasks are tasks/*
+		{"POST", "/v1/{parent=tasks/*}/pushNotifications", g.createPushNotification},
+	}
+
+	for _, route := range routes {
+		if err := mux.HandlePath(route.method, route.pattern, route.handler); err != nil {
+			return nil, fmt.Errorf("error register a2a gateway route %s %s, %w", route.method, route.pattern, err)
+		}
+	}
+
+	// The generated handlers read the body too, every route is limited
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// errorHandler answers 413 to the requests whose body exceeded
+// MAX_REQUEST_SIZE, the decoding errors of the handlers do not keep the cause.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+
+	// The limited body keeps returning its error once exceeded
+	if _, bodyErr := r.Body.Read(nil); errors.As(bodyErr, &tooLarge) {
+		err = &runtime.HTTPStatusError{
+			HTTPStatus: http.StatusRequestEntityTooLarge,
+			Err:        status.Errorf(codes.InvalidArgument, "request body larger than %d bytes", tooLarge.Limit),
+		}
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
+
+// Protocols are the protocols of the server of Handler, HTTP/2 over TLS and
+// in clear text for the gRPC clients with and without TLS.
+func Protocols() *http.Protocols {
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	return protocols
+}
+
+// Handler serves the gRPC requests with server and the rest with gateway,
+// so both share a port. The server must accept HTTP/2, see Protocols.
+func Handler(server *grpc.Server, gateway http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			server.ServeHTTP(w, r)
+			return
+		}
+
+		gateway.ServeHTTP(w, r)
+	})
+}
+
+type gateway struct {
+	mux     *runtime.ServeMux
+	service pb.A2AServiceServer
+	logger  *slog.Logger
+}
+
+func (g *gateway) messageStream(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	in := &pb.SendMessageRequest{}
+
+	if err := g.decode(r, in); err != nil {
+		g.error(w, r, err)
+		return
+	}
+
+	stream := newEventStream(g.context(r), w)
+
+	if err := g.service.SendStreamingMessage(in, stream); err != nil {
+		g.streamError(stream, w, r, err)
+	}
+}
+
+func (g *gateway) subscribe(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	stream := newEventStream(g.context(r), w)
+
+	if err := g.service.TaskSubscription(&pb.TaskSubscriptionRequest{Name: params["name"]}, stream); err != nil {
+		g.streamError(stream, w, r, err)
+	}
+}
+
+func (g *gateway) createPushNotification(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	config := &pb.TaskPushNotificationConfig{}
+
+	if err := g.decode(r, config); err != nil {
+		g.error(w, r, err)
+		return
+	}
+
+	response, err := g.service.CreateTaskPushNotification(g.context(r), &pb.CreateTaskPushNotificationRequest{
+		Parent:   params["parent"],
+		ConfigId: r.URL.Query().Get("configId"),
+		Config:   config,
+	})
+	if err != nil {
+		g.error(w, r, err)
+		return
+	}
+
+	_, marshaler := runtime.MarshalerForRequest(g.mux, r)
+
+	body, err := marshaler.Marshal(response)
+	if err != nil {
+		g.error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", marshaler.ContentType(response))
+	w.Write(body)
+}
+
+// context carries the headers of the request as incoming metadata, like the
+// generated handlers do.
+func (g *gateway) context(r *http.Request) context.Context {
+	md := metadata.MD{}
+
+	for key, values := range r.Header {
+		if name, ok := runtime.DefaultHeaderMatcher(key); ok {
+			md.Append(name, values...)
+		}
+	}
+
+	return metadata.NewIncomingContext(r.Context(), md)
+}
+
+func (g *gateway) decode(r *http.Request, message proto.Message) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "error read body, %s", err)
+	}
+
+	inbound, _ := runtime.MarshalerForRequest(g.mux, r)
+
+	if err := inbound.Unmarshal(body, message); err != nil {
+		return status.Errorf(codes.InvalidArgument, "error decode body, %s", err)
+	}
+
+	return nil
+}
+
+func (g *gateway) error(w http.ResponseWriter, r *http.Request, err error) {
+	_, outbound := runtime.MarshalerForRequest(g.mux, r)
+	runtime.HTTPError(r.Context(), g.mux, outbound, w, r, err)
+}
+
+// streamError answers with the error when the stream did not start, once
+// it started the error is its last event.
+func (g *gateway) streamError(stream *eventStream, w http.ResponseWriter, r *http.Request, err error) {
+	if !stream.started {
+		g.error(w, r, err)
+		return
+	}
+
+	if err := stream.fail(err); err != nil {
+		g.logger.Error("error send gateway stream error", "error", err)
+	}
+}
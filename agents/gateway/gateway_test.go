@@ -0,0 +1,188 @@
+package gateway_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jlrosende/go-agents/agents/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// echoService completes the tasks answering with the content of the message.
+type echoService struct {
+	pb.UnimplementedA2AServiceServer
+}
+
+func (s *echoService) task(in *pb.SendMessageRequest) *pb.Task {
+	return &pb.Task{
+		Id:        "task-1",
+		ContextId: "context-1",
+		Status:    &pb.TaskStatus{State: pb.TaskState_TASK_STATE_COMPLETED},
+		Artifacts: []*pb.Artifact{{ArtifactId: "response", Parts: in.GetRequest().GetContent()}},
+	}
+}
+
+func (s *echoService) SendMessage(ctx context.Context, in *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
+	return &pb.SendMessageResponse{Payload: &pb.SendMessageResponse_Task{Task: s.task(in)}}, nil
+}
+
+func (s *echoService) SendStreamingMessage(in *pb.SendMessageRequest, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+	task := s.task(in)
+
+	if err := stream.Send(&pb.StreamResponse{Payload: &pb.StreamResponse_Task{Task: task}}); err != nil {
+		return err
+	}
+
+	return status.Errorf(codes.Internal, "stream broken")
+}
+
+func (s *echoService) GetTask(ctx context.Context, in *pb.GetTaskRequest) (*pb.Task, error) {
+	return nil, status.Errorf(codes.NotFound, "task %s not found", in.GetName())
+}
+
+func (s *echoService) GetAgentCard(ctx context.Context, in *pb.GetAgentCardRequest) (*pb.AgentCard, error) {
+	return &pb.AgentCard{Name: "echo"}, nil
+}
+
+func TestGateway(t *testing.T) {
+	service := &echoService{}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterA2AServiceServer(grpcServer, service)
+
+	handler, err := gateway.NewHandler(context.Background(), service, slog.Default())
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(gateway.Handler(grpcServer, handler))
+	server.Config.Protocols = gateway.Protocols()
+	server.Start()
+	defer server.Close()
+
+	message := `{"request":{"messageId":"m-1","role":"ROLE_USER","content":[{"text":"hello"}]}}`
+
+	t.Run("unary", func(t *testing.T) {
+		response, err := http.Post(server.URL+"/v1/message:send", "application/json", strings.NewReader(message))
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+
+		result := &pb.SendMessageResponse{}
+		require.NoError(t, protojson.Unmarshal(body, result))
+
+		assert.Equal(t, "task-1", result.GetTask().GetId())
+		assert.Equal(t, "hello", result.GetTask().GetArtifacts()[0].GetParts()[0].GetText())
+
+		response, err = http.Get(server.URL + "/v1/tasks/missing")
+		require.NoError(t, err)
+		response.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+		response, err = http.Get(server.URL + "/v1/card")
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		body, err = io.ReadAll(response.Body)
+		require.NoError(t, err)
+
+		assert.Contains(t, string(body), `"name":"echo"`)
+	})
+
+	t.Run("server sent events", func(t *testing.T) {
+		response, err := http.Post(server.URL+"/v1/message:stream", "application/json", strings.NewReader(message))
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		lines := []string{}
+
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], `data: {"task":`), lines[0])
+		assert.Equal(t, "event: error", lines[1])
+		assert.Contains(t, lines[2], "stream broken")
+	})
+
+	t.Run("request size", func(t *testing.T) {
+		large := `{"request":{"messageId":"m-1","role":"ROLE_USER","content":[{"text":"` + strings.Repeat("a", gateway.MAX_REQUEST_SIZE) + `"}]}}`
+
+		for _, path := range []string{"/v1/message:send", "/v1/message:stream"} {
+			response, err := http.Post(server.URL+path, "application/json", strings.NewReader(large))
+			require.NoError(t, err)
+			response.Body.Close()
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode, path)
+		}
+	})
+
+	t.Run("grpc on the same port", func(t *testing.T) {
+		conn, err := grpc.NewClient(strings.TrimPrefix(server.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		card, err := pb.NewA2AServiceClient(conn).GetAgentCard(context.Background(), &pb.GetAgentCardRequest{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "echo", card.GetName())
+	})
+}
+
+func TestGatewayTLS(t *testing.T) {
+	service := &echoService{}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterA2AServiceServer(grpcServer, service)
+
+	handler, err := gateway.NewHandler(context.Background(), service, slog.Default())
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(gateway.Handler(grpcServer, handler))
+	server.Config.Protocols = gateway.Protocols()
+	server.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	server.StartTLS()
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/v1/card")
+	require.NoError(t, err)
+	response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	conn, err := grpc.NewClient(server.Listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, "example.com")))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	card, err := pb.NewA2AServiceClient(conn).GetAgentCard(context.Background(), &pb.GetAgentCardRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "echo", card.GetName())
+}
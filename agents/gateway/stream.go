@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// eventStream sends the responses of a gRPC stream as server sent events,
+// the data of each one the JSON of the response.
+type eventStream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	started bool
+}
+
+var _ grpc.ServerStreamingServer[pb.StreamResponse] = (*eventStream)(nil)
+
+func newEventStream(ctx context.Context, w http.ResponseWriter) *eventStream {
+	return &eventStream{ctx: ctx, w: w}
+}
+
+func (s *eventStream) Send(response *pb.StreamResponse) error {
+	return s.write("", response)
+}
+
+// fail sends the status of the error as an error event.
+func (s *eventStream) fail(err error) error {
+	return s.write("error", status.Convert(err).Proto())
+}
+
+func (s *eventStream) write(event string, message proto.Message) error {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	data, err := protojson.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error encode event, %w", err)
+	}
+
+	if event != "" {
+		if _, err := fmt.Fprintf(s.w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+func (s *eventStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *eventStream) SetHeader(metadata.MD) error  { return nil }
+func (s *eventStream) SendHeader(metadata.MD) error { return nil }
+func (s *eventStream) SetTrailer(metadata.MD)       {}
+func (s *eventStream) SendMsg(m any) error {
+	response, ok := m.(*pb.StreamResponse)
+	if !ok {
+		return fmt.Errorf("unexpected stream message %T", m)
+	}
+
+	return s.Send(response)
+}
+func (s *eventStream) RecvMsg(m any) error { return errors.New("server streams do not receive") }
@@ -0,0 +1,315 @@
+// Package host serves many agents on one gRPC server, with one interceptor
+// chain, one signal handler and one graceful shutdown.
+//
+// Calls are routed to an agent by the HEADER_AGENT metadata, or by a prefix
+// of the method path, /{agent}/a2a.v1.A2AService/SendMessage, for the
+// clients that can not set metadata.
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/agents/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// HEADER_AGENT is the metadata naming the agent of a call.
+const HEADER_AGENT = "x-a2a-agent"
+
+var ErrAgentNotFound = errors.New("agent not found")
+
+type hosted struct {
+	service    pb.A2AServiceServer
+	authorizer *auth.Authorizer
+}
+
+// Host is a gRPC server of many agents, listening at Listen.
+type Host struct {
+	Listen string
+	TLS    *agents.TLS
+	Logger *slog.Logger
+
+	mu     sync.RWMutex
+	agents map[string]*hosted
+	server *grpc.Server
+}
+
+func WithTLS(tls *agents.TLS) func(*Host) {
+	return func(h *Host) {
+		h.TLS = tls
+	}
+}
+
+func WithLogger(logger *slog.Logger) func(*Host) {
+	return func(h *Host) {
+		h.Logger = logger
+	}
+}
+
+func NewHost(listen string, opts ...func(*Host)) *Host {
+	h := &Host{
+		Listen: listen,
+		Logger: slog.Default(),
+		agents: map[string]*hosted{},
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Add hosts the service of an agent, its requests are authorized with
+// authorizer when it is not nil.
+func (h *Host) Add(name string, service pb.A2AServiceServer, authorizer *auth.Authorizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.agents[name] = &hosted{service: service, authorizer: authorizer}
+}
+
+// Agents returns the names of the hosted agents.
+func (h *Host) Agents() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	names := make([]string, 0, len(h.agents))
+	for name := range h.agents {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Url is the url of the clients of the agents of the host.
+func (h *Host) Url() (string, error) {
+	protocol, address, err := agents.ParseAddress(h.Listen)
+	if err != nil {
+		return "", err
+	}
+
+	if protocol == agents.PROTOCOL_UNIX {
+		return "unix://" + address, nil
+	}
+
+	// Listening on every interface, the clients of this process use loopback
+	if strings.HasPrefix(address, ":") {
+		address = "localhost" + address
+	}
+
+	return address, nil
+}
+
+// Server creates the gRPC server of the host. Every call goes through the
+// same chain, logging, recovery, routing and the authorization of the agent.
+func (h *Host) Server() (*grpc.Server, error) {
+	creds, err := h.TLS.ServerCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("error host server credentials, %w", err)
+	}
+
+	panicHandler := func(p any) error {
+		// The panic stays in the logs, clients only learn the call failed
+		h.Logger.Error("recovered from panic", "panic", p)
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	logger := logging.LoggerFunc(func(ctx context.Context, lvl logging.Level, msg string, fields ...any) {
+		h.Logger.Log(ctx, slog.Level(lvl), msg, fields...)
+	})
+
+	// No service is registered, every method reaches handle as a stream so
+	// the prefixed paths are served too
+	return grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.ChainStreamInterceptor(
+			logging.StreamServerInterceptor(logger),
+			recovery.StreamServerInterceptor(recovery.WithRecoveryHandler(panicHandler)),
+			h.route,
+		),
+		grpc.UnknownServiceHandler(h.handle),
+	), nil
+}
+
+// Serve serves the agents until the process is signaled to stop, then stops
+// gracefully.
+func (h *Host) Serve() error {
+	server, err := h.Server()
+	if err != nil {
+		return err
+	}
+
+	h.server = server
+
+	// Unix sockets are removed when the listener closes
+	lis, err := agents.Listen(h.Listen)
+	if err != nil {
+		return fmt.Errorf("error host listen, %w", err)
+	}
+	defer lis.Close()
+
+	h.Logger.Info(fmt.Sprintf("host listening at %v", lis.Addr()), "agents", h.Agents())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(
+		sigCh, os.Interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM,
+	)
+	defer signal.Stop(sigCh)
+
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case s := <-sigCh:
+			h.Logger.Info(fmt.Sprintf("got signal %v, attempting graceful shutdown", s))
+			server.GracefulStop()
+		case <-done:
+		}
+	}()
+
+	err = server.Serve(lis)
+	close(done)
+
+	if err != nil {
+		return fmt.Errorf("error host serve, %w", err)
+	}
+
+	h.Logger.Info("clean host shutdown")
+
+	return nil
+}
+
+// Stop stops the server gracefully.
+func (h *Host) Stop() {
+	if h.server != nil {
+		h.server.GracefulStop()
+	}
+}
+
+type routeKey struct{}
+
+type route struct {
+	agent  *hosted
+	method string
+}
+
+// route resolves the agent of the call and authorizes it.
+func (h *Host) route(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := stream.Context()
+
+	name, method := splitMethod(info.FullMethod)
+
+	if name == "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(HEADER_AGENT); len(values) > 0 {
+			name = values[0]
+		}
+	}
+
+	if name == "" {
+		return status.Errorf(codes.InvalidArgument, "missing agent, set the %s metadata or call /{agent}%s", HEADER_AGENT, method)
+	}
+
+	h.mu.RLock()
+	agent, ok := h.agents[name]
+	h.mu.RUnlock()
+
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s %s", ErrAgentNotFound, name)
+	}
+
+	if agent.authorizer != nil {
+		authorized, err := auth.AuthorizeCall(ctx, agent.authorizer, method)
+		if err != nil {
+			return err
+		}
+
+		ctx = authorized
+	}
+
+	wrapped := middleware.WrapServerStream(stream)
+	wrapped.WrappedContext = context.WithValue(ctx, routeKey{}, &route{agent: agent, method: method})
+
+	return handler(srv, wrapped)
+}
+
+// handle calls the method of the service of the agent of the route.
+func (h *Host) handle(srv any, stream grpc.ServerStream) error {
+	r, ok := stream.Context().Value(routeKey{}).(*route)
+	if !ok {
+		return status.Errorf(codes.Internal, "call without route")
+	}
+
+	service, name, ok := strings.Cut(strings.TrimPrefix(r.method, "/"), "/")
+	if !ok || service != pb.A2AService_ServiceDesc.ServiceName {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", r.method)
+	}
+
+	for _, method := range pb.A2AService_ServiceDesc.Methods {
+		if method.MethodName != name {
+			continue
+		}
+
+		response, err := method.Handler(r.agent.service, stream.Context(), stream.RecvMsg, nil)
+		if err != nil {
+			return err
+		}
+
+		return stream.SendMsg(response)
+	}
+
+	for _, desc := range pb.A2AService_ServiceDesc.Streams {
+		if desc.StreamName == name {
+			return desc.Handler(r.agent.service, stream)
+		}
+	}
+
+	return status.Errorf(codes.Unimplemented, "unknown method %s", r.method)
+}
+
+// splitMethod returns the agent of the prefix of a method path and the
+// method, /agent/a2a.v1.A2AService/SendMessage.
+func splitMethod(fullMethod string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 3)
+
+	if len(parts) == 3 {
+		return parts[0], "/" + parts[1] + "/" + parts[2]
+	}
+
+	return "", fullMethod
+}
+
+// RouteInterceptors add the agent metadata to the calls of a client, so the
+// host routes them to the agent.
+func RouteInterceptors(name string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(metadata.AppendToOutgoingContext(ctx, HEADER_AGENT, name), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.AppendToOutgoingContext(ctx, HEADER_AGENT, name), desc, cc, method, opts...)
+		}),
+	}
+}
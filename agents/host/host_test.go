@@ -0,0 +1,142 @@
+package host_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/agents/auth"
+	"github.com/jlrosende/go-agents/agents/host"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// namedService answers with its name, in the card and in the tasks.
+type namedService struct {
+	pb.UnimplementedA2AServiceServer
+	name string
+}
+
+func (s *namedService) GetAgentCard(ctx context.Context, in *pb.GetAgentCardRequest) (*pb.AgentCard, error) {
+	return &pb.AgentCard{Name: s.name}, nil
+}
+
+func (s *namedService) GetTask(ctx context.Context, in *pb.GetTaskRequest) (*pb.Task, error) {
+	return &pb.Task{Id: in.GetName(), ContextId: s.name}, nil
+}
+
+func (s *namedService) TaskSubscription(in *pb.TaskSubscriptionRequest, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+	return stream.Send(&pb.StreamResponse{Payload: &pb.StreamResponse_Task{Task: &pb.Task{Id: in.GetName(), ContextId: s.name}}})
+}
+
+// panicService panics reading tasks.
+type panicService struct {
+	pb.UnimplementedA2AServiceServer
+}
+
+func (s *panicService) GetTask(ctx context.Context, in *pb.GetTaskRequest) (*pb.Task, error) {
+	panic("secret state of the agent")
+}
+
+func TestHost(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "host.sock")
+
+	h := host.NewHost("unix://" + socket)
+
+	h.Add("one", &namedService{name: "one"}, nil)
+	h.Add("panics", &panicService{}, nil)
+	h.Add("two", &namedService{name: "two"}, &auth.Authorizer{
+		Schemes:  map[string]auth.Authenticator{"key": &auth.APIKey{Name: "X-API-Key", Keys: map[string]string{"S3cret-Key": "ci"}}},
+		Security: [][]string{{"key"}},
+	})
+
+	server, err := h.Server()
+	require.NoError(t, err)
+
+	lis, err := agents.Listen("unix://" + socket)
+	require.NoError(t, err)
+
+	go server.Serve(lis)
+	defer server.Stop()
+
+	url, err := h.Url()
+	require.NoError(t, err)
+
+	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewA2AServiceClient(conn)
+
+	withAgent := func(name string, kv ...string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), append([]string{host.HEADER_AGENT, name}, kv...)...)
+	}
+
+	t.Run("metadata routing", func(t *testing.T) {
+		card, err := client.GetAgentCard(withAgent("one"), &pb.GetAgentCardRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "one", card.GetName())
+
+		// Cards are public, the tasks need the api key of the agent
+		card, err = client.GetAgentCard(withAgent("two"), &pb.GetAgentCardRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "two", card.GetName())
+
+		_, err = client.GetTask(withAgent("two"), &pb.GetTaskRequest{Name: "tasks/1"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		task, err := client.GetTask(withAgent("two", "x-api-key", "S3cret-Key"), &pb.GetTaskRequest{Name: "tasks/1"})
+		require.NoError(t, err)
+		assert.Equal(t, "two", task.GetContextId())
+
+		stream, err := client.TaskSubscription(withAgent("one"), &pb.TaskSubscriptionRequest{Name: "tasks/2"})
+		require.NoError(t, err)
+
+		response, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "one", response.GetTask().GetContextId())
+	})
+
+	t.Run("path routing", func(t *testing.T) {
+		card := &pb.AgentCard{}
+
+		err := conn.Invoke(context.Background(), "/two"+pb.A2AService_GetAgentCard_FullMethodName, &pb.GetAgentCardRequest{}, card)
+		require.NoError(t, err)
+		assert.Equal(t, "two", card.GetName())
+	})
+
+	t.Run("client route interceptors", func(t *testing.T) {
+		routed, err := grpc.NewClient(url, append(host.RouteInterceptors("one"), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+		require.NoError(t, err)
+		defer routed.Close()
+
+		task, err := pb.NewA2AServiceClient(routed).GetTask(context.Background(), &pb.GetTaskRequest{Name: "tasks/3"})
+		require.NoError(t, err)
+		assert.Equal(t, "one", task.GetContextId())
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		_, err := client.GetTask(withAgent("panics"), &pb.GetTaskRequest{Name: "tasks/1"})
+		require.Error(t, err)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "internal error", status.Convert(err).Message())
+	})
+
+	t.Run("unknown agent", func(t *testing.T) {
+		_, err := client.GetAgentCard(withAgent("three"), &pb.GetAgentCardRequest{})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.GetAgentCard(context.Background(), &pb.GetAgentCardRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	assert.Equal(t, []string{"one", "panics", "two"}, h.Agents())
+}
@@ -0,0 +1,429 @@
+// Package jsonrpc serves the A2A JSON-RPC 2.0 binding over HTTP, mapping its
+// methods onto the gRPC service implementation of an agent.
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/agents/tasks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// MAX_REQUEST_SIZE limits the body of the requests, files included.
+const MAX_REQUEST_SIZE = 32 << 20
+
+// PushConfigDeleter is implemented by the services able to delete push
+// notification configs, the gRPC service has no method for it.
+type PushConfigDeleter interface {
+	DeleteTaskPushNotification(ctx context.Context, name string) error
+}
+
+type server struct {
+	service pb.A2AServiceServer
+	logger  *slog.Logger
+}
+
+// NewHandler serves the JSON-RPC methods at / and the agent card at the well
+// known paths.
+func NewHandler(service pb.A2AServiceServer, logger *slog.Logger) http.Handler {
+	s := &server{service: service, logger: logger}
+
+	card := agents.CardHandler(service.GetAgentCard)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET "+agents.AGENT_CARD_PATH, card)
+	mux.Handle("GET "+agents.AGENT_CARD_PATH_LEGACY, card)
+	mux.HandleFunc("POST /{$}", s.serveHTTP)
+
+	return mux
+}
+
+func (s *server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	request := Request{}
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)).Decode(&request); err != nil {
+		writeResponse(w, nil, nil, &Error{Code: CODE_PARSE_ERROR, Message: fmt.Sprintf("invalid json, %s", err)})
+		return
+	}
+
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		writeResponse(w, request.Id, nil, &Error{Code: CODE_INVALID_REQUEST, Message: "invalid json-rpc 2.0 request"})
+		return
+	}
+
+	ctx := r.Context()
+
+	switch request.Method {
+	case "message/stream":
+		s.messageStream(ctx, w, request)
+		return
+	case "tasks/resubscribe":
+		s.resubscribe(ctx, w, request)
+		return
+	}
+
+	result, err := s.call(ctx, request)
+	if err != nil {
+		writeResponse(w, request.Id, nil, s.rpcError(request.Method, err))
+		return
+	}
+
+	writeResponse(w, request.Id, result, nil)
+}
+
+// call runs the methods answered with one response.
+func (s *server) call(ctx context.Context, request Request) (any, error) {
+	switch request.Method {
+	case "message/send":
+		params := MessageSendParams{}
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+
+		in, err := sendRequest(params)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := s.service.SendMessage(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+
+		if task := response.GetTask(); task != nil {
+			return toTask(task), nil
+		}
+
+		return toMessage(response.GetMsg()), nil
+
+	case "tasks/get":
+		params := TaskQueryParams{}
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+
+		task, err := s.service.GetTask(ctx, &pb.GetTaskRequest{Name: tasks.Name(params.Id), HistoryLength: params.HistoryLength})
+		if err != nil {
+			return nil, err
+		}
+
+		return toTask(task), nil
+
+	case "tasks/cancel":
+		params := TaskIdParams{}
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+
+		task, err := s.service.CancelTask(ctx, &pb.CancelTaskRequest{Name: tasks.Name(params.Id)})
+		if err != nil {
+			return nil, err
+		}
+
+		return toTask(task), nil
+
+	case "tasks/pushNotificationConfig/set":
+		params := TaskPushNotificationConfig{}
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+
+		config, err := s.service.CreateTaskPushNotification(ctx, &pb.CreateTaskPushNotificationRequest{
+			Parent:   tasks.Name(params.TaskId),
+			ConfigId: params.PushNotificationConfig.Id,
+			Config: &pb.TaskPushNotificationConfig{
+				PushNotificationConfig: fromPushConfig(&params.PushNotificationConfig),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return toPushConfig(config)
+
+	case "tasks/pushNotificationConfig/get":
+		params := PushNotificationConfigParams{}
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+
+		// Without config id, the first config of the task
+		if params.PushNotificationConfigId == "" {
+			list, err := s.service.ListTaskPushNotification(ctx, &pb.ListTaskPushNotificationRequest{Parent: tasks.Name(params.Id), PageSize: 1})
+			if err != nil {
+				return nil, err
+			}
+
+			if len(list.GetConfigs()) == 0 {
+				return nil, status.Errorf(codes.NotFound, "task %s has no push notification config", params.Id)
+			}
+
+			return toPushConfig(list.GetConfigs()[0])
+		}
+
+		config, err := s.service.GetTaskPushNotification(ctx, &pb.GetTaskPushNotificationRequest{
+			Name: tasks.PushName(params.Id, params.PushNotificationConfigId),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return toPushConfig(config)
+
+	case "tasks/pushNotificationConfig/list":
+		params := TaskIdParams{}
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+
+		configs := []*TaskPushNotificationConfig{}
+		pageToken := ""
+
+		for {
+			list, err := s.service.ListTaskPushNotification(ctx, &pb.ListTaskPushNotificationRequest{Parent: tasks.Name(params.Id), PageToken: pageToken})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, config := range list.GetConfigs() {
+				converted, err := toPushConfig(config)
+				if err != nil {
+					return nil, err
+				}
+
+				configs = append(configs, converted)
+			}
+
+			if pageToken = list.GetNextPageToken(); pageToken == "" {
+				return configs, nil
+			}
+		}
+
+	case "tasks/pushNotificationConfig/delete":
+		params := PushNotificationConfigParams{}
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+
+		deleter, ok := s.service.(PushConfigDeleter)
+		if !ok {
+			return nil, status.Errorf(codes.Unimplemented, "push notification configs can not be deleted")
+		}
+
+		if err := deleter.DeleteTaskPushNotification(ctx, tasks.PushName(params.Id, params.PushNotificationConfigId)); err != nil {
+			return nil, err
+		}
+
+		return json.RawMessage("null"), nil
+	}
+
+	return nil, &Error{Code: CODE_METHOD_NOT_FOUND, Message: fmt.Sprintf("method %s not found", request.Method)}
+}
+
+func (s *server) messageStream(ctx context.Context, w http.ResponseWriter, request Request) {
+	params := MessageSendParams{}
+	if err := decodeParams(request, &params); err != nil {
+		writeResponse(w, request.Id, nil, s.rpcError(request.Method, err))
+		return
+	}
+
+	in, err := sendRequest(params)
+	if err != nil {
+		writeResponse(w, request.Id, nil, s.rpcError(request.Method, err))
+		return
+	}
+
+	stream := newEventStream(ctx, w, request.Id)
+
+	if err := s.service.SendStreamingMessage(in, stream); err != nil {
+		stream.fail(s.rpcError(request.Method, err))
+	}
+}
+
+func (s *server) resubscribe(ctx context.Context, w http.ResponseWriter, request Request) {
+	params := TaskIdParams{}
+	if err := decodeParams(request, &params); err != nil {
+		writeResponse(w, request.Id, nil, s.rpcError(request.Method, err))
+		return
+	}
+
+	stream := newEventStream(ctx, w, request.Id)
+
+	if err := s.service.TaskSubscription(&pb.TaskSubscriptionRequest{Name: tasks.Name(params.Id)}, stream); err != nil {
+		stream.fail(s.rpcError(request.Method, err))
+	}
+}
+
+func decodeParams(request Request, params any) error {
+	if len(request.Params) == 0 {
+		return &Error{Code: CODE_INVALID_PARAMS, Message: "missing params"}
+	}
+
+	if err := json.Unmarshal(request.Params, params); err != nil {
+		return &Error{Code: CODE_INVALID_PARAMS, Message: fmt.Sprintf("invalid params, %s", err)}
+	}
+
+	return nil
+}
+
+func sendRequest(params MessageSendParams) (*pb.SendMessageRequest, error) {
+	if params.Message == nil {
+		return nil, &Error{Code: CODE_INVALID_PARAMS, Message: "missing message"}
+	}
+
+	message, err := fromMessage(params.Message)
+	if err != nil {
+		return nil, &Error{Code: CODE_INVALID_PARAMS, Message: err.Error()}
+	}
+
+	metadata, err := fromMap(params.Metadata)
+	if err != nil {
+		return nil, &Error{Code: CODE_INVALID_PARAMS, Message: err.Error()}
+	}
+
+	in := &pb.SendMessageRequest{Request: message, Metadata: metadata}
+
+	if configuration := params.Configuration; configuration != nil {
+		in.Configuration = &pb.SendMessageConfiguration{
+			AcceptedOutputModes: configuration.AcceptedOutputModes,
+			HistoryLength:       configuration.HistoryLength,
+			Blocking:            configuration.Blocking,
+		}
+
+		if configuration.PushNotificationConfig != nil {
+			in.Configuration.PushNotification = fromPushConfig(configuration.PushNotificationConfig)
+		}
+	}
+
+	return in, nil
+}
+
+// rpcError converts the gRPC status errors of the service to the errors of
+// the binding.
+func (s *server) rpcError(method string, err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	st := status.Convert(err)
+
+	switch st.Code() {
+	case codes.NotFound:
+		return &Error{Code: CODE_TASK_NOT_FOUND, Message: st.Message()}
+	case codes.FailedPrecondition:
+		if method == "tasks/cancel" {
+			return &Error{Code: CODE_TASK_NOT_CANCELABLE, Message: st.Message()}
+		}
+
+		return &Error{Code: CODE_INVALID_PARAMS, Message: st.Message()}
+	case codes.InvalidArgument:
+		return &Error{Code: CODE_INVALID_PARAMS, Message: st.Message()}
+	case codes.Unimplemented:
+		if method == "tasks/pushNotificationConfig/set" {
+			return &Error{Code: CODE_PUSH_NOTIFICATION_NOT_SUPPORTED, Message: st.Message()}
+		}
+
+		return &Error{Code: CODE_UNSUPPORTED_OPERATION, Message: st.Message()}
+	}
+
+	s.logger.Error(fmt.Sprintf("error json-rpc %s", method), "error", err)
+
+	return &Error{Code: CODE_INTERNAL_ERROR, Message: st.Message()}
+}
+
+func writeResponse(w http.ResponseWriter, id json.RawMessage, result any, rpcErr *Error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(Response{JSONRPC: "2.0", Id: id, Result: result, Error: rpcErr})
+}
+
+// eventStream sends the events of a gRPC stream as server sent events, each
+// one a JSON-RPC response to the request.
+type eventStream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	id      json.RawMessage
+	started bool
+}
+
+var _ grpc.ServerStreamingServer[pb.StreamResponse] = (*eventStream)(nil)
+
+func newEventStream(ctx context.Context, w http.ResponseWriter, id json.RawMessage) *eventStream {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	return &eventStream{ctx: ctx, w: w, id: id}
+}
+
+func (s *eventStream) Send(event *pb.StreamResponse) error {
+	return s.write(Response{JSONRPC: "2.0", Id: s.id, Result: toEvent(event)})
+}
+
+// fail sends the error as an event when the stream started, as a response
+// otherwise.
+func (s *eventStream) fail(rpcErr *Error) {
+	if !s.started {
+		writeResponse(s.w, s.id, nil, rpcErr)
+		return
+	}
+
+	s.write(Response{JSONRPC: "2.0", Id: s.id, Error: rpcErr})
+}
+
+func (s *eventStream) write(response Response) error {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("error encode event, %w", err)
+	}
+
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+func (s *eventStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *eventStream) SetHeader(metadata.MD) error  { return nil }
+func (s *eventStream) SendHeader(metadata.MD) error { return nil }
+func (s *eventStream) SetTrailer(metadata.MD)       {}
+func (s *eventStream) SendMsg(m any) error {
+	event, ok := m.(*pb.StreamResponse)
+	if !ok {
+		return fmt.Errorf("unexpected stream message %T", m)
+	}
+
+	return s.Send(event)
+}
+func (s *eventStream) RecvMsg(m any) error { return errors.New("server streams do not receive") }
@@ -0,0 +1,168 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jlrosende/go-agents/agents/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// echoService completes the tasks answering with the text of the message.
+type echoService struct {
+	pb.UnimplementedA2AServiceServer
+}
+
+func (s *echoService) task(in *pb.SendMessageRequest) *pb.Task {
+	return &pb.Task{
+		Id:        "task-1",
+		ContextId: "context-1",
+		Status: &pb.TaskStatus{
+			State: pb.TaskState_TASK_STATE_COMPLETED,
+		},
+		Artifacts: []*pb.Artifact{{
+			ArtifactId: "response",
+			Parts:      in.GetRequest().GetContent(),
+		}},
+	}
+}
+
+func (s *echoService) SendMessage(ctx context.Context, in *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
+	return &pb.SendMessageResponse{Payload: &pb.SendMessageResponse_Task{Task: s.task(in)}}, nil
+}
+
+func (s *echoService) SendStreamingMessage(in *pb.SendMessageRequest, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+	task := s.task(in)
+
+	stream.Send(&pb.StreamResponse{Payload: &pb.StreamResponse_ArtifactUpdate{ArtifactUpdate: &pb.TaskArtifactUpdateEvent{
+		TaskId:    task.Id,
+		ContextId: task.ContextId,
+		Artifact:  task.Artifacts[0],
+		LastChunk: true,
+	}}})
+
+	return stream.Send(&pb.StreamResponse{Payload: &pb.StreamResponse_StatusUpdate{StatusUpdate: &pb.TaskStatusUpdateEvent{
+		TaskId:    task.Id,
+		ContextId: task.ContextId,
+		Status:    task.Status,
+		Final:     true,
+	}}})
+}
+
+func (s *echoService) GetTask(ctx context.Context, in *pb.GetTaskRequest) (*pb.Task, error) {
+	return nil, status.Errorf(codes.NotFound, "task %s not found", in.GetName())
+}
+
+func (s *echoService) GetAgentCard(ctx context.Context, in *pb.GetAgentCardRequest) (*pb.AgentCard, error) {
+	return &pb.AgentCard{Name: "echo"}, nil
+}
+
+func call(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+
+	response, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+
+	return response
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(jsonrpc.NewHandler(&echoService{}, slog.Default()))
+	defer server.Close()
+
+	message := `{"kind":"message","messageId":"m-1","role":"user","parts":[{"kind":"text","text":"hello"}]}`
+
+	t.Run("message/send", func(t *testing.T) {
+		response := call(t, server.URL, `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":`+message+`}}`)
+		defer response.Body.Close()
+
+		result := struct {
+			Id     int            `json:"id"`
+			Result jsonrpc.Task   `json:"result"`
+			Error  *jsonrpc.Error `json:"error"`
+		}{}
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+
+		assert.Nil(t, result.Error)
+		assert.Equal(t, 1, result.Id)
+		assert.Equal(t, "task", result.Result.Kind)
+		assert.Equal(t, "completed", result.Result.Status.State)
+		require.Len(t, result.Result.Artifacts, 1)
+		assert.Equal(t, "hello", result.Result.Artifacts[0].Parts[0].Text)
+	})
+
+	t.Run("message/stream", func(t *testing.T) {
+		response := call(t, server.URL, `{"jsonrpc":"2.0","id":"s","method":"message/stream","params":{"message":`+message+`}}`)
+		defer response.Body.Close()
+
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		kinds := []string{}
+
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+
+			event := struct {
+				Id     string `json:"id"`
+				Result struct {
+					Kind  string `json:"kind"`
+					Final bool   `json:"final"`
+				} `json:"result"`
+			}{}
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+
+			assert.Equal(t, "s", event.Id)
+			kinds = append(kinds, event.Result.Kind)
+		}
+
+		assert.Equal(t, []string{"artifact-update", "status-update"}, kinds)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for body, code := range map[string]int{
+			`{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"missing"}}`:    jsonrpc.CODE_TASK_NOT_FOUND,
+			`{"jsonrpc":"2.0","id":1,"method":"tasks/cancel","params":{"id":"missing"}}`: jsonrpc.CODE_UNSUPPORTED_OPERATION,
+			`{"jsonrpc":"2.0","id":1,"method":"tasks/unknown","params":{}}`:              jsonrpc.CODE_METHOD_NOT_FOUND,
+			`{"jsonrpc":"2.0","id":1,"method":"message/send"}`:                           jsonrpc.CODE_INVALID_PARAMS,
+			`{"jsonrpc":`: jsonrpc.CODE_PARSE_ERROR,
+		} {
+			response := call(t, server.URL, body)
+
+			result := jsonrpc.Response{}
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+			response.Body.Close()
+
+			require.NotNil(t, result.Error, body)
+			assert.Equal(t, code, result.Error.Code, body)
+		}
+	})
+
+	t.Run("agent card", func(t *testing.T) {
+		response, err := http.Get(server.URL + "/.well-known/agent.json")
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Contains(t, string(body), `"name":"echo"`)
+	})
+}
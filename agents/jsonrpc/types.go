@@ -0,0 +1,417 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jlrosende/go-agents/agents/tasks"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// Objects of the A2A JSON-RPC binding, they differ from the JSON of the
+// protobuf messages: parts and results have a kind and enums are lowercase.
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("json-rpc error %d, %s", e.Code, e.Message)
+}
+
+// Error codes of JSON-RPC and of A2A
+const (
+	CODE_PARSE_ERROR                     = -32700
+	CODE_INVALID_REQUEST                 = -32600
+	CODE_METHOD_NOT_FOUND                = -32601
+	CODE_INVALID_PARAMS                  = -32602
+	CODE_INTERNAL_ERROR                  = -32603
+	CODE_TASK_NOT_FOUND                  = -32001
+	CODE_TASK_NOT_CANCELABLE             = -32002
+	CODE_PUSH_NOTIFICATION_NOT_SUPPORTED = -32003
+	CODE_UNSUPPORTED_OPERATION           = -32004
+)
+
+type Message struct {
+	Kind       string         `json:"kind"`
+	MessageId  string         `json:"messageId"`
+	ContextId  string         `json:"contextId,omitempty"`
+	TaskId     string         `json:"taskId,omitempty"`
+	Role       string         `json:"role"`
+	Parts      []Part         `json:"parts"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	Extensions []string       `json:"extensions,omitempty"`
+}
+
+type Part struct {
+	Kind string         `json:"kind"`
+	Text string         `json:"text,omitempty"`
+	File *File          `json:"file,omitempty"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// File has either the bytes, base64 encoded in JSON, or the uri of the file.
+type File struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Bytes    []byte `json:"bytes,omitempty"`
+	Uri      string `json:"uri,omitempty"`
+}
+
+type Task struct {
+	Kind      string         `json:"kind"`
+	Id        string         `json:"id"`
+	ContextId string         `json:"contextId"`
+	Status    TaskStatus     `json:"status"`
+	Artifacts []Artifact     `json:"artifacts,omitempty"`
+	History   []Message      `json:"history,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+type TaskStatus struct {
+	State     string   `json:"state"`
+	Message   *Message `json:"message,omitempty"`
+	Timestamp string   `json:"timestamp,omitempty"`
+}
+
+type Artifact struct {
+	ArtifactId  string         `json:"artifactId"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Parts       []Part         `json:"parts"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Extensions  []string       `json:"extensions,omitempty"`
+}
+
+type TaskStatusUpdateEvent struct {
+	Kind      string         `json:"kind"`
+	TaskId    string         `json:"taskId"`
+	ContextId string         `json:"contextId"`
+	Status    TaskStatus     `json:"status"`
+	Final     bool           `json:"final"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+type TaskArtifactUpdateEvent struct {
+	Kind      string         `json:"kind"`
+	TaskId    string         `json:"taskId"`
+	ContextId string         `json:"contextId"`
+	Artifact  Artifact       `json:"artifact"`
+	Append    bool           `json:"append,omitempty"`
+	LastChunk bool           `json:"lastChunk,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+type MessageSendParams struct {
+	Message       *Message                  `json:"message"`
+	Configuration *MessageSendConfiguration `json:"configuration,omitempty"`
+	Metadata      map[string]any            `json:"metadata,omitempty"`
+}
+
+type MessageSendConfiguration struct {
+	AcceptedOutputModes    []string                `json:"acceptedOutputModes,omitempty"`
+	HistoryLength          int32                   `json:"historyLength,omitempty"`
+	PushNotificationConfig *PushNotificationConfig `json:"pushNotificationConfig,omitempty"`
+	Blocking               bool                    `json:"blocking,omitempty"`
+}
+
+type TaskQueryParams struct {
+	Id            string `json:"id"`
+	HistoryLength int32  `json:"historyLength,omitempty"`
+}
+
+type TaskIdParams struct {
+	Id string `json:"id"`
+}
+
+type PushNotificationConfigParams struct {
+	Id                       string `json:"id"`
+	PushNotificationConfigId string `json:"pushNotificationConfigId,omitempty"`
+}
+
+type PushNotificationConfig struct {
+	Id             string                          `json:"id,omitempty"`
+	Url            string                          `json:"url"`
+	Token          string                          `json:"token,omitempty"`
+	Authentication *PushNotificationAuthentication `json:"authentication,omitempty"`
+}
+
+type PushNotificationAuthentication struct {
+	Schemes     []string `json:"schemes"`
+	Credentials string   `json:"credentials,omitempty"`
+}
+
+type TaskPushNotificationConfig struct {
+	TaskId                 string                 `json:"taskId"`
+	PushNotificationConfig PushNotificationConfig `json:"pushNotificationConfig"`
+}
+
+// States of the tasks, by their protobuf value
+var taskStates = map[pb.TaskState]string{
+	pb.TaskState_TASK_STATE_UNSPECIFIED:    "unknown",
+	pb.TaskState_TASK_STATE_SUBMITTED:      "submitted",
+	pb.TaskState_TASK_STATE_WORKING:        "working",
+	pb.TaskState_TASK_STATE_COMPLETED:      "completed",
+	pb.TaskState_TASK_STATE_FAILED:         "failed",
+	pb.TaskState_TASK_STATE_CANCELLED:      "canceled",
+	pb.TaskState_TASK_STATE_INPUT_REQUIRED: "input-required",
+	pb.TaskState_TASK_STATE_REJECTED:       "rejected",
+	pb.TaskState_TASK_STATE_AUTH_REQUIRED:  "auth-required",
+}
+
+func toMessage(message *pb.Message) *Message {
+	if message == nil {
+		return nil
+	}
+
+	role := "user"
+	if message.GetRole() == pb.Role_ROLE_AGENT {
+		role = "agent"
+	}
+
+	return &Message{
+		Kind:       "message",
+		MessageId:  message.GetMessageId(),
+		ContextId:  message.GetContextId(),
+		TaskId:     message.GetTaskId(),
+		Role:       role,
+		Parts:      toParts(message.GetContent()),
+		Metadata:   message.GetMetadata().AsMap(),
+		Extensions: message.GetExtensions(),
+	}
+}
+
+func toParts(parts []*pb.Part) []Part {
+	converted := []Part{}
+
+	for _, part := range parts {
+		switch p := part.GetPart().(type) {
+		case *pb.Part_Text:
+			converted = append(converted, Part{Kind: "text", Text: p.Text})
+		case *pb.Part_File:
+			converted = append(converted, Part{Kind: "file", File: &File{
+				MimeType: p.File.GetMimeType(),
+				Bytes:    p.File.GetFileWithBytes(),
+				Uri:      p.File.GetFileWithUri(),
+			}})
+		case *pb.Part_Data:
+			converted = append(converted, Part{Kind: "data", Data: p.Data.GetData().AsMap()})
+		}
+	}
+
+	return converted
+}
+
+func toStatus(status *pb.TaskStatus) TaskStatus {
+	converted := TaskStatus{
+		State:   taskStates[status.GetState()],
+		Message: toMessage(status.GetUpdate()),
+	}
+
+	if status.GetTimestamp() != nil {
+		converted.Timestamp = status.GetTimestamp().AsTime().Format(time.RFC3339Nano)
+	}
+
+	return converted
+}
+
+func toArtifact(artifact *pb.Artifact) Artifact {
+	return Artifact{
+		ArtifactId:  artifact.GetArtifactId(),
+		Name:        artifact.GetName(),
+		Description: artifact.GetDescription(),
+		Parts:       toParts(artifact.GetParts()),
+		Metadata:    artifact.GetMetadata().AsMap(),
+		Extensions:  artifact.GetExtensions(),
+	}
+}
+
+func toTask(task *pb.Task) *Task {
+	converted := &Task{
+		Kind:      "task",
+		Id:        task.GetId(),
+		ContextId: task.GetContextId(),
+		Status:    toStatus(task.GetStatus()),
+		Metadata:  task.GetMetadata().AsMap(),
+	}
+
+	for _, artifact := range task.GetArtifacts() {
+		converted.Artifacts = append(converted.Artifacts, toArtifact(artifact))
+	}
+
+	for _, message := range task.GetHistory() {
+		converted.History = append(converted.History, *toMessage(message))
+	}
+
+	return converted
+}
+
+// toEvent converts the events of the streams, tasks, messages and updates.
+func toEvent(event *pb.StreamResponse) any {
+	switch payload := event.GetPayload().(type) {
+	case *pb.StreamResponse_Task:
+		return toTask(payload.Task)
+	case *pb.StreamResponse_Msg:
+		return toMessage(payload.Msg)
+	case *pb.StreamResponse_StatusUpdate:
+		return &TaskStatusUpdateEvent{
+			Kind:      "status-update",
+			TaskId:    payload.StatusUpdate.GetTaskId(),
+			ContextId: payload.StatusUpdate.GetContextId(),
+			Status:    toStatus(payload.StatusUpdate.GetStatus()),
+			Final:     payload.StatusUpdate.GetFinal(),
+			Metadata:  payload.StatusUpdate.GetMetadata().AsMap(),
+		}
+	case *pb.StreamResponse_ArtifactUpdate:
+		return &TaskArtifactUpdateEvent{
+			Kind:      "artifact-update",
+			TaskId:    payload.ArtifactUpdate.GetTaskId(),
+			ContextId: payload.ArtifactUpdate.GetContextId(),
+			Artifact:  toArtifact(payload.ArtifactUpdate.GetArtifact()),
+			Append:    payload.ArtifactUpdate.GetAppend(),
+			LastChunk: payload.ArtifactUpdate.GetLastChunk(),
+			Metadata:  payload.ArtifactUpdate.GetMetadata().AsMap(),
+		}
+	}
+
+	return nil
+}
+
+func toPushConfig(config *pb.TaskPushNotificationConfig) (*TaskPushNotificationConfig, error) {
+	taskId, _, err := tasks.ParsePushName(config.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	push := config.GetPushNotificationConfig()
+
+	converted := &TaskPushNotificationConfig{
+		TaskId: taskId,
+		PushNotificationConfig: PushNotificationConfig{
+			Id:    push.GetId(),
+			Url:   push.GetUrl(),
+			Token: push.GetToken(),
+		},
+	}
+
+	if authentication := push.GetAuthentication(); authentication != nil {
+		converted.PushNotificationConfig.Authentication = &PushNotificationAuthentication{
+			Schemes:     authentication.GetSchemes(),
+			Credentials: authentication.GetCredentials(),
+		}
+	}
+
+	return converted, nil
+}
+
+func fromMessage(message *Message) (*pb.Message, error) {
+	role := pb.Role_ROLE_USER
+	if message.Role == "agent" {
+		role = pb.Role_ROLE_AGENT
+	}
+
+	parts, err := fromParts(message.Parts)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := fromMap(message.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Message{
+		MessageId:  message.MessageId,
+		ContextId:  message.ContextId,
+		TaskId:     message.TaskId,
+		Role:       role,
+		Content:    parts,
+		Metadata:   metadata,
+		Extensions: message.Extensions,
+	}, nil
+}
+
+func fromParts(parts []Part) ([]*pb.Part, error) {
+	converted := []*pb.Part{}
+
+	for _, part := range parts {
+		switch part.Kind {
+		case "text":
+			converted = append(converted, &pb.Part{Part: &pb.Part_Text{Text: part.Text}})
+		case "file":
+			if part.File == nil {
+				return nil, fmt.Errorf("file part without file")
+			}
+
+			file := &pb.FilePart{MimeType: part.File.MimeType}
+
+			if part.File.Uri != "" {
+				file.File = &pb.FilePart_FileWithUri{FileWithUri: part.File.Uri}
+			} else {
+				file.File = &pb.FilePart_FileWithBytes{FileWithBytes: part.File.Bytes}
+			}
+
+			converted = append(converted, &pb.Part{Part: &pb.Part_File{File: file}})
+		case "data":
+			data, err := structpb.NewStruct(part.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid data part, %w", err)
+			}
+
+			converted = append(converted, &pb.Part{Part: &pb.Part_Data{Data: &pb.DataPart{Data: data}}})
+		default:
+			return nil, fmt.Errorf("part kind %q not supported", part.Kind)
+		}
+	}
+
+	return converted, nil
+}
+
+func fromPushConfig(config *PushNotificationConfig) *pb.PushNotificationConfig {
+	converted := &pb.PushNotificationConfig{
+		Id:    config.Id,
+		Url:   config.Url,
+		Token: config.Token,
+	}
+
+	if config.Authentication != nil {
+		converted.Authentication = &pb.AuthenticationInfo{
+			Schemes:     config.Authentication.Schemes,
+			Credentials: config.Authentication.Credentials,
+		}
+	}
+
+	return converted
+}
+
+func fromMap(values map[string]any) (*structpb.Struct, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	converted, err := structpb.NewStruct(values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata, %w", err)
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,125 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultUrl is the url of the agents without one, a unix socket.
+func DefaultUrl(name string) string {
+	return fmt.Sprintf("unix:///tmp/go-agent-%s.sock", name)
+}
+
+// ParseAddress returns the network and the address to listen at or dial of
+// an address, unix:///path/agent.sock, unix:path/agent.sock, tcp://host:port,
+// dns:///host:port, host:port, or an http or https url, at the port 80 or 443
+// when it has none.
+func ParseAddress(address string) (Protocol, string, error) {
+	if address == "" {
+		return "", "", fmt.Errorf("empty address")
+	}
+
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		parsed, err := url.Parse(address)
+		if err != nil || parsed.Hostname() == "" {
+			return "", "", fmt.Errorf("invalid address %q", address)
+		}
+
+		port := parsed.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[parsed.Scheme]
+		}
+
+		return PROTOCOL_TCP, net.JoinHostPort(parsed.Hostname(), port), nil
+	}
+
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		return PROTOCOL_UNIX, path, nil
+	}
+
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		return PROTOCOL_UNIX, path, nil
+	}
+
+	for _, scheme := range []string{"tcp://", "dns:///", "passthrough:///"} {
+		address = strings.TrimPrefix(address, scheme)
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", "", fmt.Errorf("invalid address %q, %w", address, err)
+	}
+
+	return PROTOCOL_TCP, address, nil
+}
+
+// Target returns the gRPC target of the url of an agent, http and https urls
+// are dialed at their host and port.
+func Target(address string) string {
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		if _, addr, err := ParseAddress(address); err == nil {
+			return addr
+		}
+	}
+
+	return address
+}
+
+// Listen listens at the address, removing the unix socket left by a process
+// that did not stop cleanly. The socket is removed when the listener closes.
+func Listen(address string) (net.Listener, error) {
+	protocol, addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if protocol == PROTOCOL_UNIX {
+		if err := removeStaleSocket(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	lis, err := net.Listen(string(protocol), addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listen at %s, %w", address, err)
+	}
+
+	return lis, nil
+}
+
+// removeStaleSocket removes the socket at path when no process accepts
+// connections on it.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error check socket %s, %w", path, err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("error listen at %s, the file exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("error listen at %s, the socket is in use", path)
+	}
+
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("error check socket %s, %w", path, err)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error remove stale socket %s, %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package agents_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	for address, expected := range map[string][2]string{
+		"unix:///tmp/agent.sock": {"unix", "/tmp/agent.sock"},
+		"unix:agent.sock":        {"unix", "agent.sock"},
+		"tcp://:8080":            {"tcp", ":8080"},
+		"dns:///localhost:8080":  {"tcp", "localhost:8080"},
+		"localhost:8080":         {"tcp", "localhost:8080"},
+		"https://agents.example": {"tcp", "agents.example:443"},
+		"http://localhost:8080/": {"tcp", "localhost:8080"},
+	} {
+		protocol, addr, err := agents.ParseAddress(address)
+		require.NoError(t, err, address)
+
+		assert.Equal(t, expected[0], string(protocol), address)
+		assert.Equal(t, expected[1], addr, address)
+	}
+
+	_, _, err := agents.ParseAddress("localhost")
+	assert.Error(t, err)
+}
+
+func TestListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+
+	t.Run("socket in use", func(t *testing.T) {
+		lis, err := agents.Listen("unix://" + path)
+		require.NoError(t, err)
+		defer lis.Close()
+
+		_, err = agents.Listen("unix://" + path)
+		assert.ErrorContains(t, err, "in use")
+	})
+
+	t.Run("stale socket", func(t *testing.T) {
+		// A listener that does not unlink leaves the socket like a killed process
+		lis, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+		require.NoError(t, err)
+		lis.SetUnlinkOnClose(false)
+		lis.Close()
+
+		assert.FileExists(t, path)
+
+		restarted, err := agents.Listen("unix://" + path)
+		require.NoError(t, err)
+
+		restarted.Close()
+
+		assert.NoFileExists(t, path)
+	})
+}
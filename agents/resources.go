@@ -0,0 +1,17 @@
+package agents
+
+import "context"
+
+type resourcesKey struct{}
+
+// WithResources attaches MCP resources to the messages sent with ctx. A
+// reference is server/uri, or a plain uri read from the first server of the
+// agent that has it.
+func WithResources(ctx context.Context, refs ...string) context.Context {
+	return context.WithValue(ctx, resourcesKey{}, append(ResourcesFromContext(ctx), refs...))
+}
+
+func ResourcesFromContext(ctx context.Context) []string {
+	refs, _ := ctx.Value(resourcesKey{}).([]string)
+	return refs
+}
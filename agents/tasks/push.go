@@ -0,0 +1,537 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+var (
+	ErrPushConfigNotFound = errors.New("push notification config not found")
+	ErrInvalidPushConfig  = errors.New("invalid push notification config")
+)
+
+const (
+	// HEADER_TOKEN carries the token of the config, so receivers can check
+	// the notification belongs to a task they follow
+	HEADER_TOKEN = "X-A2A-Notification-Token"
+	// HEADER_SIGNATURE and HEADER_TIMESTAMP carry the signature of payloads
+	// of configs with the SCHEME_HMAC authentication
+	HEADER_SIGNATURE = "X-A2A-Signature"
+	HEADER_TIMESTAMP = "X-A2A-Timestamp"
+
+	// Authentication schemes, the credentials are the bearer token or the
+	// key signing the payload
+	SCHEME_BEARER = "Bearer"
+	SCHEME_HMAC   = "HMAC-SHA256"
+)
+
+// PushName returns the resource name of a push notification config,
+// tasks/{id}/pushNotifications/{config_id}.
+func PushName(taskId, configId string) string {
+	return Name(taskId) + "/pushNotifications/" + configId
+}
+
+// ParsePushName returns the task and config ids of a push notification config
+// resource name.
+func ParsePushName(name string) (string, string, error) {
+	parts := strings.Split(name, "/")
+
+	if len(parts) != 4 || parts[0] != "tasks" || parts[2] != "pushNotifications" || parts[1] == "" || parts[3] == "" {
+		return "", "", fmt.Errorf("invalid push notification name %q, expected tasks/{id}/pushNotifications/{config_id}", name)
+	}
+
+	return parts[1], parts[3], nil
+}
+
+// Pusher POSTs the events of the tasks to the webhooks configured for them,
+// one delivery at a time per config and in order, retrying failed deliveries
+// with exponential backoff. The configs are kept in the store of the tasks
+// until the task is terminal.
+//
+// Webhooks at loopback, private or link-local addresses are rejected, but for
+// the AllowedHosts. The addresses are checked again when the default client
+// connects, so the name can not resolve elsewhere later.
+type Pusher struct {
+	Client       *http.Client
+	AllowedHosts []string
+	MaxRetries   int
+	Backoff      time.Duration
+	Logger       *slog.Logger
+
+	manager *Manager
+
+	mu      sync.Mutex
+	workers map[string]map[string]*pushWorker
+}
+
+// pushWorker delivers the events of a config.
+type pushWorker struct {
+	cancel context.CancelFunc
+}
+
+// WithPushClient sets the client of the deliveries, used as is without the
+// address checks of the default client.
+func WithPushClient(client *http.Client) func(*Pusher) {
+	return func(p *Pusher) {
+		p.Client = client
+	}
+}
+
+// WithPushAllowedHosts allows webhooks at these host names or ips, also at
+// loopback, private or link-local addresses.
+func WithPushAllowedHosts(hosts ...string) func(*Pusher) {
+	return func(p *Pusher) {
+		p.AllowedHosts = append(p.AllowedHosts, hosts...)
+	}
+}
+
+func WithPushRetries(retries int, backoff time.Duration) func(*Pusher) {
+	return func(p *Pusher) {
+		p.MaxRetries = retries
+		p.Backoff = backoff
+	}
+}
+
+func NewPusher(manager *Manager, options ...func(*Pusher)) *Pusher {
+	pusher := &Pusher{
+		MaxRetries: 3,
+		Backoff:    time.Second,
+		Logger:     slog.Default(),
+		manager:    manager,
+		workers:    map[string]map[string]*pushWorker{},
+	}
+
+	for _, o := range options {
+		o(pusher)
+	}
+
+	if pusher.Client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// A proxy would connect to the addresses in place of the pusher
+		transport.Proxy = nil
+		transport.DialContext = pusher.dial
+
+		pusher.Client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	}
+
+	return pusher
+}
+
+// Set adds or replaces a push notification config of the task and starts
+// delivering its events. Terminal tasks have no more events, their configs
+// are not kept.
+func (p *Pusher) Set(ctx context.Context, taskId string, config *pb.PushNotificationConfig) (*pb.TaskPushNotificationConfig, error) {
+	if err := p.Validate(ctx, config); err != nil {
+		return nil, err
+	}
+
+	config = proto.Clone(config).(*pb.PushNotificationConfig)
+
+	if config.Id == "" {
+		config.Id = uuid.NewString()
+	}
+
+	// Subscribe before reading the task, so no change is missed
+	subscription := p.manager.Subscribe(taskId)
+
+	task, err := p.manager.Get(ctx, taskId)
+	if err != nil {
+		subscription.Close()
+		return nil, err
+	}
+
+	if Terminal(task.GetStatus().GetState()) {
+		subscription.Close()
+		return taskPushConfig(taskId, config), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	configs, err := p.manager.Store.GetPushConfigs(ctx, taskId)
+	if err != nil {
+		subscription.Close()
+		return nil, err
+	}
+
+	configs = slices.DeleteFunc(configs, func(c *pb.PushNotificationConfig) bool {
+		return c.GetId() == config.GetId()
+	})
+
+	if err := p.manager.Store.SavePushConfigs(ctx, taskId, append(configs, config)); err != nil {
+		subscription.Close()
+		return nil, err
+	}
+
+	if previous, ok := p.workers[taskId][config.Id]; ok {
+		previous.cancel()
+	}
+
+	if p.workers[taskId] == nil {
+		p.workers[taskId] = map[string]*pushWorker{}
+	}
+
+	workerCtx, cancel := context.WithCancel(context.Background())
+
+	worker := &pushWorker{cancel: cancel}
+	p.workers[taskId][config.Id] = worker
+
+	go p.deliverAll(workerCtx, taskId, config, subscription, worker)
+
+	return taskPushConfig(taskId, config), nil
+}
+
+// ValidatePushConfig checks the webhook of the config is an http or https url.
+func ValidatePushConfig(config *pb.PushNotificationConfig) error {
+	webhook, err := url.Parse(config.GetUrl())
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+		return fmt.Errorf("error push notification url %q, expected an http or https url, %w", config.GetUrl(), ErrInvalidPushConfig)
+	}
+
+	return nil
+}
+
+// Validate checks the webhook of the config is an http or https url of an
+// allowed host, or resolving to public addresses.
+func (p *Pusher) Validate(ctx context.Context, config *pb.PushNotificationConfig) error {
+	if err := ValidatePushConfig(config); err != nil {
+		return err
+	}
+
+	webhook, _ := url.Parse(config.GetUrl())
+
+	if _, err := p.resolve(ctx, webhook.Hostname()); err != nil {
+		return fmt.Errorf("error push notification url %q, %w", config.GetUrl(), err)
+	}
+
+	return nil
+}
+
+// sharedAddresses is the shared address space of carrier-grade NAT
+// (RFC 6598), internal to the networks of providers.
+var sharedAddresses = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// internal reports whether the address is loopback, private, shared or
+// link-local.
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || sharedAddresses.Contains(ip) ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// resolve returns the addresses of the host, failing when one of them is
+// internal, nil for the allowed hosts.
+func (p *Pusher) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	if slices.ContainsFunc(p.AllowedHosts, func(allowed string) bool { return strings.EqualFold(allowed, host) }) {
+		return nil, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("error resolve %s, %w", host, err)
+	}
+
+	for _, ip := range ips {
+		if internal(ip) {
+			return nil, fmt.Errorf("host %s resolves to the internal address %s, %w", host, ip, ErrInvalidPushConfig)
+		}
+	}
+
+	return ips, nil
+}
+
+// dial connects to the checked addresses of the host, so it can not resolve
+// to an internal address after Validate.
+func (p *Pusher) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := p.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	if ips == nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	var errs []error
+
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (p *Pusher) Get(ctx context.Context, taskId, configId string) (*pb.TaskPushNotificationConfig, error) {
+	configs, err := p.manager.Store.GetPushConfigs(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.IndexFunc(configs, func(c *pb.PushNotificationConfig) bool {
+		return c.GetId() == configId
+	})
+
+	if index < 0 {
+		return nil, fmt.Errorf("error get %s, %w", PushName(taskId, configId), ErrPushConfigNotFound)
+	}
+
+	return taskPushConfig(taskId, configs[index]), nil
+}
+
+// List returns the configs of the task sorted by id.
+func (p *Pusher) List(ctx context.Context, taskId string) ([]*pb.TaskPushNotificationConfig, error) {
+	configs, err := p.manager.Store.GetPushConfigs(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(configs, func(a, b *pb.PushNotificationConfig) int {
+		return strings.Compare(a.GetId(), b.GetId())
+	})
+
+	listed := []*pb.TaskPushNotificationConfig{}
+
+	for _, config := range configs {
+		listed = append(listed, taskPushConfig(taskId, config))
+	}
+
+	return listed, nil
+}
+
+// Delete stops the deliveries of the config and forgets it.
+func (p *Pusher) Delete(ctx context.Context, taskId, configId string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	configs, err := p.manager.Store.GetPushConfigs(ctx, taskId)
+	if err != nil {
+		return err
+	}
+
+	kept := slices.DeleteFunc(slices.Clone(configs), func(c *pb.PushNotificationConfig) bool {
+		return c.GetId() == configId
+	})
+
+	if len(kept) == len(configs) {
+		return fmt.Errorf("error delete %s, %w", PushName(taskId, configId), ErrPushConfigNotFound)
+	}
+
+	if err := p.manager.Store.SavePushConfigs(ctx, taskId, kept); err != nil {
+		return err
+	}
+
+	if worker, ok := p.workers[taskId][configId]; ok {
+		worker.cancel()
+		p.removeWorker(taskId, configId)
+	}
+
+	return nil
+}
+
+// forget deletes the config once its task is terminal, unless it was replaced
+// meanwhile.
+func (p *Pusher) forget(taskId string, configId string, worker *pushWorker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.workers[taskId][configId] != worker {
+		return
+	}
+
+	p.removeWorker(taskId, configId)
+
+	ctx := context.Background()
+
+	configs, err := p.manager.Store.GetPushConfigs(ctx, taskId)
+	if err == nil {
+		configs = slices.DeleteFunc(configs, func(c *pb.PushNotificationConfig) bool {
+			return c.GetId() == configId
+		})
+
+		err = p.manager.Store.SavePushConfigs(ctx, taskId, configs)
+	}
+
+	if err != nil {
+		p.Logger.Error(fmt.Sprintf("error delete %s", PushName(taskId, configId)), "error", err)
+	}
+}
+
+// removeWorker forgets the worker of the config, the caller holds mu.
+func (p *Pusher) removeWorker(taskId, configId string) {
+	delete(p.workers[taskId], configId)
+
+	if len(p.workers[taskId]) == 0 {
+		delete(p.workers, taskId)
+	}
+}
+
+// taskPushConfig returns the config without its credentials, only the
+// webhook needs them.
+func taskPushConfig(taskId string, config *pb.PushNotificationConfig) *pb.TaskPushNotificationConfig {
+	redacted := proto.Clone(config).(*pb.PushNotificationConfig)
+
+	if redacted.GetAuthentication().GetCredentials() != "" {
+		redacted.Authentication.Credentials = ""
+	}
+
+	return &pb.TaskPushNotificationConfig{
+		Name:                   PushName(taskId, config.GetId()),
+		PushNotificationConfig: redacted,
+	}
+}
+
+// deliverAll sends the events of the task until it is terminal or the config
+// is deleted.
+func (p *Pusher) deliverAll(ctx context.Context, taskId string, config *pb.PushNotificationConfig, subscription *Subscription, worker *pushWorker) {
+	defer subscription.Close()
+	defer worker.cancel()
+
+	for {
+		event, err := subscription.Next(ctx)
+		if err != nil {
+			return
+		}
+
+		if err := p.deliver(ctx, config, event); err != nil {
+			p.Logger.Error(fmt.Sprintf("error push notification of task %s to %s", taskId, config.GetUrl()), "error", err)
+		}
+
+		if Terminal(event.GetStatusUpdate().GetStatus().GetState()) {
+			p.forget(taskId, config.GetId(), worker)
+			return
+		}
+	}
+}
+
+// deliver POSTs the event, retrying network errors, 429 and 5xx responses.
+func (p *Pusher) deliver(ctx context.Context, config *pb.PushNotificationConfig, event *pb.StreamResponse) error {
+	body, err := protojson.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encode push notification, %w", err)
+	}
+
+	backoff := p.Backoff
+
+	for attempt := 0; ; attempt++ {
+		retry, err := p.post(ctx, config, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= p.MaxRetries || errors.Is(err, ErrInvalidPushConfig) {
+			return err
+		}
+
+		p.Logger.Warn(fmt.Sprintf("error push notification to %s, retry in %s", config.GetUrl(), backoff), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func (p *Pusher) post(ctx context.Context, config *pb.PushNotificationConfig, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.GetUrl(), bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("error create push notification request, %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if config.GetToken() != "" {
+		req.Header.Set(HEADER_TOKEN, config.GetToken())
+	}
+
+	authentication := config.GetAuthentication()
+
+	if slices.Contains(authentication.GetSchemes(), SCHEME_BEARER) {
+		req.Header.Set("Authorization", "Bearer "+authentication.GetCredentials())
+	}
+
+	if slices.Contains(authentication.GetSchemes(), SCHEME_HMAC) {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+		req.Header.Set(HEADER_TIMESTAMP, timestamp)
+		req.Header.Set(HEADER_SIGNATURE, Sign(authentication.GetCredentials(), timestamp, body))
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("error send push notification, %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+	return retry, fmt.Errorf("error push notification rejected with status %s", resp.Status)
+}
+
+// Sign returns the signature of a push notification payload, the HMAC-SHA256
+// of timestamp.body.
+func Sign(key, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature headers of a push notification, with
+// a timestamp at most tolerance away from now to reject replays.
+func VerifySignature(key string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp := header.Get(HEADER_TIMESTAMP)
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid push notification timestamp %q", timestamp)
+	}
+
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("push notification timestamp out of tolerance, %s", age)
+	}
+
+	if !hmac.Equal([]byte(header.Get(HEADER_SIGNATURE)), []byte(Sign(key, timestamp, body))) {
+		return fmt.Errorf("invalid push notification signature")
+	}
+
+	return nil
+}
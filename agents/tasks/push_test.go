@@ -0,0 +1,189 @@
+package tasks_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/agents/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// receiver is a webhook recording the notifications it accepts, failing the
+// first failures requests.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests int
+	headers  []http.Header
+	events   []*pb.StreamResponse
+	done     chan struct{}
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+
+	event := &pb.StreamResponse{}
+	if err := protojson.Unmarshal(body, event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	header := req.Header.Clone()
+	header.Set("X-Verified", "false")
+
+	if tasks.VerifySignature("secret", req.Header, body, time.Minute) == nil {
+		header.Set("X-Verified", "true")
+	}
+
+	r.headers = append(r.headers, header)
+	r.events = append(r.events, event)
+
+	if tasks.Terminal(event.GetStatusUpdate().GetStatus().GetState()) {
+		close(r.done)
+	}
+}
+
+func TestPusher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("deliver with retries and signature", func(t *testing.T) {
+		webhook := &receiver{failures: 2, done: make(chan struct{})}
+		server := httptest.NewServer(webhook)
+		defer server.Close()
+
+		manager := tasks.NewManager(nil)
+		pusher := tasks.NewPusher(manager, tasks.WithPushRetries(3, time.Millisecond), tasks.WithPushAllowedHosts("127.0.0.1"))
+
+		task, err := manager.Create(ctx, "context-1", "", textMessage(pb.Role_ROLE_USER, "hello"))
+		require.NoError(t, err)
+
+		config, err := pusher.Set(ctx, task.GetId(), &pb.PushNotificationConfig{
+			Url:   server.URL,
+			Token: "token",
+			Authentication: &pb.AuthenticationInfo{
+				Schemes:     []string{tasks.SCHEME_HMAC, tasks.SCHEME_BEARER},
+				Credentials: "secret",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tasks.PushName(task.GetId(), config.GetPushNotificationConfig().GetId()), config.GetName())
+		assert.Empty(t, config.GetPushNotificationConfig().GetAuthentication().GetCredentials())
+
+		_, err = manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_WORKING, nil)
+		require.NoError(t, err)
+		_, err = manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_COMPLETED, textMessage(pb.Role_ROLE_AGENT, "bye"))
+		require.NoError(t, err)
+
+		select {
+		case <-webhook.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook not notified")
+		}
+
+		webhook.mu.Lock()
+		defer webhook.mu.Unlock()
+
+		assert.Equal(t, 4, webhook.requests)
+		require.Len(t, webhook.events, 2)
+		assert.Equal(t, pb.TaskState_TASK_STATE_WORKING, webhook.events[0].GetStatusUpdate().GetStatus().GetState())
+		assert.Equal(t, pb.TaskState_TASK_STATE_COMPLETED, webhook.events[1].GetStatusUpdate().GetStatus().GetState())
+
+		for _, header := range webhook.headers {
+			assert.Equal(t, "token", header.Get(tasks.HEADER_TOKEN))
+			assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+			assert.Equal(t, "true", header.Get("X-Verified"))
+		}
+
+		// The configs of terminal tasks are deleted
+		assert.Eventually(t, func() bool {
+			configs, err := pusher.List(ctx, task.GetId())
+			return err == nil && len(configs) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("internal addresses", func(t *testing.T) {
+		manager := tasks.NewManager(nil)
+		pusher := tasks.NewPusher(manager)
+
+		task, err := manager.Create(ctx, "context-1", "", textMessage(pb.Role_ROLE_USER, "hello"))
+		require.NoError(t, err)
+
+		for _, webhook := range []string{"http://127.0.0.1/hook", "http://10.0.0.1/hook", "http://169.254.169.254/latest", "http://100.100.100.200/latest", "http://[::1]/hook"} {
+			_, err = pusher.Set(ctx, task.GetId(), &pb.PushNotificationConfig{Url: webhook})
+			assert.ErrorIs(t, err, tasks.ErrInvalidPushConfig, webhook)
+		}
+	})
+
+	t.Run("configs", func(t *testing.T) {
+		manager := tasks.NewManager(nil)
+		pusher := tasks.NewPusher(manager, tasks.WithPushAllowedHosts("localhost"))
+
+		task, err := manager.Create(ctx, "context-1", "", textMessage(pb.Role_ROLE_USER, "hello"))
+		require.NoError(t, err)
+
+		_, err = pusher.Set(ctx, task.GetId(), &pb.PushNotificationConfig{Url: "file:///etc/passwd"})
+		assert.ErrorIs(t, err, tasks.ErrInvalidPushConfig)
+
+		_, err = pusher.Set(ctx, "missing", &pb.PushNotificationConfig{Url: "http://localhost"})
+		assert.ErrorIs(t, err, tasks.ErrTaskNotFound)
+
+		for _, id := range []string{"b", "a"} {
+			_, err = pusher.Set(ctx, task.GetId(), &pb.PushNotificationConfig{Id: id, Url: "http://localhost/" + id})
+			require.NoError(t, err)
+		}
+
+		configs, err := pusher.List(ctx, task.GetId())
+		require.NoError(t, err)
+		require.Len(t, configs, 2)
+		assert.Equal(t, "a", configs[0].GetPushNotificationConfig().GetId())
+
+		config, err := pusher.Get(ctx, task.GetId(), "b")
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost/b", config.GetPushNotificationConfig().GetUrl())
+
+		require.NoError(t, pusher.Delete(ctx, task.GetId(), "b"))
+
+		_, err = pusher.Get(ctx, task.GetId(), "b")
+		assert.ErrorIs(t, err, tasks.ErrPushConfigNotFound)
+	})
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"statusUpdate":{}}`)
+
+	header := http.Header{}
+	header.Set(tasks.HEADER_TIMESTAMP, "1700000000")
+	header.Set(tasks.HEADER_SIGNATURE, tasks.Sign("secret", "1700000000", body))
+
+	// Old timestamps are replays
+	assert.Error(t, tasks.VerifySignature("secret", header, body, time.Minute))
+
+	now := http.Header{}
+	now.Set(tasks.HEADER_TIMESTAMP, strconv.FormatInt(time.Now().Unix(), 10))
+	now.Set(tasks.HEADER_SIGNATURE, tasks.Sign("secret", now.Get(tasks.HEADER_TIMESTAMP), body))
+
+	assert.NoError(t, tasks.VerifySignature("secret", now, body, time.Minute))
+	assert.Error(t, tasks.VerifySignature("other", now, body, time.Minute))
+	assert.Error(t, tasks.VerifySignature("secret", now, []byte(`{}`), time.Minute))
+}
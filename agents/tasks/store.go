@@ -0,0 +1,415 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+var ErrTaskNotFound = errors.New("task not found")
+
+// Store persists the tasks of an agent and their push notification configs,
+// deleted with the task. Implementations return copies, the tasks and configs
+// they return can be modified by the caller.
+type Store interface {
+	Get(ctx context.Context, id string) (*pb.Task, error)
+	Save(ctx context.Context, task *pb.Task) error
+	Delete(ctx context.Context, id string) error
+
+	GetPushConfigs(ctx context.Context, taskId string) ([]*pb.PushNotificationConfig, error)
+	// SavePushConfigs replaces the configs of the task, none deletes them
+	SavePushConfigs(ctx context.Context, taskId string, configs []*pb.PushNotificationConfig) error
+}
+
+type StoreType string
+
+const (
+	STORE_MEMORY StoreType = "memory"
+	STORE_FILE   StoreType = "file"
+)
+
+// DEFAULT_TASK_RETENTION is the time the memory store keeps terminal tasks.
+const DEFAULT_TASK_RETENTION = 24 * time.Hour
+
+// NewStore builds a store of the given type, path is the directory of the
+// file store and retention the time the memory store keeps terminal tasks.
+func NewStore(storeType StoreType, path string, retention time.Duration) (Store, error) {
+	switch storeType {
+	case "", STORE_MEMORY:
+		return NewMemoryStore(WithRetention(retention)), nil
+	case STORE_FILE:
+		return NewFileStore(path)
+	}
+
+	return nil, fmt.Errorf("task store %s not supported", storeType)
+}
+
+// MemoryStore keeps the tasks until the agent stops, terminal tasks are
+// forgotten Retention after their last change.
+type MemoryStore struct {
+	Retention time.Duration
+
+	mu    sync.RWMutex
+	tasks map[string]*pb.Task
+	push  map[string][]*pb.PushNotificationConfig
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// WithRetention sets the time terminal tasks are kept, DEFAULT_TASK_RETENTION
+// when it is not positive.
+func WithRetention(retention time.Duration) func(*MemoryStore) {
+	return func(s *MemoryStore) {
+		if retention > 0 {
+			s.Retention = retention
+		}
+	}
+}
+
+func NewMemoryStore(options ...func(*MemoryStore)) *MemoryStore {
+	store := &MemoryStore{
+		Retention: DEFAULT_TASK_RETENTION,
+		tasks:     map[string]*pb.Task{},
+		push:      map[string][]*pb.PushNotificationConfig{},
+	}
+
+	for _, o := range options {
+		o(store)
+	}
+
+	return store
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*pb.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("error get task %s, %w", id, ErrTaskNotFound)
+	}
+
+	return proto.Clone(task).(*pb.Task), nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, task *pb.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks[task.GetId()] = proto.Clone(task).(*pb.Task)
+
+	s.expire()
+
+	return nil
+}
+
+// expire deletes the terminal tasks older than Retention, the caller holds
+// mu.
+func (s *MemoryStore) expire() {
+	deadline := time.Now().Add(-s.Retention)
+
+	for id, task := range s.tasks {
+		status := task.GetStatus()
+
+		if Terminal(status.GetState()) && status.GetTimestamp().AsTime().Before(deadline) {
+			delete(s.tasks, id)
+			delete(s.push, id)
+		}
+	}
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tasks, id)
+	delete(s.push, id)
+
+	return nil
+}
+
+func (s *MemoryStore) GetPushConfigs(ctx context.Context, taskId string) ([]*pb.PushNotificationConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return clonePushConfigs(s.push[taskId]), nil
+}
+
+func (s *MemoryStore) SavePushConfigs(ctx context.Context, taskId string, configs []*pb.PushNotificationConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(configs) == 0 {
+		delete(s.push, taskId)
+		return nil
+	}
+
+	s.push[taskId] = clonePushConfigs(configs)
+
+	return nil
+}
+
+func clonePushConfigs(configs []*pb.PushNotificationConfig) []*pb.PushNotificationConfig {
+	clones := make([]*pb.PushNotificationConfig, 0, len(configs))
+
+	for _, config := range configs {
+		clones = append(clones, proto.Clone(config).(*pb.PushNotificationConfig))
+	}
+
+	return clones
+}
+
+// FileStore keeps every task in a JSON file of a directory, {id}.json, and its
+// push notification configs in {id}.push.json, so tasks survive restarts of
+// the agent. The runs of the tasks do not, the tasks found
+// unfinished when the store opens fail.
+type FileStore struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+var _ Store = (*FileStore)(nil)
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("error create task store, empty path")
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error create task store %s, %w", dir, err)
+	}
+
+	store := &FileStore{dir: dir}
+
+	if err := store.failUnfinished(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// failUnfinished fails the tasks that were not terminal when the agent
+// stopped, nothing runs them anymore.
+func (s *FileStore) failUnfinished() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error load task store %s, %w", s.dir, err)
+	}
+
+	ctx := context.Background()
+
+	for _, file := range files {
+		if strings.HasSuffix(file, PUSH_FILE_SUFFIX) {
+			continue
+		}
+
+		task, err := s.Get(ctx, strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return fmt.Errorf("error load task store %s, %w", s.dir, err)
+		}
+
+		if Terminal(task.GetStatus().GetState()) {
+			continue
+		}
+
+		update := &pb.Message{
+			MessageId: uuid.NewString(),
+			TaskId:    task.GetId(),
+			ContextId: task.GetContextId(),
+			Role:      pb.Role_ROLE_AGENT,
+			Content:   []*pb.Part{{Part: &pb.Part_Text{Text: "task interrupted by a restart of the agent"}}},
+		}
+
+		task.History = append(task.History, update)
+		task.Status = &pb.TaskStatus{
+			State:     pb.TaskState_TASK_STATE_FAILED,
+			Update:    update,
+			Timestamp: timestamppb.Now(),
+		}
+
+		if err := s.Save(ctx, task); err != nil {
+			return fmt.Errorf("error load task store %s, %w", s.dir, err)
+		}
+	}
+
+	return nil
+}
+
+// PUSH_FILE_SUFFIX ends the files of the push notification configs.
+const PUSH_FILE_SUFFIX = ".push.json"
+
+// path returns the file of the task, ids naming other files are not found.
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || filepath.Base(id) != id || id[0] == '.' || strings.HasSuffix(id, ".push") {
+		return "", fmt.Errorf("error task id %q, %w", id, ErrTaskNotFound)
+	}
+
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *FileStore) pushPath(id string) (string, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(path, ".json") + PUSH_FILE_SUFFIX, nil
+}
+
+func (s *FileStore) Get(ctx context.Context, id string) (*pb.Task, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error get task %s, %w", id, ErrTaskNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error read task %s, %w", id, err)
+	}
+
+	task := &pb.Task{}
+	if err := protojson.Unmarshal(data, task); err != nil {
+		return nil, fmt.Errorf("error decode task %s, %w", id, err)
+	}
+
+	return task, nil
+}
+
+func (s *FileStore) Save(ctx context.Context, task *pb.Task) error {
+	path, err := s.path(task.GetId())
+	if err != nil {
+		return err
+	}
+
+	data, err := protojson.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("error encode task %s, %w", task.GetId(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := writeFile(path, data); err != nil {
+		return fmt.Errorf("error write task %s, %w", task.GetId(), err)
+	}
+
+	return nil
+}
+
+// writeFile replaces the file, readers never see it partially written.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, file := range []string{path, strings.TrimSuffix(path, ".json") + PUSH_FILE_SUFFIX} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error delete task %s, %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *FileStore) GetPushConfigs(ctx context.Context, taskId string) ([]*pb.PushNotificationConfig, error) {
+	path, err := s.pushPath(taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*pb.PushNotificationConfig{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error read push notification configs of task %s, %w", taskId, err)
+	}
+
+	stored := &pb.ListTaskPushNotificationResponse{}
+	if err := protojson.Unmarshal(data, stored); err != nil {
+		return nil, fmt.Errorf("error decode push notification configs of task %s, %w", taskId, err)
+	}
+
+	configs := []*pb.PushNotificationConfig{}
+
+	for _, config := range stored.GetConfigs() {
+		configs = append(configs, config.GetPushNotificationConfig())
+	}
+
+	return configs, nil
+}
+
+func (s *FileStore) SavePushConfigs(ctx context.Context, taskId string, configs []*pb.PushNotificationConfig) error {
+	path, err := s.pushPath(taskId)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(configs) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error delete push notification configs of task %s, %w", taskId, err)
+		}
+
+		return nil
+	}
+
+	stored := &pb.ListTaskPushNotificationResponse{}
+
+	for _, config := range configs {
+		stored.Configs = append(stored.Configs, &pb.TaskPushNotificationConfig{
+			Name:                   PushName(taskId, config.GetId()),
+			PushNotificationConfig: config,
+		})
+	}
+
+	data, err := protojson.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("error encode push notification configs of task %s, %w", taskId, err)
+	}
+
+	if err := writeFile(path, data); err != nil {
+		return fmt.Errorf("error write push notification configs of task %s, %w", taskId, err)
+	}
+
+	return nil
+}
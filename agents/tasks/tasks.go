@@ -0,0 +1,377 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+var ErrInvalidTransition = errors.New("invalid task transition")
+
+// METADATA_OWNER is the key of the metadata of a task naming the principal
+// that created it.
+const METADATA_OWNER = "owner"
+
+// Name returns the resource name of a task, tasks/{id}.
+func Name(id string) string {
+	return "tasks/" + id
+}
+
+// ParseName returns the id of a task resource name.
+func ParseName(name string) (string, error) {
+	id, ok := strings.CutPrefix(name, "tasks/")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", fmt.Errorf("invalid task name %q, expected tasks/{id}", name)
+	}
+
+	return id, nil
+}
+
+// Terminal reports whether the task finished, terminal tasks never change.
+func Terminal(state pb.TaskState) bool {
+	switch state {
+	case pb.TaskState_TASK_STATE_COMPLETED,
+		pb.TaskState_TASK_STATE_FAILED,
+		pb.TaskState_TASK_STATE_CANCELLED,
+		pb.TaskState_TASK_STATE_REJECTED:
+		return true
+	}
+
+	return false
+}
+
+// Interrupted reports whether the task waits for the client.
+func Interrupted(state pb.TaskState) bool {
+	switch state {
+	case pb.TaskState_TASK_STATE_INPUT_REQUIRED,
+		pb.TaskState_TASK_STATE_AUTH_REQUIRED:
+		return true
+	}
+
+	return false
+}
+
+// Owner returns the principal that created the task, empty when it was
+// created without one.
+func Owner(task *pb.Task) string {
+	return task.GetMetadata().GetFields()[METADATA_OWNER].GetStringValue()
+}
+
+// Manager drives the lifecycle of the tasks of an agent, saving every change
+// in the store and publishing it to the subscribers of the task.
+type Manager struct {
+	Store Store
+
+	mu          sync.Mutex
+	subscribers map[string][]*Subscription
+}
+
+func NewManager(store Store) *Manager {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	return &Manager{
+		Store:       store,
+		subscribers: map[string][]*Subscription{},
+	}
+}
+
+// Create saves a submitted task of owner, the principal sending the message
+// starting it, empty for none.
+func (m *Manager) Create(ctx context.Context, contextId, owner string, message *pb.Message) (*pb.Task, error) {
+	id := uuid.NewString()
+
+	message.TaskId = id
+	message.ContextId = contextId
+
+	task := &pb.Task{
+		Id:        id,
+		ContextId: contextId,
+		Status: &pb.TaskStatus{
+			State:     pb.TaskState_TASK_STATE_SUBMITTED,
+			Timestamp: timestamppb.Now(),
+		},
+		History: []*pb.Message{message},
+	}
+
+	if owner != "" {
+		task.Metadata = &structpb.Struct{Fields: map[string]*structpb.Value{
+			METADATA_OWNER: structpb.NewStringValue(owner),
+		}}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.Store.Save(ctx, task); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (*pb.Task, error) {
+	return m.Store.Get(ctx, id)
+}
+
+// Update moves the task to state, with the message of the agent describing
+// it. Terminal tasks fail with ErrInvalidTransition.
+func (m *Manager) Update(ctx context.Context, id string, state pb.TaskState, update *pb.Message) (*pb.Task, error) {
+	return m.update(ctx, id, func(task *pb.Task) error {
+		if Terminal(task.GetStatus().GetState()) {
+			return fmt.Errorf("error update task %s to %s, it is %s, %w", id, state, task.GetStatus().GetState(), ErrInvalidTransition)
+		}
+
+		if update != nil {
+			update.TaskId = task.GetId()
+			update.ContextId = task.GetContextId()
+			task.History = append(task.History, update)
+		}
+
+		task.Status = &pb.TaskStatus{
+			State:     state,
+			Update:    update,
+			Timestamp: timestamppb.Now(),
+		}
+
+		return nil
+	}, statusEvent)
+}
+
+// AddArtifact adds the artifact to the task, or appends its parts to the
+// artifact with the same id when appendParts is set, publishing the chunk.
+// Terminal tasks fail with ErrInvalidTransition.
+func (m *Manager) AddArtifact(ctx context.Context, id string, artifact *pb.Artifact, appendParts, lastChunk bool) (*pb.Task, error) {
+	return m.update(ctx, id, func(task *pb.Task) error {
+		if Terminal(task.GetStatus().GetState()) {
+			return fmt.Errorf("error add artifact %s to task %s, it is %s, %w", artifact.GetArtifactId(), id, task.GetStatus().GetState(), ErrInvalidTransition)
+		}
+
+		index := slices.IndexFunc(task.Artifacts, func(a *pb.Artifact) bool {
+			return a.GetArtifactId() == artifact.GetArtifactId()
+		})
+
+		switch {
+		case index < 0:
+			task.Artifacts = append(task.Artifacts, proto.Clone(artifact).(*pb.Artifact))
+		case appendParts:
+			stored := task.Artifacts[index]
+			for _, part := range artifact.GetParts() {
+				stored.Parts = append(stored.Parts, proto.Clone(part).(*pb.Part))
+			}
+		default:
+			task.Artifacts[index] = proto.Clone(artifact).(*pb.Artifact)
+		}
+
+		return nil
+	}, func(task *pb.Task) *pb.StreamResponse {
+		return &pb.StreamResponse{
+			Payload: &pb.StreamResponse_ArtifactUpdate{
+				ArtifactUpdate: &pb.TaskArtifactUpdateEvent{
+					TaskId:    task.GetId(),
+					ContextId: task.GetContextId(),
+					Artifact:  proto.Clone(artifact).(*pb.Artifact),
+					Append:    appendParts,
+					LastChunk: lastChunk,
+				},
+			},
+		}
+	})
+}
+
+// AddMessage appends a message of the client to the history of the task.
+func (m *Manager) AddMessage(ctx context.Context, id string, message *pb.Message) (*pb.Task, error) {
+	return m.update(ctx, id, func(task *pb.Task) error {
+		message.TaskId = task.GetId()
+		message.ContextId = task.GetContextId()
+		task.History = append(task.History, message)
+
+		return nil
+	}, nil)
+}
+
+// update applies change to the stored task, publishing the event of the
+// changed task when event is set.
+func (m *Manager) update(ctx context.Context, id string, change func(task *pb.Task) error, event func(task *pb.Task) *pb.StreamResponse) (*pb.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, err := m.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := change(task); err != nil {
+		return nil, err
+	}
+
+	if err := m.Store.Save(ctx, task); err != nil {
+		return nil, err
+	}
+
+	if event != nil {
+		m.publish(id, event(task))
+	}
+
+	return task, nil
+}
+
+func statusEvent(task *pb.Task) *pb.StreamResponse {
+	state := task.GetStatus().GetState()
+
+	return &pb.StreamResponse{
+		Payload: &pb.StreamResponse_StatusUpdate{
+			StatusUpdate: &pb.TaskStatusUpdateEvent{
+				TaskId:    task.GetId(),
+				ContextId: task.GetContextId(),
+				Status:    proto.Clone(task.GetStatus()).(*pb.TaskStatus),
+				Final:     Terminal(state) || Interrupted(state),
+			},
+		},
+	}
+}
+
+// Publish sends an event of the task to its subscribers.
+func (m *Manager) Publish(id string, event *pb.StreamResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.publish(id, event)
+}
+
+func (m *Manager) publish(id string, event *pb.StreamResponse) {
+	for _, subscription := range m.subscribers[id] {
+		subscription.push(event)
+	}
+}
+
+// Subscribe follows the events of the task until the subscription is closed.
+func (m *Manager) Subscribe(id string) *Subscription {
+	subscription := &Subscription{
+		notify: make(chan struct{}, 1),
+	}
+
+	subscription.close = func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.subscribers[id] = slices.DeleteFunc(m.subscribers[id], func(s *Subscription) bool {
+			return s == subscription
+		})
+
+		if len(m.subscribers[id]) == 0 {
+			delete(m.subscribers, id)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subscribers[id] = append(m.subscribers[id], subscription)
+
+	return subscription
+}
+
+// Wait blocks until the task is terminal or interrupted and returns it.
+func (m *Manager) Wait(ctx context.Context, id string) (*pb.Task, error) {
+	// Subscribe before reading the task, so no change is missed
+	subscription := m.Subscribe(id)
+	defer subscription.Close()
+
+	for {
+		task, err := m.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if state := task.GetStatus().GetState(); Terminal(state) || Interrupted(state) {
+			return task, nil
+		}
+
+		if _, err := subscription.Next(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Subscription queues the events of a task, slow readers never block the
+// task nor lose events.
+type Subscription struct {
+	mu     sync.Mutex
+	events []*pb.StreamResponse
+	notify chan struct{}
+	close  func()
+}
+
+func (s *Subscription) push(event *pb.StreamResponse) {
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Next returns the next event, waiting for it.
+func (s *Subscription) Next(ctx context.Context) (*pb.StreamResponse, error) {
+	for {
+		s.mu.Lock()
+		if len(s.events) > 0 {
+			event := s.events[0]
+			s.events = s.events[1:]
+			s.mu.Unlock()
+
+			return event, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.notify:
+		}
+	}
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+// Final reports whether the event is the last one of a stream, the task is
+// terminal or interrupted.
+func Final(event *pb.StreamResponse) bool {
+	switch payload := event.GetPayload().(type) {
+	case *pb.StreamResponse_StatusUpdate:
+		return payload.StatusUpdate.GetFinal()
+	case *pb.StreamResponse_Task:
+		state := payload.Task.GetStatus().GetState()
+		return Terminal(state) || Interrupted(state)
+	case *pb.StreamResponse_Msg:
+		return true
+	}
+
+	return false
+}
+
+// Trim keeps the last length messages of the history, all when length is not
+// positive.
+func Trim(task *pb.Task, length int32) *pb.Task {
+	if length > 0 && int(length) < len(task.History) {
+		task.History = task.History[len(task.History)-int(length):]
+	}
+
+	return task
+}
@@ -0,0 +1,188 @@
+package tasks_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/agents/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+func textMessage(role pb.Role, text string) *pb.Message {
+	return &pb.Message{
+		MessageId: text,
+		Role:      role,
+		Content:   []*pb.Part{{Part: &pb.Part_Text{Text: text}}},
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+
+	stores := map[string]func(t *testing.T) tasks.Store{
+		"memory": func(t *testing.T) tasks.Store {
+			return tasks.NewMemoryStore()
+		},
+		"file": func(t *testing.T) tasks.Store {
+			store, err := tasks.NewFileStore(t.TempDir())
+			require.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			manager := tasks.NewManager(newStore(t))
+
+			task, err := manager.Create(ctx, "context-1", "", textMessage(pb.Role_ROLE_USER, "hello"))
+			require.NoError(t, err)
+			assert.Equal(t, pb.TaskState_TASK_STATE_SUBMITTED, task.GetStatus().GetState())
+
+			subscription := manager.Subscribe(task.GetId())
+			defer subscription.Close()
+
+			_, err = manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_WORKING, nil)
+			require.NoError(t, err)
+
+			_, err = manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_COMPLETED, textMessage(pb.Role_ROLE_AGENT, "bye"))
+			require.NoError(t, err)
+
+			working, err := subscription.Next(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, pb.TaskState_TASK_STATE_WORKING, working.GetStatusUpdate().GetStatus().GetState())
+			assert.False(t, tasks.Final(working))
+
+			completed, err := subscription.Next(ctx)
+			require.NoError(t, err)
+			assert.True(t, tasks.Final(completed))
+
+			stored, err := manager.Get(ctx, task.GetId())
+			require.NoError(t, err)
+			assert.Equal(t, "context-1", stored.GetContextId())
+			assert.Len(t, stored.GetHistory(), 2)
+			assert.Equal(t, "bye", stored.GetStatus().GetUpdate().GetContent()[0].GetText())
+
+			_, err = manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_CANCELLED, nil)
+			assert.ErrorIs(t, err, tasks.ErrInvalidTransition)
+
+			_, err = manager.Get(ctx, "missing")
+			assert.ErrorIs(t, err, tasks.ErrTaskNotFound)
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	ctx := context.Background()
+	manager := tasks.NewManager(nil)
+
+	task, err := manager.Create(ctx, "context-1", "", textMessage(pb.Role_ROLE_USER, "hello"))
+	require.NoError(t, err)
+
+	// Wait returns the task whether the updates happen before or after it
+	// subscribes
+	go func() {
+		manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_WORKING, nil)
+		manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_INPUT_REQUIRED, textMessage(pb.Role_ROLE_AGENT, "approve?"))
+	}()
+
+	waited, err := manager.Wait(ctx, task.GetId())
+	require.NoError(t, err)
+	assert.Equal(t, pb.TaskState_TASK_STATE_INPUT_REQUIRED, waited.GetStatus().GetState())
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	_, err = manager.Update(ctx, task.GetId(), pb.TaskState_TASK_STATE_WORKING, nil)
+	require.NoError(t, err)
+
+	_, err = manager.Wait(timeout, task.GetId())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestParseName(t *testing.T) {
+	id, err := tasks.ParseName(tasks.Name("1234"))
+	require.NoError(t, err)
+	assert.Equal(t, "1234", id)
+
+	for _, name := range []string{"1234", "tasks/", "tasks/a/b", "agents/1234"} {
+		_, err := tasks.ParseName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestFileStoreIds(t *testing.T) {
+	store, err := tasks.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, id := range []string{"../escape", ".hidden", ""} {
+		_, err := store.Get(context.Background(), id)
+		assert.ErrorIs(t, err, tasks.ErrTaskNotFound, id)
+	}
+}
+
+func TestMemoryStoreRetention(t *testing.T) {
+	ctx := context.Background()
+	store := tasks.NewMemoryStore(tasks.WithRetention(time.Hour))
+
+	old := &pb.Task{
+		Id: "old",
+		Status: &pb.TaskStatus{
+			State:     pb.TaskState_TASK_STATE_COMPLETED,
+			Timestamp: timestamppb.New(time.Now().Add(-2 * time.Hour)),
+		},
+	}
+	waiting := &pb.Task{
+		Id: "waiting",
+		Status: &pb.TaskStatus{
+			State:     pb.TaskState_TASK_STATE_INPUT_REQUIRED,
+			Timestamp: timestamppb.New(time.Now().Add(-2 * time.Hour)),
+		},
+	}
+
+	require.NoError(t, store.Save(ctx, old))
+	require.NoError(t, store.Save(ctx, waiting))
+
+	_, err := store.Get(ctx, "old")
+	assert.ErrorIs(t, err, tasks.ErrTaskNotFound)
+
+	_, err = store.Get(ctx, "waiting")
+	assert.NoError(t, err)
+}
+
+func TestFileStoreRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := tasks.NewFileStore(dir)
+	require.NoError(t, err)
+
+	manager := tasks.NewManager(store)
+
+	working, err := manager.Create(ctx, "context-1", "", textMessage(pb.Role_ROLE_USER, "hello"))
+	require.NoError(t, err)
+
+	_, err = manager.Update(ctx, working.GetId(), pb.TaskState_TASK_STATE_INPUT_REQUIRED, textMessage(pb.Role_ROLE_AGENT, "approve?"))
+	require.NoError(t, err)
+
+	completed, err := manager.Create(ctx, "context-1", "", textMessage(pb.Role_ROLE_USER, "bye"))
+	require.NoError(t, err)
+
+	_, err = manager.Update(ctx, completed.GetId(), pb.TaskState_TASK_STATE_COMPLETED, nil)
+	require.NoError(t, err)
+
+	restarted, err := tasks.NewFileStore(dir)
+	require.NoError(t, err)
+
+	task, err := restarted.Get(ctx, working.GetId())
+	require.NoError(t, err)
+	assert.Equal(t, pb.TaskState_TASK_STATE_FAILED, task.GetStatus().GetState())
+
+	task, err = restarted.Get(ctx, completed.GetId())
+	require.NoError(t, err)
+	assert.Equal(t, pb.TaskState_TASK_STATE_COMPLETED, task.GetStatus().GetState())
+}
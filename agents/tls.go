@@ -0,0 +1,135 @@
+package agents
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TLS of the server and the client of an agent. With ClientCAFile the
+// server requires client certificates signed by it (mTLS), the client sends
+// ClientCertFile to the servers.
+type TLS struct {
+	// Certificate of the server
+	CertFile string
+	KeyFile  string
+
+	// CA verifying the clients, enables mTLS
+	ClientCAFile string
+
+	// CA verifying the servers, the system pool when empty
+	CAFile string
+	// Certificate of the client for servers with mTLS
+	ClientCertFile string
+	ClientKeyFile  string
+	// Name verified in the certificate of the servers, the host of the url
+	// when empty
+	ServerName string
+}
+
+// ServerConfig is the TLS config of the servers of the agent, nil without
+// TLS.
+func (t *TLS) ServerConfig() (*tls.Config, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	if t.CertFile == "" || t.KeyFile == "" {
+		return nil, fmt.Errorf("error tls, the server needs cert_file and key_file")
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error load server certificate, %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if t.ClientCAFile != "" {
+		pool, err := certPool(t.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// ClientConfig is the TLS config of the clients of the agent, nil without
+// TLS.
+func (t *TLS) ClientConfig() (*tls.Config, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if t.CAFile != "" {
+		pool, err := certPool(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	if t.ClientCertFile != "" || t.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.ClientCertFile, t.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error load client certificate, %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// ServerCredentials are the transport credentials of the gRPC servers,
+// insecure without TLS.
+func (t *TLS) ServerCredentials() (credentials.TransportCredentials, error) {
+	config, err := t.ServerConfig()
+	if err != nil || config == nil {
+		return insecure.NewCredentials(), err
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// ClientCredentials are the transport credentials of the gRPC clients,
+// insecure without TLS.
+func (t *TLS) ClientCredentials() (credentials.TransportCredentials, error) {
+	config, err := t.ClientConfig()
+	if err != nil || config == nil {
+		return insecure.NewCredentials(), err
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+func certPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error read ca %s, %w", file, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("error ca %s, no certificates found", file)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,131 @@
+package agents_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+type cardService struct {
+	pb.UnimplementedA2AServiceServer
+}
+
+func (s *cardService) GetAgentCard(ctx context.Context, in *pb.GetAgentCardRequest) (*pb.AgentCard, error) {
+	return &pb.AgentCard{Name: "secure"}, nil
+}
+
+// certificate writes a certificate signed by parent, self signed when nil,
+// and returns its files.
+func certificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (string, string, *x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+
+	return certFile, keyFile, cert, key
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	caFile, _, ca, caKey := certificate(t, dir, "ca", nil, nil)
+	serverCert, serverKey, _, _ := certificate(t, dir, "localhost", ca, caKey)
+	clientCert, clientKey, _, _ := certificate(t, dir, "client", ca, caKey)
+
+	config := &agents.TLS{
+		CertFile:     serverCert,
+		KeyFile:      serverKey,
+		ClientCAFile: caFile,
+		CAFile:       caFile,
+		ServerName:   "localhost",
+	}
+
+	creds, err := config.ServerCredentials()
+	require.NoError(t, err)
+
+	server := grpc.NewServer(grpc.Creds(creds))
+	pb.RegisterA2AServiceServer(server, &cardService{})
+
+	lis, err := agents.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	go server.Serve(lis)
+	defer server.Stop()
+
+	call := func(config *agents.TLS) (*pb.AgentCard, error) {
+		creds, err := config.ClientCredentials()
+		require.NoError(t, err)
+
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		return pb.NewA2AServiceClient(conn).GetAgentCard(ctx, &pb.GetAgentCardRequest{})
+	}
+
+	t.Run("client certificate", func(t *testing.T) {
+		withCert := *config
+		withCert.ClientCertFile = clientCert
+		withCert.ClientKeyFile = clientKey
+
+		card, err := call(&withCert)
+		require.NoError(t, err)
+
+		assert.Equal(t, "secure", card.GetName())
+	})
+
+	t.Run("without client certificate", func(t *testing.T) {
+		_, err := call(config)
+		assert.Error(t, err)
+	})
+}
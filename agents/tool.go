@@ -0,0 +1,198 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jlrosende/go-agents/memory"
+	"github.com/jlrosende/go-agents/tools"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+type AgentToolArgs struct {
+	Message string `json:"message" jsonschema_description:"Request for the agent, with all the context it needs to complete it"`
+}
+
+type agentTool struct {
+	agent      Agent
+	tool       mcp_tool.Tool
+	structured bool
+}
+
+var _ tools.Tool = (*agentTool)(nil)
+
+// NewAgentTool exposes an agent as a tool. The tool takes a message unless the
+// agent declares an input schema, then the arguments are sent as JSON. Agents
+// with a model are called in process, the rest (remote agents and workflows)
+// over A2A.
+func NewAgentTool(agent Agent) (tools.Tool, error) {
+
+	description := agent.GetDescription()
+	if description == "" {
+		description = fmt.Sprintf("Delegate a request to the agent %s", agent.GetName())
+	}
+
+	var inputSchema mcp_tool.ToolInputSchema
+	var err error
+
+	structured := agent.GetInputSchema() != nil
+
+	if structured {
+		inputSchema, err = tools.NewInputSchema(agent.GetInputSchema())
+	} else {
+		inputSchema, err = tools.ReflectInputSchema(new(AgentToolArgs))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error create tool of agent %s, %w", agent.GetName(), err)
+	}
+
+	return &agentTool{
+		agent: agent,
+		tool: mcp_tool.Tool{
+			Name:        agent.GetName(),
+			Description: description,
+			InputSchema: inputSchema,
+			// The tools of the agent are approved by its own policies
+			Annotations: mcp_tool.ToolAnnotation{
+				DestructiveHint: mcp_tool.ToBoolPtr(false),
+			},
+		},
+		structured: structured,
+	}, nil
+}
+
+func (t *agentTool) Definition() mcp_tool.Tool {
+	return t.tool
+}
+
+func (t *agentTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+
+	var message string
+
+	if t.structured {
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("error marshal arguments of agent %s, %w", t.agent.GetName(), err)
+		}
+
+		message = string(raw)
+	} else {
+		text, ok := args["message"].(string)
+		if !ok {
+			return mcp_tool.NewToolResultError("argument message is required"), nil
+		}
+
+		message = text
+	}
+
+	var content []mcp_tool.Content
+	var err error
+
+	ctx = childContext(ctx)
+
+	if t.agent.GetModel() != "" {
+		// Calls over A2A are authorized by the server of the agent
+		ctx, err = t.agent.Authorize(ctx)
+		if err != nil {
+			return mcp_tool.NewToolResultError(err.Error()), nil
+		}
+
+		content, err = t.agent.Generate(ctx, message)
+	} else {
+		content, err = sendA2A(ctx, t.agent, message)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp_tool.CallToolResult{Content: content}, nil
+}
+
+// childContext keeps the cancellation, approver and principal of the caller,
+// but the agent answers each call in a new conversation, without the
+// resources attached to the caller and without adding artifacts to its task.
+func childContext(ctx context.Context) context.Context {
+	ctx = memory.WithMemory(ctx, new(memory.Memory))
+	ctx = context.WithValue(ctx, resourcesKey{}, []string(nil))
+
+	return WithArtifactSink(ctx, nil)
+}
+
+func sendA2A(ctx context.Context, agent Agent, message string) ([]mcp_tool.Content, error) {
+
+	client := agent.GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("agent %s is not started", agent.GetName())
+	}
+
+	response, err := client.SendMessage(ctx, &pb.SendMessageRequest{
+		Configuration: &pb.SendMessageConfiguration{
+			Blocking: true,
+		},
+		Request: &pb.Message{
+			MessageId: uuid.NewString(),
+			Role:      pb.Role_ROLE_USER,
+			Content: []*pb.Part{
+				{
+					Part: &pb.Part_Text{
+						Text: message,
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error send message to agent %s, %w", agent.GetName(), err)
+	}
+
+	if task := response.GetTask(); task != nil {
+		if task.GetStatus().GetState() != pb.TaskState_TASK_STATE_COMPLETED {
+			return nil, fmt.Errorf("agent %s stopped in state %s, %s", agent.GetName(), task.GetStatus().GetState(), PartsText(task.GetStatus().GetUpdate().GetContent()))
+		}
+
+		return PartsToContent(task.GetStatus().GetUpdate().GetContent()), nil
+	}
+
+	return PartsToContent(response.GetMsg().GetContent()), nil
+}
+
+// PartsToContent converts A2A message parts to MCP content.
+func PartsToContent(parts []*pb.Part) []mcp_tool.Content {
+	content := []mcp_tool.Content{}
+
+	for _, part := range parts {
+		switch p := part.GetPart().(type) {
+		case *pb.Part_Text:
+			content = append(content, mcp_tool.NewTextContent(p.Text))
+		case *pb.Part_Data:
+			data, _ := json.Marshal(p.Data.GetData().AsMap())
+			content = append(content, mcp_tool.NewTextContent(string(data)))
+		case *pb.Part_File:
+			if uri := p.File.GetFileWithUri(); uri != "" {
+				content = append(content, mcp_tool.NewTextContent(uri))
+			}
+		}
+	}
+
+	return content
+}
+
+func PartsText(parts []*pb.Part) string {
+	text := ""
+
+	for _, content := range PartsToContent(parts) {
+		if c, ok := content.(mcp_tool.TextContent); ok {
+			text += c.Text + "\n"
+		}
+	}
+
+	return text
+}
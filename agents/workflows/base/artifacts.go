@@ -0,0 +1,290 @@
+package base
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/tools"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// wrapArtifacts makes the tools of the toolset send their results to the
+// artifact sink of the calls.
+func wrapArtifacts(toolset []tools.Tool) []tools.Tool {
+	wrapped := make([]tools.Tool, 0, len(toolset))
+
+	for _, tool := range toolset {
+		wrapped = append(wrapped, &artifactTool{Tool: tool})
+	}
+
+	return wrapped
+}
+
+// artifactTool sends the files, images, audio and structured content of the
+// results of the tool, and the files it writes, to the task calling it, while
+// the task runs.
+type artifactTool struct {
+	tools.Tool
+}
+
+func (t *artifactTool) Path() string {
+	return tools.Path(t.Tool)
+}
+
+func (t *artifactTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	result, err := t.Tool.Call(ctx, args)
+	if err != nil || result == nil || result.IsError {
+		return result, err
+	}
+
+	if sink, ok := agents.ArtifactSinkFromContext(ctx); ok {
+		sink(ctx, t.Definition(), t.Path(), args, result)
+	}
+
+	return result, nil
+}
+
+// resultArtifact converts the content of a tool result that is not text to
+// an artifact, one part per content, with the file written by the call
+// first. Results with text only have none, their text is part of the answer
+// of the agent.
+func resultArtifact(definition mcp_tool.Tool, tool string, args map[string]any, result *mcp_tool.CallToolResult) (*pb.Artifact, error) {
+	parts := []*pb.Part{}
+	metadata := map[string]any{"tool": tool}
+
+	if part, path := writtenFile(definition, args); part != nil {
+		parts = append(parts, part)
+		metadata["path"] = path
+	}
+
+	for _, content := range result.Content {
+		part, err := contentPart(content)
+		if err != nil {
+			return nil, fmt.Errorf("error artifact of tool %s, %w", tool, err)
+		}
+
+		if part != nil {
+			parts = append(parts, part)
+		}
+	}
+
+	if result.StructuredContent != nil {
+		part, err := dataPart(result.StructuredContent)
+		if err != nil {
+			return nil, fmt.Errorf("error artifact of tool %s, %w", tool, err)
+		}
+
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	structured, err := structpb.NewStruct(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Artifact{
+		ArtifactId:  uuid.NewString(),
+		Name:        tool,
+		Description: fmt.Sprintf("Result of the tool %s", tool),
+		Parts:       parts,
+		Metadata:    structured,
+	}, nil
+}
+
+// writtenFile returns the file written by a call of a tool that is not
+// read-only, named by the argument path as write_file and edit_file of the
+// filesystem server. The file has the bytes of the argument content when the
+// call sets them, it is a reference to the path otherwise.
+func writtenFile(definition mcp_tool.Tool, args map[string]any) (*pb.Part, string) {
+	if tools.ReadOnly(definition) {
+		return nil, ""
+	}
+
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return nil, ""
+	}
+
+	file := &pb.FilePart{
+		File:     &pb.FilePart_FileWithUri{FileWithUri: path},
+		MimeType: mimeType("", path, "application/octet-stream"),
+	}
+
+	if content, ok := args["content"].(string); ok {
+		file.File = &pb.FilePart_FileWithBytes{FileWithBytes: []byte(content)}
+		file.MimeType = mimeType("", path, "text/plain")
+	}
+
+	return filePart(file), path
+}
+
+func contentPart(content mcp_tool.Content) (*pb.Part, error) {
+	switch c := content.(type) {
+	case mcp_tool.ImageContent:
+		return bytesPart(c.Data, c.MIMEType)
+	case mcp_tool.AudioContent:
+		return bytesPart(c.Data, c.MIMEType)
+	case mcp_tool.ResourceLink:
+		return filePart(&pb.FilePart{
+			File:     &pb.FilePart_FileWithUri{FileWithUri: c.URI},
+			MimeType: mimeType(c.MIMEType, c.URI, "application/octet-stream"),
+		}), nil
+	case mcp_tool.EmbeddedResource:
+		switch r := c.Resource.(type) {
+		case mcp_tool.TextResourceContents:
+			return filePart(&pb.FilePart{
+				File:     &pb.FilePart_FileWithBytes{FileWithBytes: []byte(r.Text)},
+				MimeType: mimeType(r.MIMEType, r.URI, "text/plain"),
+			}), nil
+		case mcp_tool.BlobResourceContents:
+			return bytesPart(r.Blob, mimeType(r.MIMEType, r.URI, "application/octet-stream"))
+		}
+	}
+
+	return nil, nil
+}
+
+// bytesPart decodes the base64 data of images, audio and blobs.
+func bytesPart(data, mimeType string) (*pb.Part, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decode %s content, %w", mimeType, err)
+	}
+
+	return filePart(&pb.FilePart{
+		File:     &pb.FilePart_FileWithBytes{FileWithBytes: raw},
+		MimeType: mimeType,
+	}), nil
+}
+
+func filePart(file *pb.FilePart) *pb.Part {
+	return &pb.Part{Part: &pb.Part_File{File: file}}
+}
+
+// dataPart converts a JSON value to a DataPart, values that are not objects
+// are wrapped as {"result": value}.
+func dataPart(value any) (*pb.Part, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("error encode structured content, %w", err)
+	}
+
+	// Round trip so structpb gets plain JSON types
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("error decode structured content, %w", err)
+	}
+
+	object, ok := decoded.(map[string]any)
+	if !ok {
+		object = map[string]any{"result": decoded}
+	}
+
+	data, err := structpb.NewStruct(object)
+	if err != nil {
+		return nil, fmt.Errorf("error encode structured content, %w", err)
+	}
+
+	return &pb.Part{Part: &pb.Part_Data{Data: &pb.DataPart{Data: data}}}, nil
+}
+
+// mimeType returns the declared MIME type, the one of the extension of the
+// uri or fallback.
+func mimeType(declared, uri, fallback string) string {
+	if declared != "" {
+		return declared
+	}
+
+	if byExtension := mime.TypeByExtension(path.Ext(uri)); byExtension != "" {
+		return byExtension
+	}
+
+	return fallback
+}
+
+// limitArtifact keeps the parts of the artifact up to maxSize bytes. Text,
+// including text files, is cut at the size left, the parts that do not fit
+// otherwise are replaced by a note.
+func limitArtifact(artifact *pb.Artifact, maxSize int) *pb.Artifact {
+	left := maxSize
+
+	for i, part := range artifact.GetParts() {
+		size := proto.Size(part)
+		if size <= left {
+			left -= size
+			continue
+		}
+
+		file := part.GetFile()
+
+		// The size of the encoding of the part is over the size of its text
+		switch text, bytes := part.GetText(), file.GetFileWithBytes(); {
+		case text != "":
+			artifact.Parts[i] = &pb.Part{Part: &pb.Part_Text{Text: truncateText(text, left-(size-len(text)))}}
+		case bytes != nil && strings.HasPrefix(file.GetMimeType(), "text/"):
+			file.File = &pb.FilePart_FileWithBytes{FileWithBytes: []byte(truncateText(string(bytes), left-(size-len(bytes))))}
+		default:
+			artifact.Parts[i] = &pb.Part{Part: &pb.Part_Text{Text: fmt.Sprintf("content of %d bytes not included, over the artifact size limit", size)}}
+		}
+
+		left -= min(left, proto.Size(artifact.Parts[i]))
+	}
+
+	return artifact
+}
+
+// truncateText cuts the text to size bytes with a mark, never in the middle
+// of a character.
+func truncateText(text string, size int) string {
+	const mark = "\n... truncated"
+
+	if size <= len(mark) {
+		return ""
+	}
+
+	end := min(size-len(mark), len(text))
+	for end > 0 && end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	return text[:end] + mark
+}
+
+// responseArtifact is the answer of the agent, a DataPart when it is a JSON
+// object and text otherwise.
+func responseArtifact(text string) *pb.Artifact {
+	part := &pb.Part{Part: &pb.Part_Text{Text: text}}
+
+	if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "{") {
+		object := map[string]any{}
+
+		if json.Unmarshal([]byte(trimmed), &object) == nil {
+			if data, err := dataPart(object); err == nil {
+				part = data
+			}
+		}
+	}
+
+	return &pb.Artifact{
+		ArtifactId: uuid.NewString(),
+		Name:       "response",
+		Parts:      []*pb.Part{part},
+	}
+}
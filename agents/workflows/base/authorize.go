@@ -0,0 +1,40 @@
+package base
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jlrosende/go-agents/agents/auth"
+	"github.com/jlrosende/go-agents/tools"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// wrapSkills makes the tools of the toolset check that the principal of the
+// call may use them, the skills of the agent are its tools.
+func wrapSkills(toolset []tools.Tool) []tools.Tool {
+	wrapped := make([]tools.Tool, 0, len(toolset))
+
+	for _, tool := range toolset {
+		wrapped = append(wrapped, &skillTool{Tool: tool})
+	}
+
+	return wrapped
+}
+
+type skillTool struct {
+	tools.Tool
+}
+
+func (t *skillTool) Path() string {
+	return tools.Path(t.Tool)
+}
+
+func (t *skillTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	// The model gets the refusal like any tool error and can answer with it
+	if principal := auth.FromContext(ctx); !principal.Allowed(t.Path()) {
+		return mcp_tool.NewToolResultError(fmt.Sprintf("%s is not allowed to use the tool %s", principal, t.Path())), nil
+	}
+
+	return t.Tool.Call(ctx, args)
+}
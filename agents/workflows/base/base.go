@@ -1,25 +1,39 @@
 package base
 
 import (
-	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
-	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"slices"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/invopop/jsonschema"
 	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/agents/auth"
+	"github.com/jlrosende/go-agents/agents/gateway"
+	"github.com/jlrosende/go-agents/agents/host"
+	"github.com/jlrosende/go-agents/agents/jsonrpc"
+	"github.com/jlrosende/go-agents/agents/tasks"
 	"github.com/jlrosende/go-agents/llm/providers"
 	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/memory"
+	"github.com/jlrosende/go-agents/tools"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	mcp_client "github.com/mark3labs/mcp-go/client"
 	mcp_tool "github.com/mark3labs/mcp-go/mcp"
 
 	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
@@ -31,9 +45,23 @@ type BaseAgent struct {
 	Name        string
 	Description string
 
+	// JSON schema of the input when called as a tool, nil takes a message
+	InputSchema map[string]any
+
 	//A2A
-	Url      string // unix:///tmp/agent.sock, https://<agent>:<port>
+	Url      string // unix:///tmp/agent.sock, <agent>:<port>
 	Protocol agents.Protocol
+	// Address the server listens at, unix:///tmp/agent.sock or :<port>, the
+	// url when empty
+	Listen string
+	// TLS of the servers and the client, insecure when nil
+	TLS *agents.TLS
+	// Authentication and authorization of the requests, open when nil
+	Authorizer *auth.Authorizer
+	// Credentials of the calls of the client
+	Credentials *auth.ClientCredentials
+	// Served by a shared host instead of its own server
+	hosted bool
 
 	// MCP
 	Servers      []string
@@ -41,6 +69,52 @@ type BaseAgent struct {
 	ExcludeTools []string
 	mcpServers   map[string]*mcp.MCPServer
 
+	// Directories the MCP servers of the agent may access
+	Roots []string
+
+	// Servers reading any uri of the File parts of A2A messages, the rest
+	// read only the resources they list
+	FileServers []string
+
+	// MCP tools are sent to the model as server<ToolSeparator>tool, tools
+	// sharing a name fail unless ToolCollisions is warn
+	ToolSeparator  string
+	ToolCollisions tools.CollisionPolicy
+
+	// Go tools, filtered with IncludeTools and ExcludeTools like MCP tools
+	Tools []tools.Tool
+
+	// Reuses the results of read-only tools, disabled when nil
+	ToolCache *tools.ResultCache
+
+	// Agents called as tools
+	AgentTools []string
+	tooling    *toolState
+
+	// Tool approval, Approver answers the tools with policy ask of the calls
+	// in process, Send and Generate, e.g. tools.CLIApprover in local runs. A2A
+	// tasks always ask their client in TASK_STATE_INPUT_REQUIRED.
+	ToolApproval tools.ApprovalPolicies
+	Approver     tools.Approver
+
+	// A2A tasks, kept in memory when Tasks is nil
+	Tasks tasks.Store
+	tasks *tasks.Manager
+	// Webhooks notified of the changes of the tasks, PushAllowedHosts may be
+	// at internal addresses
+	PushAllowedHosts []string
+	pusher           *tasks.Pusher
+
+	// History of the A2A contexts, forgotten after ConversationTimeout idle
+	ConversationTimeout time.Duration
+	conversations       *memory.Conversations
+	runs                *runRegistry
+	// Tasks still running after RunTimeout fail, DEFAULT_RUN_TIMEOUT when 0
+	RunTimeout time.Duration
+	// Bytes of the parts of an artifact of a task, DEFAULT_MAX_ARTIFACT_SIZE
+	// when 0
+	MaxArtifactSize int
+
 	Logger *slog.Logger
 
 	// LLM
@@ -48,13 +122,36 @@ type BaseAgent struct {
 	Instructions string
 	llm          providers.LLM
 
+	// Model summarizing the tool results over the size limit, the model of
+	// the agent when it is empty
+	SummaryModel string
+	summaryLLM   providers.LLM
+
+	// MCP prompt used as instructions, server/prompt_name
+	InstructionsPrompt     string
+	InstructionsPromptArgs map[string]string
+
 	RequestParams *providers.RequestParams
 
+	// Declared fields of the agent card
+	Card *pb.AgentCard
+
+	// Address of the A2A JSON-RPC binding, serving the agent card at the
+	// well known paths, disabled when empty
+	HTTPAddr string
+
+	// Serves the REST binding of the google.api.http annotations on the port
+	// of the gRPC server
+	Gateway bool
+
 	// GRCP Server
 	pb.UnimplementedA2AServiceServer
 
 	//
 	Server *grpc.Server
+	// Options of the server added to ServerOptions when the agent serves
+	GRPCServerOptions []grpc.ServerOption
+
 	Client pb.A2AServiceClient
 }
 
@@ -70,9 +167,7 @@ func (a *BaseAgent) Start() error {
 
 	a.Logger.Debug("start SERVER")
 
-	err := a.StartServer(func(server *grpc.Server) {
-		pb.RegisterA2AServiceServer(a.Server, a)
-	})
+	err := a.StartServer(a)
 
 	if err != nil {
 		return fmt.Errorf("error start agent %s server, %w", a.GetName(), err)
@@ -101,30 +196,81 @@ func (a *BaseAgent) Initialize() error {
 			return fmt.Errorf("error initialize llm %s in agent %s, %w", a.Model, a.Name, err)
 		}
 
-		// Init clients and create missing configurations
-		a.llm.AttachTools(a.mcpServers, a.IncludeTools, a.ExcludeTools)
-	}
+		if a.summaryLLM != nil {
+			if err := a.summaryLLM.Initialize(); err != nil {
+				return fmt.Errorf("error initialize summary llm %s in agent %s, %w", a.SummaryModel, a.Name, err)
+			}
+		}
+
+		if err := a.loadInstructions(); err != nil {
+			return fmt.Errorf("error load instructions in agent %s, %w", a.Name, err)
+		}
+
+		a.tooling = &toolState{listed: map[string][]mcp_tool.Tool{}}
+
+		// Lazy servers are not started, their start tools are loaded instead
+		if err := a.loadTools(); err != nil {
+			return err
+		}
 
-	if a.Protocol == "" {
-		a.Protocol = agents.PROTOCOL_UNIX
+		// Follow tools/list_changed notifications and reconnections
+		for _, server := range a.mcpServers {
+			server.OnToolsChanged(func(listed []mcp_tool.Tool) {
+				a.reloadTools(server.Name, listed)
+			})
+		}
 	}
-	// Check config and configure
 
 	if a.Url == "" {
-		a.Url = fmt.Sprintf("unix:///tmp/%s.sock", a.Name)
+		a.Url = agents.DefaultUrl(a.Name)
+	}
+
+	protocol, _, err := agents.ParseAddress(a.ListenAddress())
+	if err != nil {
+		return fmt.Errorf("error agent %s listen address, %w", a.Name, err)
 	}
 
-	a.Server = grpc.NewServer()
+	a.Protocol = protocol
+
+	a.runs = newRunRegistry()
+	a.tasks = tasks.NewManager(a.Tasks)
+	a.pusher = tasks.NewPusher(a.tasks, tasks.WithPushAllowedHosts(a.PushAllowedHosts...), func(p *tasks.Pusher) { p.Logger = a.Logger })
+	a.conversations = memory.NewConversations(a.ConversationTimeout)
 
 	return nil
 }
 
 func (a *BaseAgent) StartClient() error {
 
+	creds, err := a.TLS.ClientCredentials()
+	if err != nil {
+		return fmt.Errorf("error agent %s client credentials, %w", a.GetName(), err)
+	}
+
+	// https urls use TLS, verified with the system roots without a config
+	if a.TLS == nil && strings.HasPrefix(a.Url, "https://") {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	options := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+
+	if a.Credentials != nil {
+		var perRPC credentials.PerRPCCredentials = a.Credentials
+
+		// Unix sockets never leave the machine, the rest needs TLS
+		if protocol, _, err := agents.ParseAddress(a.Url); err == nil && protocol == agents.PROTOCOL_UNIX {
+			perRPC = a.Credentials.Local()
+		}
+
+		options = append(options, grpc.WithPerRPCCredentials(perRPC))
+	}
+
+	if a.hosted {
+		options = append(options, host.RouteInterceptors(a.Name)...)
+	}
+
 	// Set up a connection to the server.
-	conn, err := grpc.NewClient(a.Url,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	conn, err := grpc.NewClient(agents.Target(a.Url), options...)
 	if err != nil {
 		return fmt.Errorf("can not create client for agent %s, %w", a.GetName(), err)
 	}
@@ -134,25 +280,131 @@ func (a *BaseAgent) StartClient() error {
 	return nil
 }
 
-func (a *BaseAgent) StartServer(serviceRegister func(server *grpc.Server)) error {
-	var lis net.Listener
-	var err error
+// HostOn serves the agent on the shared server h instead of its own, service
+// is the agent embedding BaseAgent when there is one. The client of the agent
+// calls h with its TLS, routed with the agent metadata.
+func (a *BaseAgent) HostOn(h *host.Host, service pb.A2AServiceServer) error {
+	url, err := h.Url()
+	if err != nil {
+		return fmt.Errorf("error host agent %s, %w", a.Name, err)
+	}
+
+	h.Add(a.Name, service, a.Authorizer)
+
+	a.Url = url
+	a.TLS = h.TLS
+	a.hosted = true
+
+	return nil
+}
+
+// ServerOptions are the credentials and the authorization interceptors of
+// the gRPC server of the agent.
+func (a *BaseAgent) ServerOptions() ([]grpc.ServerOption, error) {
+	creds, err := a.TLS.ServerCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("error agent %s server credentials, %w", a.Name, err)
+	}
+
+	options := []grpc.ServerOption{grpc.Creds(creds)}
+
+	if a.Authorizer != nil {
+		options = append(options,
+			grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor(a.Authorizer)),
+			grpc.ChainStreamInterceptor(auth.StreamServerInterceptor(a.Authorizer)),
+		)
+	}
+
+	return options, nil
+}
+
+// httpHandler authorizes the requests of handler when the agent has an
+// authorizer.
+func (a *BaseAgent) httpHandler(handler http.Handler) http.Handler {
+	if a.Authorizer == nil {
+		return handler
+	}
+
+	return auth.Middleware(a.Authorizer, handler)
+}
+
+// StartServer serves the service over gRPC, with the REST gateway when
+// Gateway is set, and over JSON-RPC with the agent card when HTTPAddr is set,
+// until the process is signaled to stop. Hosted agents have no server.
+func (a *BaseAgent) StartServer(service pb.A2AServiceServer) error {
+	if a.hosted {
+		a.Logger.Info(fmt.Sprintf("agent %s served by the host at %s", a.Name, a.Url))
+		return nil
+	}
+
+	// The server credentials are only needed by the agents that serve
+	options, err := a.ServerOptions()
+	if err != nil {
+		return err
+	}
+
+	a.Server = grpc.NewServer(append(options, a.GRPCServerOptions...)...)
 
-	switch a.Protocol {
-	case agents.PROTOCOL_TCP:
-		lis, err = net.Listen("tcp", fmt.Sprintf(":%d", 1234))
-	case agents.PROTOCOL_UNIX:
-		lis, err = net.Listen("unix", fmt.Sprintf("/tmp/go-agent-%s.sock", a.GetName()))
+	tlsConfig, err := a.TLS.ServerConfig()
+	if err != nil {
+		return fmt.Errorf("error agent %s tls, %w", a.Name, err)
 	}
 
+	// Unix sockets are removed when the listener closes
+	lis, err := agents.Listen(a.ListenAddress())
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
+	defer lis.Close()
 
-	serviceRegister(a.Server)
+	pb.RegisterA2AServiceServer(a.Server, service)
 
 	a.Logger.Info(fmt.Sprintf("agent %s listening at %v", a.Name, lis.Addr()))
 
+	var gatewayServer *http.Server
+
+	if a.Gateway {
+		handler, err := gateway.NewHandler(context.Background(), service, a.Logger)
+		if err != nil {
+			return fmt.Errorf("error start agent %s gateway, %w", a.Name, err)
+		}
+
+		gatewayServer = &http.Server{
+			Handler:           gateway.Handler(a.Server, a.httpHandler(handler)),
+			Protocols:         gateway.Protocols(),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		a.Logger.Info(fmt.Sprintf("agent %s gateway listening at %v", a.Name, lis.Addr()))
+	}
+
+	var httpServer *http.Server
+
+	if a.HTTPAddr != "" {
+		httpServer = &http.Server{
+			Addr:              a.HTTPAddr,
+			Handler:           a.httpHandler(jsonrpc.NewHandler(service, a.Logger)),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			a.Logger.Info(fmt.Sprintf("agent %s json-rpc listening at %s", a.Name, a.HTTPAddr))
+
+			var err error
+			if tlsConfig != nil {
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.Logger.Error(fmt.Sprintf("error serve agent %s json-rpc", a.Name), "error", err)
+			}
+		}()
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(
 		sigCh, os.Interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM,
@@ -164,24 +416,53 @@ func (a *BaseAgent) StartServer(serviceRegister func(server *grpc.Server)) error
 		s := <-sigCh
 		a.Logger.Info(fmt.Sprintf("got signal %v, attempting graceful shutdown", s))
 
+		if httpServer != nil {
+			httpServer.Shutdown(context.Background())
+		}
+
+		if gatewayServer != nil {
+			gatewayServer.Shutdown(context.Background())
+		}
+
 		a.Server.GracefulStop()
 		// grpc.Stop() // leads to error while receiving stream response: rpc error: code = Unavailable desc = transport is closing
 		wg.Done()
 	}()
 
-	if err := a.Server.Serve(lis); err != nil {
-		return fmt.Errorf("failed to serve: %@", err)
+	if gatewayServer != nil && tlsConfig != nil {
+		err = gatewayServer.ServeTLS(lis, "", "")
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	} else if gatewayServer != nil {
+		err = gatewayServer.Serve(lis)
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	} else {
+		err = a.Server.Serve(lis)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to serve: %w", err)
 	}
 
 	wg.Wait()
 
 	a.Logger.Info(fmt.Sprintf("clean agent %s shutdown", a.Name))
 
-	defer lis.Close()
-
 	return nil
 }
 
+// ListenAddress is the address the server listens at, Listen or the url.
+func (a *BaseAgent) ListenAddress() string {
+	if a.Listen != "" {
+		return a.Listen
+	}
+
+	return a.Url
+}
+
 func (a *BaseAgent) SetProtocol(protocol agents.Protocol) {
 	a.Protocol = protocol
 }
@@ -190,6 +471,18 @@ func (a BaseAgent) GetName() string {
 	return a.Name
 }
 
+func (a BaseAgent) GetDescription() string {
+	return a.Description
+}
+
+func (a BaseAgent) GetInputSchema() map[string]any {
+	return a.InputSchema
+}
+
+func (a BaseAgent) GetServers() []string {
+	return a.Servers
+}
+
 func (a BaseAgent) GetModel() string {
 	return a.Model
 }
@@ -206,22 +499,219 @@ func (a *BaseAgent) AttachLLM(llm providers.LLM) {
 	a.llm = llm
 }
 
+// AttachSummaryLLM sets the model of SummaryModel.
+func (a *BaseAgent) AttachSummaryLLM(llm providers.LLM) {
+	a.summaryLLM = llm
+}
+
 func (a *BaseAgent) AttachMCPServers(servers map[string]*mcp.MCPServer) {
 
 	if a.mcpServers == nil {
 		a.mcpServers = map[string]*mcp.MCPServer{}
 	}
 
+	roots := []mcp_tool.Root{}
+
+	for _, root := range a.Roots {
+		roots = append(roots, mcp.NewRoot(root))
+	}
+
 	for name, server := range servers {
 		if slices.Contains(a.Servers, name) {
 			a.mcpServers[name] = server
+			server.AddRoots(roots...)
 		}
 	}
 }
 
-func (a *BaseAgent) Send(message string) (string, error) {
-	response, err := a.Generate(message)
+// AttachAgents adds the agents listed in AgentTools as tools. Agents missing
+// from agentMap, as the disabled ones, and agents calling back this one
+// through their own agent tools are rejected.
+func (a *BaseAgent) AttachAgents(agentMap map[string]agents.Agent) error {
+
+	for _, name := range a.AgentTools {
+		if _, ok := agentMap[name]; !ok {
+			return fmt.Errorf("agent tool %s of agent %s not found or disabled", name, a.Name)
+		}
+	}
+
+	if cycle := agentToolCycle(a.Name, a.AgentTools, agentMap, []string{a.Name}); cycle != nil {
+		return fmt.Errorf("agent tools of agent %s form the cycle %s", a.Name, strings.Join(cycle, " -> "))
+	}
+
+	for _, name := range a.AgentTools {
+		agent := agentMap[name]
+
+		tool, err := agents.NewAgentTool(agent)
+		if err != nil {
+			return fmt.Errorf("error create agent tool %s, %w", name, err)
+		}
+
+		a.AddTools(tool)
+	}
+
+	return nil
+}
+
+// agentToolCycle returns the path from start back to itself through the agent
+// tools of the agents, nil when there is none.
+func agentToolCycle(start string, agentTools []string, agentMap map[string]agents.Agent, path []string) []string {
+	for _, name := range agentTools {
+		if name == start {
+			return append(slices.Clone(path), name)
+		}
+
+		// Agents already in the path are part of another cycle
+		if slices.Contains(path, name) {
+			continue
+		}
+
+		caller, ok := agentMap[name].(interface{ GetAgentTools() []string })
+		if !ok {
+			continue
+		}
+
+		if cycle := agentToolCycle(start, caller.GetAgentTools(), agentMap, append(path, name)); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// GetAgentTools returns the names of the agents called as tools.
+func (a *BaseAgent) GetAgentTools() []string {
+	return a.AgentTools
+}
+
+func (a *BaseAgent) AddTools(toolset ...tools.Tool) {
+	a.Tools = append(a.Tools, toolset...)
+}
+
+type toolState struct {
+	mu     sync.Mutex
+	loaded bool
+
+	// Tools listed by each MCP server, a reload lists only the server that
+	// changed them
+	listed map[string][]mcp_tool.Tool
+
+	// Skills of the card, the tools attached
+	skills []*pb.AgentSkill
+}
+
+// loadTools attaches the tools to the llm the first time it is called.
+func (a *BaseAgent) loadTools() error {
+	a.tooling.mu.Lock()
+	defer a.tooling.mu.Unlock()
+
+	if a.tooling.loaded {
+		return nil
+	}
+
+	toolset, err := a.toolset()
+	if err != nil {
+		return fmt.Errorf("error load tools in agent %s, %w", a.Name, err)
+	}
+
+	if err := a.llm.AttachTools(toolset); err != nil {
+		return fmt.Errorf("error attach tools in agent %s, %w", a.Name, err)
+	}
+
+	a.tooling.loaded = true
+
+	return nil
+}
+
+// reloadTools attaches the tools again when the server changes them to listed,
+// unless they were never loaded. The tools of the other servers are kept.
+func (a *BaseAgent) reloadTools(server string, listed []mcp_tool.Tool) {
+	a.tooling.mu.Lock()
+	defer a.tooling.mu.Unlock()
+
+	a.tooling.listed[server] = listed
+
+	if !a.tooling.loaded {
+		return
+	}
+
+	toolset, err := a.toolset()
 	if err != nil {
+		a.Logger.Error("error reload tools", "error", err)
+		return
+	}
+
+	if err := a.llm.AttachTools(toolset); err != nil {
+		a.Logger.Error("error reload tools", "error", err)
+		return
+	}
+
+	a.Logger.Info("tools reloaded", "tools", len(toolset))
+}
+
+// toolset joins the tools of the MCP servers and the Go tools of the agent.
+// A server failing to list its tools is left out, the rest are attached. The
+// caller holds tooling.mu.
+func (a *BaseAgent) toolset() ([]tools.Tool, error) {
+	toolset := []tools.Tool{}
+
+	// Follow the configuration order, the first tool wins a collision
+	for _, name := range a.Servers {
+		server, ok := a.mcpServers[name]
+		if !ok {
+			continue
+		}
+
+		// Lazy servers start when the model calls their start tool
+		if server.Lazy && !server.Started() {
+			toolset = append(toolset, a.startServerTool(server))
+			continue
+		}
+
+		listed, ok := a.tooling.listed[name]
+		if !ok {
+			var err error
+
+			listed, err = server.ListTools()
+			if err != nil {
+				a.Logger.Error(fmt.Sprintf("error list tools of mcp server %s, its tools are not attached", name), "error", err)
+				continue
+			}
+
+			a.tooling.listed[name] = listed
+		}
+
+		for _, tool := range listed {
+			toolset = append(toolset, tools.NewMCPTool(server, tool, a.ToolSeparator))
+		}
+	}
+
+	toolset = append(toolset, a.Tools...)
+
+	toolset, collisions := tools.Deduplicate(tools.Filter(toolset, a.IncludeTools, a.ExcludeTools))
+
+	for name, paths := range collisions {
+		if a.ToolCollisions != tools.COLLISION_WARN {
+			return nil, fmt.Errorf("error tool name %s used by %s, exclude all but one", name, strings.Join(paths, ", "))
+		}
+
+		a.Logger.Warn(fmt.Sprintf("tool name %s used by %s, using %s", name, strings.Join(paths, ", "), paths[0]))
+	}
+
+	a.tooling.skills = toolSkills(toolset)
+
+	if a.ToolCache != nil {
+		toolset = a.ToolCache.Wrap(toolset)
+	}
+
+	return tools.Trace(wrapArtifacts(wrapSkills(toolset)), a.Logger), nil
+}
+
+// Send returns the text of the response. When the model does not finish, the
+// text generated so far is returned with the error.
+func (a *BaseAgent) Send(ctx context.Context, message string) (string, error) {
+	response, err := a.Generate(ctx, message)
+	if err != nil && len(response) == 0 {
 		return "", err
 	}
 
@@ -229,11 +719,24 @@ func (a *BaseAgent) Send(message string) (string, error) {
 
 	result := mcp.Result(response)
 
-	return result.AllText(), nil
+	return result.AllText(), err
 }
 
-func (a *BaseAgent) Generate(message string) ([]mcp_tool.Content, error) {
-	response, err := a.llm.Generate(message)
+func (a *BaseAgent) Generate(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+	if err := a.loadTools(); err != nil {
+		return nil, err
+	}
+
+	message, err := a.withResources(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := a.llm.Generate(a.toolContext(ctx), message)
+
+	if errors.Is(err, providers.ErrMaxIterations) {
+		return response, err
+	}
 
 	if err != nil {
 		return nil, err
@@ -242,7 +745,42 @@ func (a *BaseAgent) Generate(message string) ([]mcp_tool.Content, error) {
 	return response, nil
 }
 
-func (a BaseAgent) Structured(message string, responseStruct any) ([]mcp_tool.Content, error) {
+// toolContext installs the tool policies of the agent and the handlers of the
+// requests its MCP servers make while it calls them: sampling with the llm of
+// the agent, approved as the tool server/sampling, and elicitation with the
+// approver. The approver already in ctx, of an A2A task or the agent calling
+// this one, wins over the configured Approver. Tool results are summarized
+// with the summary llm, or the llm of the agent.
+func (a BaseAgent) toolContext(ctx context.Context) context.Context {
+	if a.Approver != nil && tools.ApproverFromContext(ctx) == nil {
+		ctx = tools.WithApprover(ctx, a.Approver)
+	}
+
+	if elicitor, ok := tools.ApproverFromContext(ctx).(mcp_client.ElicitationHandler); ok {
+		ctx = mcp.WithElicitor(ctx, elicitor)
+	}
+
+	ctx = mcp.WithSampler(ctx, approvedSampler{a.llm})
+
+	summarizer := a.summaryLLM
+	if summarizer == nil {
+		summarizer = a.llm
+	}
+
+	ctx = tools.WithSummarizer(ctx, providers.Summarizer(summarizer))
+
+	return tools.WithApprovalPolicies(ctx, a.ToolApproval)
+}
+
+func (a BaseAgent) Structured(ctx context.Context, message string, responseStruct any) ([]mcp_tool.Content, error) {
+	if err := a.loadTools(); err != nil {
+		return nil, err
+	}
+
+	message, err := a.withResources(ctx, message)
+	if err != nil {
+		return nil, err
+	}
 
 	reflector := jsonschema.Reflector{
 		AllowAdditionalProperties: false,
@@ -252,7 +790,7 @@ func (a BaseAgent) Structured(message string, responseStruct any) ([]mcp_tool.Co
 	schema := reflector.Reflect(responseStruct)
 	// return schema
 
-	response, err := a.llm.Structured(message, schema)
+	response, err := a.llm.Structured(a.toolContext(ctx), message, schema)
 
 	if err != nil {
 		return nil, err
@@ -261,77 +799,659 @@ func (a BaseAgent) Structured(message string, responseStruct any) ([]mcp_tool.Co
 	return response, nil
 }
 
+// GetAgentCard returns the card of the agent, the declared fields of Card
+// completed with what the agent implements. Without declared skills, every
+// tool attached to the agent is a skill.
 func (a *BaseAgent) GetAgentCard(ctx context.Context, in *pb.GetAgentCardRequest) (*pb.AgentCard, error) {
-	return &pb.AgentCard{
-		Name:        a.Name,
-		Description: a.Description,
-		Url:         "http:://<url>:<port>",
-		Version:     "0.0.2",
-		Capabilities: &pb.AgentCapabilities{
-			Streaming:         true,
-			PushNotifications: true,
-		},
-		DefaultInputModes:  []string{"text", "audio"},
-		DefaultOutputModes: []string{"text", "audio"},
-		Skills: []*pb.AgentSkill{
-			{
-				Id:          "filesystem",
-				Name:        "FileSystem",
-				Description: "hj0",
-				Tags:        []string{"jhgbvkjhbvkjhv"},
-			},
-		},
-	}, nil
+	card := &pb.AgentCard{}
+	if a.Card != nil {
+		card = proto.Clone(a.Card).(*pb.AgentCard)
+	}
+
+	card.Name = a.Name
+	card.Description = a.Description
+
+	// Unix sockets are not reachable by the readers of the card
+	if protocol, _, err := agents.ParseAddress(a.Url); card.Url == "" && err == nil && protocol != agents.PROTOCOL_UNIX {
+		card.Url = a.Url
+	}
+
+	if card.Version == "" {
+		card.Version = agents.Version
+	}
+
+	card.Capabilities = &pb.AgentCapabilities{
+		Streaming:         a.tasks != nil,
+		PushNotifications: a.tasks != nil,
+	}
+
+	// Messages are what the model reads, JSON only with an input schema,
+	// files of tools are artifacts of their own type
+	if len(card.DefaultInputModes) == 0 {
+		card.DefaultInputModes = a.inputModes()
+	}
+
+	if len(card.DefaultOutputModes) == 0 {
+		card.DefaultOutputModes = a.outputModes()
+	}
+
+	if len(card.Skills) == 0 && a.tooling != nil {
+		a.tooling.mu.Lock()
+		for _, skill := range a.tooling.skills {
+			card.Skills = append(card.Skills, proto.Clone(skill).(*pb.AgentSkill))
+		}
+		a.tooling.mu.Unlock()
+	}
+
+	return card, nil
 }
 
+func (a *BaseAgent) inputModes() []string {
+	if a.InputSchema != nil {
+		return []string{"application/json"}
+	}
+
+	if a.llm == nil {
+		return slices.Clone(providers.DEFAULT_MODES)
+	}
+
+	return providers.InputModes(a.llm)
+}
+
+func (a *BaseAgent) outputModes() []string {
+	if a.llm == nil {
+		return slices.Clone(providers.DEFAULT_MODES)
+	}
+
+	return providers.OutputModes(a.llm)
+}
+
+// toolSkills describes the tools as skills, the start tools of lazy servers
+// stand for tools that are not known yet, their skills must be declared.
+func toolSkills(toolset []tools.Tool) []*pb.AgentSkill {
+	skills := []*pb.AgentSkill{}
+
+	for _, tool := range toolset {
+		if _, ok := tool.(*startTool); ok {
+			continue
+		}
+
+		definition := tool.Definition()
+
+		tag := "tool"
+		if server, _, ok := strings.Cut(tools.Path(tool), "/"); ok {
+			tag = server
+		}
+
+		description := definition.Description
+		if description == "" {
+			description = definition.Name
+		}
+
+		skills = append(skills, &pb.AgentSkill{
+			Id:          tools.Path(tool),
+			Name:        definition.Name,
+			Description: description,
+			Tags:        []string{tag},
+		})
+	}
+
+	return skills
+}
+
+// SendMessage starts a task with the message, or resumes the task waiting
+// for input the message belongs to. Blocking requests, and requests without
+// configuration, wait until the task is terminal or interrupted, the others
+// return the submitted task.
 func (a *BaseAgent) SendMessage(ctx context.Context, in *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
 
 	a.Logger.Debug(fmt.Sprintf("Received: %v", in.GetRequest()))
 
-	var buffer bytes.Buffer
-	for _, part := range in.GetRequest().GetContent() {
+	if a.tasks == nil {
+		return nil, status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	var task *pb.Task
+	var err error
+
+	if taskId := in.GetRequest().GetTaskId(); taskId != "" {
+		task, err = a.resumeTask(ctx, taskId, in.GetRequest(), in.GetConfiguration().GetPushNotification())
+	} else {
+		task, err = a.startTask(ctx, in.GetRequest(), in.GetConfiguration().GetPushNotification())
+	}
+
+	if err != nil {
+		return nil, err
+	}
 
-		switch p := part.GetPart().(type) {
-		case *pb.Part_Text:
-			buffer.WriteString(p.Text + "\n")
-		case *pb.Part_Data:
-			buffer.WriteString(p.Data.GetData().String() + "\n")
-		case *pb.Part_File:
-			buffer.WriteString(p.File.GetFileWithUri() + "\n")
+	if in.GetConfiguration() == nil || in.GetConfiguration().GetBlocking() {
+		task, err = a.tasks.Wait(ctx, task.GetId())
+		if err != nil {
+			return nil, taskError(err)
 		}
 	}
 
-	// TODO Change for generate to perform more interactions
-	msg, err := a.Send(buffer.String())
+	return &pb.SendMessageResponse{
+		Payload: &pb.SendMessageResponse_Task{
+			Task: tasks.Trim(task, in.GetConfiguration().GetHistoryLength()),
+		},
+	}, nil
+}
+
+// SendStreamingMessage starts or resumes a task like SendMessage, streaming
+// the task followed by its status and artifact updates until it is terminal
+// or interrupted.
+func (a *BaseAgent) SendStreamingMessage(in *pb.SendMessageRequest, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+
+	if a.tasks == nil {
+		return status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	ctx := stream.Context()
+
+	var task *pb.Task
+	var err error
+
+	if taskId := in.GetRequest().GetTaskId(); taskId != "" {
+		task, err = a.resumeTask(ctx, taskId, in.GetRequest(), in.GetConfiguration().GetPushNotification())
+	} else {
+		task, err = a.startTask(ctx, in.GetRequest(), in.GetConfiguration().GetPushNotification())
+	}
 
 	if err != nil {
-		return nil, fmt.Errorf("error sending message to agent %w", err)
+		return err
 	}
 
-	// TODO Need more logic to add more interactions
-	// - Tasks
-	//   - Support for Artifacts
-	// - Multi-Turn Intecraction
-	// - Push notifications
-	// - File exchange support
-	// - Structured Responses
+	return a.streamTask(ctx, task.GetId(), stream.Send)
+}
 
-	return &pb.SendMessageResponse{
-		Payload: &pb.SendMessageResponse_Msg{
-			Msg: &pb.Message{
-				MessageId: uuid.NewString(),
-				ContextId: uuid.NewString(),
-				Role:      pb.Role_ROLE_AGENT,
-				Content: []*pb.Part{
-					{
-						Part: &pb.Part_Text{
-							Text: msg,
-						},
-					},
+// startTask creates the task of the message and runs it in the background,
+// notifying push when it is set.
+func (a *BaseAgent) startTask(ctx context.Context, message *pb.Message, push *pb.PushNotificationConfig) (*pb.Task, error) {
+
+	if push != nil {
+		if err := a.pusher.Validate(ctx, push); err != nil {
+			return nil, taskError(err)
+		}
+	}
+
+	contextId := message.GetContextId()
+	if contextId == "" {
+		contextId = uuid.NewString()
+	}
+
+	// Tasks of the same context continue its conversation, contexts are bound
+	// to the principal of their first task
+	conversation, ok := a.conversations.Open(contextId, owner(ctx))
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "context %s belongs to another principal", contextId)
+	}
+
+	task, err := a.tasks.Create(ctx, contextId, owner(ctx), message)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error create task, %s", err)
+	}
+
+	// Set before the run starts, so the webhook gets every change
+	if push != nil {
+		if _, err := a.pusher.Set(ctx, task.GetId(), push); err != nil {
+			return nil, taskError(err)
+		}
+	}
+
+	// The task outlives this call, it stops when it finishes, is cancelled or
+	// runs out of time, also waiting for an answer that never comes
+	runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.runTimeout())
+
+	run := newRun(task, a.tasks, cancel)
+	a.runs.Add(run)
+
+	runCtx = tools.WithApprover(runCtx, run)
+
+	runCtx = memory.WithMemory(runCtx, conversation)
+
+	runCtx = agents.WithArtifactSink(runCtx, a.addToolArtifact(run.Id))
+
+	// Files referenced by uri are attached when a server lists them or reads
+	// any file uri, see FileServers
+	for _, part := range message.GetContent() {
+		uri := part.GetFile().GetFileWithUri()
+		if uri == "" {
+			continue
+		}
+
+		ref, ok := a.fileResource(uri)
+		if !ok {
+			a.Logger.Warn(fmt.Sprintf("file %s not attached, no server of agent %s lists it", uri, a.Name))
+			continue
+		}
+
+		runCtx = agents.WithResources(runCtx, ref)
+	}
+
+	go a.execute(runCtx, run, messageText(message))
+
+	return task, nil
+}
+
+func (a *BaseAgent) runTimeout() time.Duration {
+	if a.RunTimeout <= 0 {
+		return DEFAULT_RUN_TIMEOUT
+	}
+
+	return a.RunTimeout
+}
+
+// execute generates the answer of the task, moving it to its final state.
+func (a *BaseAgent) execute(ctx context.Context, r *run, message string) {
+	defer a.runs.Delete(r.Id)
+	defer r.cancel()
+
+	if _, err := a.tasks.Update(ctx, r.Id, pb.TaskState_TASK_STATE_WORKING, nil); err != nil {
+		a.Logger.Error(fmt.Sprintf("error start task %s", r.Id), "error", err)
+		return
+	}
+
+	content, err := a.Generate(ctx, message)
+
+	state := pb.TaskState_TASK_STATE_COMPLETED
+	text := mcp.Result(content).AllText()
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		a.Logger.Error(fmt.Sprintf("task %s timed out", r.Id), "error", err)
+		state = pb.TaskState_TASK_STATE_FAILED
+		text = fmt.Sprintf("task did not finish in %s", a.runTimeout())
+		ctx = context.WithoutCancel(ctx)
+	case ctx.Err() != nil:
+		// Cancelled with CancelTask, the task is already final
+		return
+	case err != nil:
+		a.Logger.Error(fmt.Sprintf("task %s failed", r.Id), "error", err)
+
+		// The response of a model that did not finish is kept
+		if text != "" {
+			a.addArtifact(ctx, r.Id, responseArtifact(text))
+		}
+
+		// The error may expose internals of the agent, it is only logged
+		state = pb.TaskState_TASK_STATE_FAILED
+		text = "task failed, the agent could not answer"
+	case text != "":
+		a.addArtifact(ctx, r.Id, responseArtifact(text))
+	}
+
+	update := &pb.Message{
+		MessageId: uuid.NewString(),
+		Role:      pb.Role_ROLE_AGENT,
+		Content: []*pb.Part{
+			{
+				Part: &pb.Part_Text{
+					Text: text,
 				},
 			},
 		},
-	}, nil
+	}
+
+	// A task cancelled while finishing keeps its cancelled state
+	if _, err := a.tasks.Update(ctx, r.Id, state, update); err != nil && !errors.Is(err, tasks.ErrInvalidTransition) {
+		a.Logger.Error(fmt.Sprintf("error finish task %s", r.Id), "error", err)
+	}
+}
+
+// addToolArtifact returns the sink adding the artifacts of the tool results
+// to the task.
+func (a *BaseAgent) addToolArtifact(taskId string) agents.ArtifactSink {
+	return func(ctx context.Context, definition mcp_tool.Tool, tool string, args map[string]any, result *mcp_tool.CallToolResult) {
+		artifact, err := resultArtifact(definition, tool, args, result)
+		if err != nil {
+			a.Logger.Error(fmt.Sprintf("error artifact of task %s", taskId), "error", err)
+			return
+		}
+
+		if artifact != nil {
+			a.addArtifact(ctx, taskId, artifact)
+		}
+	}
+}
+
+// addArtifact adds the artifact to the task in one update, limited to
+// MaxArtifactSize.
+func (a *BaseAgent) addArtifact(ctx context.Context, taskId string, artifact *pb.Artifact) {
+	artifact = limitArtifact(artifact, a.maxArtifactSize())
+
+	if _, err := a.tasks.AddArtifact(ctx, taskId, artifact, false, true); err != nil {
+		a.Logger.Error(fmt.Sprintf("error add artifact %s to task %s", artifact.GetArtifactId(), taskId), "error", err)
+	}
+}
+
+func (a *BaseAgent) maxArtifactSize() int {
+	if a.MaxArtifactSize <= 0 {
+		return DEFAULT_MAX_ARTIFACT_SIZE
+	}
+
+	return a.MaxArtifactSize
+}
+
+// owner is the name of the principal of the request, the owner of the tasks
+// and contexts it creates, empty without one.
+func owner(ctx context.Context) string {
+	if principal := auth.FromContext(ctx); principal != nil {
+		return principal.Name
+	}
+
+	return ""
+}
+
+// ownedTask returns the task when the principal of the request owns it, the
+// tasks of other principals are not found.
+func (a *BaseAgent) ownedTask(ctx context.Context, taskId string) (*pb.Task, error) {
+	task, err := a.tasks.Get(ctx, taskId)
+	if err != nil {
+		return nil, taskError(err)
+	}
+
+	if tasks.Owner(task) != owner(ctx) {
+		return nil, status.Errorf(codes.NotFound, "error get task %s, %s", taskId, tasks.ErrTaskNotFound)
+	}
+
+	return task, nil
+}
+
+// resumeTask answers the question of a task waiting for input.
+func (a *BaseAgent) resumeTask(ctx context.Context, taskId string, message *pb.Message, push *pb.PushNotificationConfig) (*pb.Task, error) {
+
+	// Only the owner answers the questions of a task, approvals included
+	task, err := a.ownedTask(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	if tasks.Terminal(task.GetStatus().GetState()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "task %s is %s", taskId, task.GetStatus().GetState())
+	}
+
+	run, ok := a.runs.Get(taskId)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "task %s is not waiting for input", taskId)
+	}
+
+	if push != nil {
+		if err := a.pusher.Validate(ctx, push); err != nil {
+			return nil, taskError(err)
+		}
+	}
+
+	if err := run.Resolve(ctx, message); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", err)
+	}
+
+	// Only the answer of a waiting task sets the config
+	if push != nil {
+		if _, err := a.pusher.Set(ctx, taskId, push); err != nil {
+			return nil, taskError(err)
+		}
+	}
+
+	a.Logger.Info(fmt.Sprintf("task %s resumed", taskId))
+
+	return a.tasks.Get(ctx, taskId)
+}
+
+func (a *BaseAgent) GetTask(ctx context.Context, in *pb.GetTaskRequest) (*pb.Task, error) {
+
+	if a.tasks == nil {
+		return nil, status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	taskId, err := tasks.ParseName(in.GetName())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	task, err := a.ownedTask(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks.Trim(task, in.GetHistoryLength()), nil
+}
+
+// CancelTask stops the run of the task, terminal tasks can not be cancelled.
+func (a *BaseAgent) CancelTask(ctx context.Context, in *pb.CancelTaskRequest) (*pb.Task, error) {
+
+	if a.tasks == nil {
+		return nil, status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	taskId, err := tasks.ParseName(in.GetName())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	if _, err := a.ownedTask(ctx, taskId); err != nil {
+		return nil, err
+	}
+
+	task, err := a.tasks.Update(ctx, taskId, pb.TaskState_TASK_STATE_CANCELLED, nil)
+	if err != nil {
+		return nil, taskError(err)
+	}
+
+	if run, ok := a.runs.Get(taskId); ok {
+		run.cancel()
+	}
+
+	a.Logger.Info(fmt.Sprintf("task %s cancelled", taskId))
+
+	return task, nil
+}
+
+// TaskSubscription streams the task and its updates until it is terminal or
+// interrupted.
+func (a *BaseAgent) TaskSubscription(in *pb.TaskSubscriptionRequest, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+
+	if a.tasks == nil {
+		return status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	taskId, err := tasks.ParseName(in.GetName())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	if _, err := a.ownedTask(stream.Context(), taskId); err != nil {
+		return err
+	}
+
+	return a.streamTask(stream.Context(), taskId, stream.Send)
+}
+
+// streamTask sends the task followed by its events until the final one.
+func (a *BaseAgent) streamTask(ctx context.Context, taskId string, send func(*pb.StreamResponse) error) error {
+
+	// Subscribe before reading the task, so no event is missed
+	subscription := a.tasks.Subscribe(taskId)
+	defer subscription.Close()
+
+	task, err := a.tasks.Get(ctx, taskId)
+	if err != nil {
+		return taskError(err)
+	}
+
+	event := &pb.StreamResponse{
+		Payload: &pb.StreamResponse_Task{
+			Task: task,
+		},
+	}
+
+	for {
+		if err := send(event); err != nil {
+			return err
+		}
+
+		if tasks.Final(event) {
+			return nil
+		}
+
+		event, err = subscription.Next(ctx)
+		if err != nil {
+			return status.FromContextError(err).Err()
+		}
+	}
+}
+
+// CreateTaskPushNotification sets a webhook notified of the changes of the
+// task, replacing the config with the same id.
+func (a *BaseAgent) CreateTaskPushNotification(ctx context.Context, in *pb.CreateTaskPushNotificationRequest) (*pb.TaskPushNotificationConfig, error) {
+
+	if a.tasks == nil {
+		return nil, status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	taskId, err := tasks.ParseName(in.GetParent())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	config := in.GetConfig().GetPushNotificationConfig()
+	if config == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "missing push notification config")
+	}
+
+	if in.GetConfigId() != "" {
+		config = proto.Clone(config).(*pb.PushNotificationConfig)
+		config.Id = in.GetConfigId()
+	}
+
+	if _, err := a.ownedTask(ctx, taskId); err != nil {
+		return nil, err
+	}
+
+	push, err := a.pusher.Set(ctx, taskId, config)
+	if err != nil {
+		return nil, taskError(err)
+	}
+
+	return push, nil
+}
+
+func (a *BaseAgent) GetTaskPushNotification(ctx context.Context, in *pb.GetTaskPushNotificationRequest) (*pb.TaskPushNotificationConfig, error) {
+
+	if a.tasks == nil {
+		return nil, status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	taskId, configId, err := tasks.ParsePushName(in.GetName())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	if _, err := a.ownedTask(ctx, taskId); err != nil {
+		return nil, err
+	}
+
+	push, err := a.pusher.Get(ctx, taskId, configId)
+	if err != nil {
+		return nil, taskError(err)
+	}
+
+	return push, nil
+}
+
+// ListTaskPushNotification returns the configs of the task sorted by id, the
+// page token is the id of the last config of the previous page.
+func (a *BaseAgent) ListTaskPushNotification(ctx context.Context, in *pb.ListTaskPushNotificationRequest) (*pb.ListTaskPushNotificationResponse, error) {
+
+	if a.tasks == nil {
+		return nil, status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	taskId, err := tasks.ParseName(in.GetParent())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	if _, err := a.ownedTask(ctx, taskId); err != nil {
+		return nil, err
+	}
+
+	configs, err := a.pusher.List(ctx, taskId)
+	if err != nil {
+		return nil, taskError(err)
+	}
+
+	configs = slices.DeleteFunc(configs, func(config *pb.TaskPushNotificationConfig) bool {
+		return in.GetPageToken() != "" && config.GetPushNotificationConfig().GetId() <= in.GetPageToken()
+	})
+
+	response := &pb.ListTaskPushNotificationResponse{Configs: configs}
+
+	if size := int(in.GetPageSize()); size > 0 && len(configs) > size {
+		response.Configs = configs[:size]
+		response.NextPageToken = configs[size-1].GetPushNotificationConfig().GetId()
+	}
+
+	return response, nil
+}
+
+// DeleteTaskPushNotification stops notifying the webhook of a config, name
+// is tasks/{id}/pushNotifications/{config_id}.
+func (a *BaseAgent) DeleteTaskPushNotification(ctx context.Context, name string) error {
+
+	if a.tasks == nil {
+		return status.Errorf(codes.Unimplemented, "agent %s has no tasks", a.Name)
+	}
+
+	taskId, configId, err := tasks.ParsePushName(name)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	if _, err := a.ownedTask(ctx, taskId); err != nil {
+		return err
+	}
+
+	if err := a.pusher.Delete(ctx, taskId, configId); err != nil {
+		return taskError(err)
+	}
+
+	return nil
+}
+
+// taskError converts the errors of the task store to gRPC status errors.
+func taskError(err error) error {
+	switch {
+	case errors.Is(err, tasks.ErrTaskNotFound), errors.Is(err, tasks.ErrPushConfigNotFound):
+		return status.Errorf(codes.NotFound, "%s", err)
+	case errors.Is(err, tasks.ErrInvalidPushConfig):
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	case errors.Is(err, tasks.ErrInvalidTransition):
+		return status.Errorf(codes.FailedPrecondition, "%s", err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+
+	return status.Errorf(codes.Internal, "%s", err)
+}
+
+// Authorize applies the policy of the Authorizer to the principal of ctx, the
+// skills of the caller are not the ones of the agent.
+func (a *BaseAgent) Authorize(ctx context.Context) (context.Context, error) {
+	if a.Authorizer == nil {
+		if principal := auth.FromContext(ctx); principal != nil {
+			ctx = auth.WithPrincipal(ctx, &auth.Principal{Name: principal.Name, Scheme: principal.Scheme})
+		}
+
+		return ctx, nil
+	}
+
+	principal, err := a.Authorizer.Permit(auth.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error authorize agent %s, %w", a.Name, err)
+	}
+
+	return auth.WithPrincipal(ctx, principal), nil
 }
 
 func (a *BaseAgent) GetClient() pb.A2AServiceClient {
@@ -0,0 +1,904 @@
+package base_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/agents/auth"
+	"github.com/jlrosende/go-agents/agents/tasks"
+	"github.com/jlrosende/go-agents/agents/workflows/base"
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/memory"
+	"github.com/jlrosende/go-agents/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+	mcp_server "github.com/mark3labs/mcp-go/server"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// fakeLLM answers every message with generate, the attached tools are kept
+// in toolset when it is set.
+type fakeLLM struct {
+	generate func(ctx context.Context, message string) ([]mcp_tool.Content, error)
+	toolset  *[]tools.Tool
+}
+
+func (f fakeLLM) Initialize() error                 { return nil }
+func (f fakeLLM) GetModel(name string) (any, error) { return name, nil }
+func (f fakeLLM) ListModels() (any, error)          { return nil, nil }
+func (f fakeLLM) AttachTools(toolset []tools.Tool) error {
+	if f.toolset != nil {
+		*f.toolset = toolset
+	}
+	return nil
+}
+func (f fakeLLM) SetInstructions(instructions string) {}
+func (f fakeLLM) Generate(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+	return f.generate(ctx, message)
+}
+func (f fakeLLM) Structured(ctx context.Context, message string, responseStruct any) ([]mcp_tool.Content, error) {
+	return f.generate(ctx, message)
+}
+func (f fakeLLM) CreateMessage(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error) {
+	return nil, nil
+}
+
+func newAgent(t *testing.T, generate func(ctx context.Context, message string) ([]mcp_tool.Content, error)) *base.BaseAgent {
+	t.Helper()
+
+	agent := &base.BaseAgent{
+		Name:             "test",
+		Model:            "fake",
+		PushAllowedHosts: []string{"localhost"},
+		ToolApproval: tools.ApprovalPolicies{
+			Default: tools.APPROVAL_ASK,
+		},
+	}
+
+	agent.AttachLLM(fakeLLM{generate: generate})
+	require.NoError(t, agent.Initialize())
+
+	return agent
+}
+
+func userMessage(text string) *pb.Message {
+	return &pb.Message{
+		MessageId: text,
+		Role:      pb.Role_ROLE_USER,
+		Content:   []*pb.Part{{Part: &pb.Part_Text{Text: text}}},
+	}
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("non blocking send", func(t *testing.T) {
+		release := make(chan struct{})
+
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			<-release
+			return []mcp_tool.Content{mcp_tool.NewTextContent("done")}, nil
+		})
+
+		response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{
+			Request:       userMessage("hello"),
+			Configuration: &pb.SendMessageConfiguration{Blocking: false},
+		})
+		require.NoError(t, err)
+
+		task := response.GetTask()
+		assert.False(t, tasks.Terminal(task.GetStatus().GetState()))
+
+		close(release)
+
+		assert.Eventually(t, func() bool {
+			task, err := agent.GetTask(ctx, &pb.GetTaskRequest{Name: tasks.Name(task.GetId())})
+			return err == nil && task.GetStatus().GetState() == pb.TaskState_TASK_STATE_COMPLETED
+		}, time.Second, 10*time.Millisecond)
+
+		task, err = agent.GetTask(ctx, &pb.GetTaskRequest{Name: tasks.Name(task.GetId()), HistoryLength: 1})
+		require.NoError(t, err)
+		assert.Len(t, task.GetHistory(), 1)
+		assert.Contains(t, task.GetStatus().GetUpdate().GetContent()[0].GetText(), "done")
+	})
+
+	t.Run("input required", func(t *testing.T) {
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			decision, err := tools.Approve(ctx, tools.ApprovalRequest{Tool: "write_file"})
+			if err != nil {
+				return nil, err
+			}
+
+			if !decision.Approved {
+				return []mcp_tool.Content{mcp_tool.NewTextContent("denied")}, nil
+			}
+
+			return []mcp_tool.Content{mcp_tool.NewTextContent("written")}, nil
+		})
+
+		response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{Request: userMessage("write")})
+		require.NoError(t, err)
+
+		task := response.GetTask()
+		require.Equal(t, pb.TaskState_TASK_STATE_INPUT_REQUIRED, task.GetStatus().GetState())
+
+		answer := userMessage("approve")
+		answer.TaskId = task.GetId()
+
+		response, err = agent.SendMessage(ctx, &pb.SendMessageRequest{Request: answer})
+		require.NoError(t, err)
+
+		task = response.GetTask()
+		assert.Equal(t, pb.TaskState_TASK_STATE_COMPLETED, task.GetStatus().GetState())
+		assert.Contains(t, task.GetStatus().GetUpdate().GetContent()[0].GetText(), "written")
+		assert.Len(t, task.GetHistory(), 4)
+
+		_, err = agent.SendMessage(ctx, &pb.SendMessageRequest{Request: answer})
+		assert.Error(t, err, "completed tasks do not take messages")
+	})
+
+	t.Run("parallel approvals", func(t *testing.T) {
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			decisions := make([]bool, 2)
+			wg := sync.WaitGroup{}
+
+			for i, tool := range []string{"read_file", "delete_file"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					decision, _ := tools.Approve(ctx, tools.ApprovalRequest{Tool: tool, ToolCallID: tool})
+					decisions[i] = decision.Approved
+				}()
+			}
+
+			wg.Wait()
+
+			return []mcp_tool.Content{mcp_tool.NewTextContent(fmt.Sprintf("read_file:%v delete_file:%v", decisions[0], decisions[1]))}, nil
+		})
+		agent.RunTimeout = time.Second
+
+		response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{Request: userMessage("clean")})
+		require.NoError(t, err)
+
+		// Each question gets the answer of the tool it names, also when the
+		// other call had the time to ask
+		for task := response.GetTask(); task.GetStatus().GetState() == pb.TaskState_TASK_STATE_INPUT_REQUIRED; task = response.GetTask() {
+			time.Sleep(20 * time.Millisecond)
+
+			task, err = agent.GetTask(ctx, &pb.GetTaskRequest{Name: tasks.Name(task.GetId())})
+			require.NoError(t, err)
+
+			tool := task.GetStatus().GetUpdate().GetContent()[1].GetData().GetData().GetFields()["tool"].GetStringValue()
+
+			answer := userMessage("deny")
+			if tool == "read_file" {
+				answer = userMessage("approve")
+			}
+			answer.TaskId = task.GetId()
+
+			response, err = agent.SendMessage(ctx, &pb.SendMessageRequest{Request: answer})
+			require.NoError(t, err)
+		}
+
+		task := response.GetTask()
+		assert.Equal(t, pb.TaskState_TASK_STATE_COMPLETED, task.GetStatus().GetState())
+		assert.Contains(t, task.GetStatus().GetUpdate().GetContent()[0].GetText(), "read_file:true delete_file:false")
+	})
+
+	t.Run("unanswered tasks time out", func(t *testing.T) {
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			_, err := tools.Approve(ctx, tools.ApprovalRequest{Tool: "write_file"})
+			return nil, err
+		})
+		agent.RunTimeout = 50 * time.Millisecond
+
+		response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{Request: userMessage("write")})
+		require.NoError(t, err)
+		require.Equal(t, pb.TaskState_TASK_STATE_INPUT_REQUIRED, response.GetTask().GetStatus().GetState())
+
+		id := response.GetTask().GetId()
+
+		assert.Eventually(t, func() bool {
+			task, err := agent.GetTask(ctx, &pb.GetTaskRequest{Name: tasks.Name(id)})
+			return err == nil && task.GetStatus().GetState() == pb.TaskState_TASK_STATE_FAILED
+		}, time.Second, 10*time.Millisecond)
+
+		answer := userMessage("approve")
+		answer.TaskId = id
+
+		_, err = agent.SendMessage(ctx, &pb.SendMessageRequest{Request: answer})
+		assert.Error(t, err, "the run is gone")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+		response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{
+			Request:       userMessage("wait"),
+			Configuration: &pb.SendMessageConfiguration{},
+		})
+		require.NoError(t, err)
+
+		name := tasks.Name(response.GetTask().GetId())
+
+		task, err := agent.CancelTask(ctx, &pb.CancelTaskRequest{Name: name})
+		require.NoError(t, err)
+		assert.Equal(t, pb.TaskState_TASK_STATE_CANCELLED, task.GetStatus().GetState())
+
+		_, err = agent.CancelTask(ctx, &pb.CancelTaskRequest{Name: name})
+		assert.Error(t, err, "terminal tasks can not be cancelled")
+
+		_, err = agent.GetTask(ctx, &pb.GetTaskRequest{Name: "tasks/missing"})
+		assert.Error(t, err)
+	})
+
+	t.Run("push notifications", func(t *testing.T) {
+		// The configs are kept while the task runs
+		release := make(chan struct{})
+		defer close(release)
+
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			<-release
+			return []mcp_tool.Content{mcp_tool.NewTextContent("done")}, nil
+		})
+
+		_, err := agent.SendMessage(ctx, &pb.SendMessageRequest{
+			Request: userMessage("hello"),
+			Configuration: &pb.SendMessageConfiguration{
+				PushNotification: &pb.PushNotificationConfig{Url: "ftp://localhost"},
+			},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{
+			Request: userMessage("hello"),
+			Configuration: &pb.SendMessageConfiguration{
+				PushNotification: &pb.PushNotificationConfig{Id: "first", Url: "http://localhost:1"},
+			},
+		})
+		require.NoError(t, err)
+
+		name := tasks.Name(response.GetTask().GetId())
+
+		config, err := agent.CreateTaskPushNotification(ctx, &pb.CreateTaskPushNotificationRequest{
+			Parent:   name,
+			ConfigId: "second",
+			Config: &pb.TaskPushNotificationConfig{
+				PushNotificationConfig: &pb.PushNotificationConfig{Url: "http://localhost:2"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, name+"/pushNotifications/second", config.GetName())
+
+		config, err = agent.GetTaskPushNotification(ctx, &pb.GetTaskPushNotificationRequest{Name: config.GetName()})
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:2", config.GetPushNotificationConfig().GetUrl())
+
+		list, err := agent.ListTaskPushNotification(ctx, &pb.ListTaskPushNotificationRequest{Parent: name, PageSize: 1})
+		require.NoError(t, err)
+		require.Len(t, list.GetConfigs(), 1)
+		assert.Equal(t, "first", list.GetNextPageToken())
+
+		list, err = agent.ListTaskPushNotification(ctx, &pb.ListTaskPushNotificationRequest{Parent: name, PageToken: list.GetNextPageToken()})
+		require.NoError(t, err)
+		require.Len(t, list.GetConfigs(), 1)
+		assert.Equal(t, "second", list.GetConfigs()[0].GetPushNotificationConfig().GetId())
+		assert.Empty(t, list.GetNextPageToken())
+
+		_, err = agent.GetTaskPushNotification(ctx, &pb.GetTaskPushNotificationRequest{Name: name + "/pushNotifications/missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("conversations by context", func(t *testing.T) {
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			history, ok := memory.FromContext(ctx)
+			if !ok {
+				return nil, fmt.Errorf("no conversation")
+			}
+
+			history.Append(message)
+
+			return []mcp_tool.Content{mcp_tool.NewTextContent(fmt.Sprint(history.Get()...))}, nil
+		})
+
+		send := func(contextId, text string) string {
+			message := userMessage(text)
+			message.ContextId = contextId
+
+			response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{Request: message})
+			require.NoError(t, err)
+			assert.Equal(t, contextId, response.GetTask().GetContextId())
+
+			return strings.Join(strings.Fields(response.GetTask().GetStatus().GetUpdate().GetContent()[0].GetText()), " ")
+		}
+
+		assert.Equal(t, "hello", send("alice", "hello"))
+		assert.Equal(t, "hi", send("bob", "hi"))
+		assert.Equal(t, "hello again", send("alice", "again"))
+	})
+
+	t.Run("principals own their tasks", func(t *testing.T) {
+		agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			if _, err := tools.Approve(ctx, tools.ApprovalRequest{Tool: "write_file"}); err != nil {
+				return nil, err
+			}
+
+			return []mcp_tool.Content{mcp_tool.NewTextContent("written")}, nil
+		})
+
+		alice := auth.WithPrincipal(ctx, &auth.Principal{Name: "alice"})
+		bob := auth.WithPrincipal(ctx, &auth.Principal{Name: "bob"})
+
+		message := userMessage("write")
+		message.ContextId = "context-1"
+
+		response, err := agent.SendMessage(alice, &pb.SendMessageRequest{Request: message})
+		require.NoError(t, err)
+
+		task := response.GetTask()
+		require.Equal(t, pb.TaskState_TASK_STATE_INPUT_REQUIRED, task.GetStatus().GetState())
+
+		name := tasks.Name(task.GetId())
+
+		_, err = agent.GetTask(bob, &pb.GetTaskRequest{Name: name})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = agent.GetTask(ctx, &pb.GetTaskRequest{Name: name})
+		assert.Equal(t, codes.NotFound, status.Code(err), "anonymous requests do not own it")
+
+		_, err = agent.CancelTask(bob, &pb.CancelTaskRequest{Name: name})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = agent.ListTaskPushNotification(bob, &pb.ListTaskPushNotificationRequest{Parent: name})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		answer := userMessage("approve")
+		answer.TaskId = task.GetId()
+
+		_, err = agent.SendMessage(bob, &pb.SendMessageRequest{Request: answer})
+		assert.Equal(t, codes.NotFound, status.Code(err), "others do not approve the tools")
+
+		other := userMessage("hi")
+		other.ContextId = "context-1"
+
+		_, err = agent.SendMessage(bob, &pb.SendMessageRequest{Request: other})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		response, err = agent.SendMessage(alice, &pb.SendMessageRequest{Request: answer})
+		require.NoError(t, err)
+		assert.Equal(t, pb.TaskState_TASK_STATE_COMPLETED, response.GetTask().GetStatus().GetState())
+	})
+}
+
+// streamRecorder keeps the events sent to a stream.
+type streamRecorder struct {
+	grpc.ServerStream
+	events []*pb.StreamResponse
+}
+
+func (s *streamRecorder) Context() context.Context {
+	return context.Background()
+}
+
+func (s *streamRecorder) Send(event *pb.StreamResponse) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+type screenshotArgs struct{}
+
+type writeFileArgs struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+func TestArtifacts(t *testing.T) {
+	screenshot, err := tools.NewFunctionTool("screenshot", "Take a screenshot", func(ctx context.Context, args screenshotArgs) (*mcp_tool.CallToolResult, error) {
+		return &mcp_tool.CallToolResult{
+			Content: []mcp_tool.Content{
+				mcp_tool.NewTextContent("screenshot taken"),
+				mcp_tool.NewImageContent(base64.StdEncoding.EncodeToString([]byte("png")), "image/png"),
+				mcp_tool.NewResourceLink("file:///tmp/report.pdf", "report", "", ""),
+			},
+			StructuredContent: map[string]any{"width": 800},
+		}, nil
+	})
+	require.NoError(t, err)
+
+	writeFile, err := tools.NewFunctionTool("write_file", "Write a file", func(ctx context.Context, args writeFileArgs) (*mcp_tool.CallToolResult, error) {
+		return mcp_tool.NewToolResultText("written " + args.Path), nil
+	})
+	require.NoError(t, err)
+
+	toolset := []tools.Tool{}
+
+	agent := &base.BaseAgent{
+		Name:            "test",
+		Model:           "fake",
+		Tools:           []tools.Tool{screenshot, writeFile},
+		MaxArtifactSize: 1000,
+		ToolApproval:    tools.ApprovalPolicies{Default: tools.APPROVAL_ALWAYS},
+	}
+
+	agent.AttachLLM(fakeLLM{
+		toolset: &toolset,
+		generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			if _, err := toolset[0].Call(ctx, map[string]any{}); err != nil {
+				return nil, err
+			}
+
+			if _, err := toolset[1].Call(ctx, map[string]any{"path": "notes.md", "content": strings.Repeat("a", 2000)}); err != nil {
+				return nil, err
+			}
+
+			return []mcp_tool.Content{mcp_tool.NewTextContent(`{"answer": 42}`)}, nil
+		},
+	})
+	require.NoError(t, agent.Initialize())
+
+	stream := &streamRecorder{}
+
+	require.NoError(t, agent.SendStreamingMessage(&pb.SendMessageRequest{Request: userMessage("screenshot")}, stream))
+
+	updates := []*pb.TaskArtifactUpdateEvent{}
+	for _, event := range stream.events {
+		if update := event.GetArtifactUpdate(); update != nil {
+			updates = append(updates, update)
+		}
+	}
+
+	// The tool artifacts, the written file and the response
+	require.Len(t, updates, 3)
+	assert.Equal(t, "screenshot", updates[0].GetArtifact().GetName())
+	assert.False(t, updates[0].GetAppend())
+	assert.True(t, updates[0].GetLastChunk())
+
+	parts := updates[0].GetArtifact().GetParts()
+	require.Len(t, parts, 3)
+	assert.Equal(t, "image/png", parts[0].GetFile().GetMimeType())
+	assert.Equal(t, []byte("png"), parts[0].GetFile().GetFileWithBytes())
+	assert.Equal(t, "application/pdf", parts[1].GetFile().GetMimeType())
+	assert.Equal(t, 800.0, parts[2].GetData().GetData().AsMap()["width"])
+
+	written := updates[1].GetArtifact()
+	assert.Equal(t, "notes.md", written.GetMetadata().AsMap()["path"])
+	assert.Equal(t, "text/markdown; charset=utf-8", written.GetParts()[0].GetFile().GetMimeType())
+	assert.Less(t, len(written.GetParts()[0].GetFile().GetFileWithBytes()), 1000)
+	assert.Contains(t, string(written.GetParts()[0].GetFile().GetFileWithBytes()), "truncated")
+
+	last := stream.events[len(stream.events)-1]
+	assert.Equal(t, pb.TaskState_TASK_STATE_COMPLETED, last.GetStatusUpdate().GetStatus().GetState())
+
+	task, err := agent.GetTask(context.Background(), &pb.GetTaskRequest{Name: tasks.Name(last.GetStatusUpdate().GetTaskId())})
+	require.NoError(t, err)
+	require.Len(t, task.GetArtifacts(), 3)
+	assert.Len(t, task.GetArtifacts()[0].GetParts(), 3)
+	assert.Equal(t, "response", task.GetArtifacts()[2].GetName())
+	assert.Equal(t, 42.0, task.GetArtifacts()[2].GetParts()[0].GetData().GetData().AsMap()["answer"])
+}
+
+func TestLocalApprover(t *testing.T) {
+	out := &strings.Builder{}
+
+	agent := newAgent(t, func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+		decision, err := tools.Approve(ctx, tools.ApprovalRequest{Tool: "write_file"})
+		if err != nil {
+			return nil, err
+		}
+
+		return []mcp_tool.Content{mcp_tool.NewTextContent(fmt.Sprintf("approved:%v", decision.Approved))}, nil
+	})
+	agent.Approver = tools.NewCLIApprover(strings.NewReader("y\n"), out)
+
+	// Calls in process ask the approver of the agent
+	response, err := agent.Send(context.Background(), "write")
+	require.NoError(t, err)
+
+	assert.Contains(t, response, "approved:true")
+	assert.Contains(t, out.String(), "write_file")
+}
+
+func TestAgentTools(t *testing.T) {
+	ctx := context.Background()
+
+	callee := &base.BaseAgent{Name: "callee", Model: "fake"}
+	callee.AttachLLM(fakeLLM{generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+		return []mcp_tool.Content{mcp_tool.NewTextContent("callee got " + message)}, nil
+	}})
+	require.NoError(t, callee.Initialize())
+
+	t.Run("call agents in process", func(t *testing.T) {
+		toolset := []tools.Tool{}
+
+		caller := &base.BaseAgent{Name: "caller", Model: "fake", AgentTools: []string{"callee"}}
+		caller.AttachLLM(fakeLLM{
+			toolset: &toolset,
+			generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+				result, err := toolset[0].Call(ctx, map[string]any{"message": message})
+				if err != nil {
+					return nil, err
+				}
+
+				return result.Content, nil
+			},
+		})
+
+		require.NoError(t, caller.AttachAgents(map[string]agents.Agent{"callee": callee}))
+		require.NoError(t, caller.Initialize())
+
+		require.Len(t, toolset, 1)
+		assert.Equal(t, "callee", toolset[0].Definition().Name)
+
+		response, err := caller.Send(ctx, "hello")
+		require.NoError(t, err)
+		assert.Contains(t, response, "callee got hello")
+	})
+
+	t.Run("policy of the callee", func(t *testing.T) {
+		guarded := &base.BaseAgent{
+			Name:       "guarded",
+			Model:      "fake",
+			Authorizer: &auth.Authorizer{Policy: []auth.Rule{{Principals: []string{"alice"}}}},
+		}
+		guarded.AttachLLM(fakeLLM{generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			return []mcp_tool.Content{mcp_tool.NewTextContent("guarded got " + message)}, nil
+		}})
+		require.NoError(t, guarded.Initialize())
+
+		toolset := []tools.Tool{}
+
+		caller := &base.BaseAgent{Name: "caller", Model: "fake", AgentTools: []string{"guarded"}}
+		caller.AttachLLM(fakeLLM{toolset: &toolset, generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			result, err := toolset[0].Call(ctx, map[string]any{"message": message})
+			if err != nil {
+				return nil, err
+			}
+
+			return result.Content, nil
+		}})
+
+		require.NoError(t, caller.AttachAgents(map[string]agents.Agent{"guarded": guarded}))
+		require.NoError(t, caller.Initialize())
+
+		response, err := caller.Send(auth.WithPrincipal(ctx, &auth.Principal{Name: "alice"}), "hello")
+		require.NoError(t, err)
+		assert.Contains(t, response, "guarded got hello")
+
+		response, err = caller.Send(auth.WithPrincipal(ctx, &auth.Principal{Name: "bob"}), "hello")
+		require.NoError(t, err)
+		assert.Contains(t, response, "can not call the agent")
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		first := &base.BaseAgent{Name: "first", Model: "fake", AgentTools: []string{"second"}}
+		second := &base.BaseAgent{Name: "second", Model: "fake", AgentTools: []string{"callee", "first"}}
+		self := &base.BaseAgent{Name: "self", Model: "fake", AgentTools: []string{"self"}}
+
+		agentMap := map[string]agents.Agent{"first": first, "second": second, "callee": callee, "self": self}
+
+		err := first.AttachAgents(agentMap)
+		assert.ErrorContains(t, err, "first -> second -> first")
+
+		err = self.AttachAgents(agentMap)
+		assert.ErrorContains(t, err, "self -> self")
+	})
+
+	t.Run("disabled agents", func(t *testing.T) {
+		caller := &base.BaseAgent{Name: "caller", Model: "fake", AgentTools: []string{"disabled"}}
+
+		err := caller.AttachAgents(map[string]agents.Agent{"callee": callee})
+		assert.ErrorContains(t, err, "not found or disabled")
+	})
+}
+
+func TestLazyServers(t *testing.T) {
+	mcpTools := mcp_server.NewMCPServer("test", "0.0.1")
+	mcpTools.AddTool(mcp_tool.NewTool("echo"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		return mcp_tool.NewToolResultText("echo"), nil
+	})
+
+	httpServer := httptest.NewServer(mcp_server.NewStreamableHTTPServer(mcpTools))
+	defer httpServer.Close()
+
+	lazy, err := mcp.NewMCPServer(context.Background(), "lazy", mcp.TRANSPORT_HTTP, httpServer.URL+"/mcp", "", nil, nil, mcp.WithLazy(true))
+	require.NoError(t, err)
+	defer lazy.Close()
+
+	toolset := []tools.Tool{}
+
+	agent := &base.BaseAgent{Name: "test", Model: "fake", Servers: []string{"lazy"}, ToolSeparator: "__"}
+	agent.AttachLLM(fakeLLM{
+		toolset: &toolset,
+		generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			return []mcp_tool.Content{mcp_tool.NewTextContent("hi")}, nil
+		},
+	})
+	agent.AttachMCPServers(map[string]*mcp.MCPServer{"lazy": lazy})
+
+	require.NoError(t, agent.Initialize())
+
+	_, err = agent.Send(context.Background(), "hello")
+	require.NoError(t, err)
+
+	assert.False(t, lazy.Started(), "messages do not start lazy servers")
+	require.Len(t, toolset, 1)
+	assert.Equal(t, "start_lazy", toolset[0].Definition().Name)
+
+	result, err := toolset[0].Call(context.Background(), map[string]any{})
+	require.NoError(t, err)
+	assert.Contains(t, mcp.Result(result.Content).FirstText(), "lazy__echo")
+
+	assert.True(t, lazy.Started())
+	require.Len(t, toolset, 1)
+	assert.Equal(t, "lazy__echo", toolset[0].Definition().Name)
+}
+
+func TestToolReload(t *testing.T) {
+	servers := map[string]*mcp.MCPServer{}
+	mcpTools := map[string]*mcp_server.MCPServer{}
+	httpServers := map[string]*httptest.Server{}
+
+	for _, name := range []string{"one", "two"} {
+		mcpTools[name] = mcp_server.NewMCPServer(name, "0.0.1")
+		mcpTools[name].AddTool(mcp_tool.NewTool("echo"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+			return mcp_tool.NewToolResultText("echo"), nil
+		})
+
+		httpServers[name] = httptest.NewServer(mcp_server.NewStreamableHTTPServer(mcpTools[name]))
+		defer httpServers[name].Close()
+
+		server, err := mcp.NewMCPServer(context.Background(), name, mcp.TRANSPORT_HTTP, httpServers[name].URL+"/mcp", "", nil, nil)
+		require.NoError(t, err)
+		defer server.Close()
+
+		require.NoError(t, server.Start())
+		servers[name] = server
+	}
+
+	toolset := []tools.Tool{}
+
+	agent := &base.BaseAgent{Name: "test", Model: "fake", Servers: []string{"one", "two"}, ToolSeparator: "__"}
+	agent.AttachLLM(fakeLLM{toolset: &toolset})
+	agent.AttachMCPServers(servers)
+
+	require.NoError(t, agent.Initialize())
+
+	names := func() []string {
+		listed := []string{}
+		for _, tool := range toolset {
+			listed = append(listed, tool.Definition().Name)
+		}
+		return listed
+	}
+
+	assert.ElementsMatch(t, []string{"one__echo", "two__echo"}, names())
+
+	// The server two is closed, the reload of one keeps its tools
+	require.NoError(t, servers["two"].Close())
+
+	mcpTools["one"].AddTool(mcp_tool.NewTool("time"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		return mcp_tool.NewToolResultText("now"), nil
+	})
+
+	_, err := servers["one"].RefreshTools()
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"one__echo", "one__time", "two__echo"}, names())
+
+	card, err := agent.GetAgentCard(context.Background(), &pb.GetAgentCardRequest{})
+	require.NoError(t, err)
+	assert.Len(t, card.GetSkills(), 3, "skills follow the reloaded tools")
+}
+
+func TestFileResources(t *testing.T) {
+	ctx := context.Background()
+
+	mcpDocs := mcp_server.NewMCPServer("docs", "0.0.1", mcp_server.WithResourceCapabilities(false, false))
+	mcpDocs.AddResource(mcp_tool.NewResource("docs://listed", "listed"), func(ctx context.Context, request mcp_tool.ReadResourceRequest) ([]mcp_tool.ResourceContents, error) {
+		return []mcp_tool.ResourceContents{mcp_tool.TextResourceContents{URI: request.Params.URI, Text: "listed content"}}, nil
+	})
+	mcpDocs.AddResourceTemplate(mcp_tool.NewResourceTemplate("docs://{name}", "any"), func(ctx context.Context, request mcp_tool.ReadResourceRequest) ([]mcp_tool.ResourceContents, error) {
+		return []mcp_tool.ResourceContents{mcp_tool.TextResourceContents{URI: request.Params.URI, Text: "template content"}}, nil
+	})
+
+	httpServer := httptest.NewServer(mcp_server.NewStreamableHTTPServer(mcpDocs))
+	defer httpServer.Close()
+
+	docs, err := mcp.NewMCPServer(ctx, "docs", mcp.TRANSPORT_HTTP, httpServer.URL+"/mcp", "", nil, nil)
+	require.NoError(t, err)
+	defer docs.Close()
+
+	require.NoError(t, docs.Start())
+
+	send := func(t *testing.T, agent *base.BaseAgent, uri string) (*pb.Task, string) {
+		t.Helper()
+
+		received := make(chan string, 1)
+
+		agent.AttachLLM(fakeLLM{generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			received <- message
+			return []mcp_tool.Content{mcp_tool.NewTextContent("done")}, nil
+		}})
+		agent.AttachMCPServers(map[string]*mcp.MCPServer{"docs": docs})
+		require.NoError(t, agent.Initialize())
+
+		message := userMessage("read")
+		message.Content = append(message.Content, &pb.Part{Part: &pb.Part_File{File: &pb.FilePart{File: &pb.FilePart_FileWithUri{FileWithUri: uri}}}})
+
+		response, err := agent.SendMessage(ctx, &pb.SendMessageRequest{
+			Request:       message,
+			Configuration: &pb.SendMessageConfiguration{Blocking: true},
+		})
+		require.NoError(t, err)
+
+		select {
+		case text := <-received:
+			return response.GetTask(), text
+		default:
+			return response.GetTask(), ""
+		}
+	}
+
+	t.Run("listed resources", func(t *testing.T) {
+		agent := &base.BaseAgent{Name: "test", Model: "fake", Servers: []string{"docs"}}
+
+		_, text := send(t, agent, "docs://listed")
+		assert.Contains(t, text, "listed content")
+
+		_, text = send(t, agent, "docs://secret")
+		assert.NotContains(t, text, "template content", "not listed by the server")
+	})
+
+	t.Run("file servers", func(t *testing.T) {
+		agent := &base.BaseAgent{Name: "test", Model: "fake", Servers: []string{"docs"}, FileServers: []string{"docs"}}
+
+		_, text := send(t, agent, "docs://secret")
+		assert.Contains(t, text, "template content")
+	})
+
+	t.Run("approval policies", func(t *testing.T) {
+		agent := &base.BaseAgent{
+			Name:    "test",
+			Model:   "fake",
+			Servers: []string{"docs"},
+			ToolApproval: tools.ApprovalPolicies{
+				Tools: map[string]tools.ApprovalPolicy{"docs/read_resource": tools.APPROVAL_NEVER},
+			},
+		}
+
+		task, text := send(t, agent, "docs://listed")
+		assert.Empty(t, text)
+		assert.Equal(t, pb.TaskState_TASK_STATE_FAILED, task.GetStatus().GetState())
+	})
+}
+
+func TestSamplingApproval(t *testing.T) {
+	mcpTools := mcp_server.NewMCPServer("test", "0.0.1")
+	mcpTools.EnableSampling()
+	mcpTools.AddTool(mcp_tool.NewTool("summarise"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		sampling := mcp_tool.CreateMessageRequest{}
+		sampling.Messages = []mcp_tool.SamplingMessage{
+			{Role: mcp_tool.RoleUser, Content: mcp_tool.NewTextContent("summarise this")},
+		}
+
+		if _, err := mcpTools.RequestSampling(ctx, sampling); err != nil {
+			return mcp_tool.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp_tool.NewToolResultText("summary"), nil
+	})
+
+	httpServer := httptest.NewServer(mcp_server.NewStreamableHTTPServer(mcpTools))
+	defer httpServer.Close()
+
+	server, err := mcp.NewMCPServer(context.Background(), "docs", mcp.TRANSPORT_HTTP, httpServer.URL+"/mcp", "", nil, nil)
+	require.NoError(t, err)
+	defer server.Close()
+
+	require.NoError(t, server.Start())
+
+	toolset := []tools.Tool{}
+
+	agent := &base.BaseAgent{
+		Name:    "test",
+		Model:   "fake",
+		Servers: []string{"docs"},
+		ToolApproval: tools.ApprovalPolicies{
+			Tools: map[string]tools.ApprovalPolicy{"docs/sampling": tools.APPROVAL_NEVER},
+		},
+	}
+
+	agent.AttachLLM(fakeLLM{
+		toolset: &toolset,
+		generate: func(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+			result, err := toolset[0].Call(ctx, map[string]any{})
+			if err != nil {
+				return nil, err
+			}
+
+			return result.Content, nil
+		},
+	})
+	agent.AttachMCPServers(map[string]*mcp.MCPServer{"docs": server})
+
+	require.NoError(t, agent.Initialize())
+
+	text, err := agent.Send(context.Background(), "summarise")
+	require.NoError(t, err)
+	assert.Contains(t, text, "not approved")
+}
+
+func TestAgentCard(t *testing.T) {
+	echo, err := tools.NewFunctionTool("echo", "Echo the message", func(ctx context.Context, args screenshotArgs) (string, error) {
+		return "echo", nil
+	})
+	require.NoError(t, err)
+
+	agent := &base.BaseAgent{
+		Name:        "test",
+		Model:       "fake",
+		Description: "Test agent",
+		Url:         "unix:///tmp/test.sock",
+		Tools:       []tools.Tool{echo},
+	}
+
+	agent.AttachLLM(fakeLLM{})
+	require.NoError(t, agent.Initialize())
+
+	card, err := agent.GetAgentCard(context.Background(), &pb.GetAgentCardRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "test", card.GetName())
+	assert.Empty(t, card.GetUrl(), "unix sockets are not card urls")
+	assert.Equal(t, agents.Version, card.GetVersion())
+	assert.True(t, card.GetCapabilities().GetStreaming())
+	assert.True(t, card.GetCapabilities().GetPushNotifications())
+	assert.Equal(t, []string{"text/plain", "application/json"}, card.GetDefaultInputModes())
+	require.Len(t, card.GetSkills(), 1)
+	assert.Equal(t, "echo", card.GetSkills()[0].GetId())
+	assert.Equal(t, "Echo the message", card.GetSkills()[0].GetDescription())
+
+	agent.Card = &pb.AgentCard{
+		Url:     "https://agents.example.com/test",
+		Version: "1.2.3",
+		Skills:  []*pb.AgentSkill{{Id: "files", Name: "Files"}},
+	}
+
+	server := httptest.NewServer(agents.CardHandler(agent.GetAgentCard))
+	defer server.Close()
+
+	for _, path := range []string{agents.AGENT_CARD_PATH, agents.AGENT_CARD_PATH_LEGACY} {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		served := &pb.AgentCard{}
+		require.NoError(t, protojson.Unmarshal(body, served))
+		assert.Equal(t, "https://agents.example.com/test", served.GetUrl())
+		assert.Equal(t, "1.2.3", served.GetVersion())
+		assert.Equal(t, "files", served.GetSkills()[0].GetId())
+	}
+}
@@ -0,0 +1,62 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/tools"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// startServerTool stands for the tools of a lazy MCP server until the model
+// needs them. Calling it starts the server and attaches its tools, available
+// from the next request of the model.
+func (a *BaseAgent) startServerTool(server *mcp.MCPServer) tools.Tool {
+	name := "start_" + server.Name
+
+	return &startTool{
+		agent:  a,
+		server: server,
+		tool: mcp_tool.NewTool(name,
+			mcp_tool.WithDescription(fmt.Sprintf("Start the MCP server %s to use its tools, they are available after this call", server.Name)),
+			mcp_tool.WithReadOnlyHintAnnotation(true),
+			mcp_tool.WithDestructiveHintAnnotation(false),
+			mcp_tool.WithIdempotentHintAnnotation(true),
+		),
+	}
+}
+
+type startTool struct {
+	agent  *BaseAgent
+	server *mcp.MCPServer
+	tool   mcp_tool.Tool
+}
+
+func (t *startTool) Definition() mcp_tool.Tool {
+	return t.tool
+}
+
+// Path keeps the tool in the filters of the server, as server/*.
+func (t *startTool) Path() string {
+	return t.server.Name + "/" + t.tool.Name
+}
+
+func (t *startTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	if err := t.server.Start(); err != nil {
+		return nil, fmt.Errorf("error start mcp server %s, %w", t.server.Name, err)
+	}
+
+	listed := t.server.Tools()
+
+	t.agent.reloadTools(t.server.Name, listed)
+
+	names := []string{}
+	for _, tool := range listed {
+		names = append(names, tools.NewMCPTool(t.server, tool, t.agent.ToolSeparator).Definition().Name)
+	}
+
+	return mcp_tool.NewToolResultText(fmt.Sprintf("MCP server %s started, its tools are %s", t.server.Name, strings.Join(names, ", "))), nil
+}
@@ -0,0 +1,182 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/tools"
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// splitRef separates server/name references of the servers of the agent.
+func (a *BaseAgent) splitRef(ref string) (*mcp.MCPServer, string, bool) {
+	name, rest, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, "", false
+	}
+
+	server, ok := a.mcpServers[name]
+	if !ok {
+		return nil, "", false
+	}
+
+	return server, rest, true
+}
+
+// loadInstructions replaces the instructions with the MCP prompt set in
+// InstructionsPrompt, the configured instructions are appended to it.
+func (a *BaseAgent) loadInstructions() error {
+	if a.InstructionsPrompt == "" {
+		return nil
+	}
+
+	server, name, ok := a.splitRef(a.InstructionsPrompt)
+	if !ok {
+		return fmt.Errorf("error instructions prompt %s, use server/prompt_name with a server of agent %s", a.InstructionsPrompt, a.Name)
+	}
+
+	prompt, err := server.GetPrompt(name, a.InstructionsPromptArgs)
+	if err != nil {
+		return err
+	}
+
+	instructions := mcp.PromptText(prompt)
+
+	if a.Instructions != "" {
+		instructions += "\n\n" + a.Instructions
+	}
+
+	a.Instructions = instructions
+	a.llm.SetInstructions(instructions)
+
+	return nil
+}
+
+// withResources prepends the resources attached to ctx to the message.
+// Server references must be readable, plain uris that no server has are left
+// to the model.
+func (a *BaseAgent) withResources(ctx context.Context, message string) (string, error) {
+	refs := agents.ResourcesFromContext(ctx)
+	if len(refs) == 0 {
+		return message, nil
+	}
+
+	var builder strings.Builder
+
+	for _, ref := range refs {
+		if err := a.approveResource(ctx, ref); err != nil {
+			return "", err
+		}
+
+		contents, err := a.readResource(ref)
+		if err != nil {
+			if _, _, ok := a.splitRef(ref); ok {
+				return "", err
+			}
+
+			a.Logger.Warn(fmt.Sprintf("resource %s not attached", ref), "error", err)
+			continue
+		}
+
+		for _, content := range contents {
+			builder.WriteString(resourceText(content))
+		}
+	}
+
+	builder.WriteString(message)
+
+	return builder.String(), nil
+}
+
+func (a *BaseAgent) readResource(ref string) ([]mcp_tool.ResourceContents, error) {
+	if server, uri, ok := a.splitRef(ref); ok {
+		return server.ReadResource(uri)
+	}
+
+	for _, name := range a.Servers {
+		server, ok := a.mcpServers[name]
+		if !ok {
+			continue
+		}
+
+		if contents, err := server.ReadResource(ref); err == nil {
+			return contents, nil
+		}
+	}
+
+	return nil, fmt.Errorf("resource %s not found in the servers of agent %s", ref, a.Name)
+}
+
+// approveResource applies the approval policies of the agent to the read of
+// the resource, as calls of the tool server/read_resource, or read_resource
+// for plain uris.
+func (a *BaseAgent) approveResource(ctx context.Context, ref string) error {
+	req := tools.ApprovalRequest{
+		Tool:      "read_resource",
+		Path:      "read_resource",
+		Arguments: map[string]any{"uri": ref},
+	}
+
+	if server, uri, ok := a.splitRef(ref); ok {
+		req.Path = server.Name + "/read_resource"
+		req.Arguments["uri"] = uri
+	}
+
+	decision, err := tools.Approve(a.toolContext(ctx), req)
+	if err != nil {
+		return fmt.Errorf("error approve resource %s, %w", ref, err)
+	}
+
+	if !decision.Approved {
+		return fmt.Errorf("resource %s not approved, %s", ref, decision.Reason)
+	}
+
+	return nil
+}
+
+// fileResource returns the server/uri reference of a File part uri, read from
+// the first server listing it or else the first of FileServers. The uris of
+// the callers are not read from servers that do not expect them.
+func (a *BaseAgent) fileResource(uri string) (string, bool) {
+	for _, name := range a.Servers {
+		server, ok := a.mcpServers[name]
+		if !ok || (server.Lazy && !server.Started()) {
+			continue
+		}
+
+		listed, err := server.ListResources()
+		if err != nil {
+			a.Logger.Warn(fmt.Sprintf("error list resources of mcp server %s", name), "error", err)
+			continue
+		}
+
+		for _, resource := range listed {
+			if resource.URI == uri {
+				return name + "/" + uri, true
+			}
+		}
+	}
+
+	for _, name := range a.FileServers {
+		if _, ok := a.mcpServers[name]; ok && slices.Contains(a.Servers, name) {
+			return name + "/" + uri, true
+		}
+	}
+
+	return "", false
+}
+
+func resourceText(content mcp_tool.ResourceContents) string {
+	switch c := content.(type) {
+	case mcp_tool.TextResourceContents:
+		return fmt.Sprintf("<resource uri=%q mime_type=%q>\n%s\n</resource>\n\n", c.URI, c.MIMEType, c.Text)
+	case mcp_tool.BlobResourceContents:
+		return fmt.Sprintf("<resource uri=%q mime_type=%q>binary content not included</resource>\n\n", c.URI, c.MIMEType)
+	}
+
+	return ""
+}
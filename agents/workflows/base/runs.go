@@ -0,0 +1,326 @@
+package base
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jlrosende/go-agents/agents/tasks"
+	"github.com/jlrosende/go-agents/tools"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	mcp_client "github.com/mark3labs/mcp-go/client"
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// DEFAULT_RUN_TIMEOUT is the time a task may run, waiting for answers
+// included, before it fails.
+const DEFAULT_RUN_TIMEOUT = time.Hour
+
+// DEFAULT_MAX_ARTIFACT_SIZE is the size of the parts of an artifact of a
+// task, the rest is truncated.
+const DEFAULT_MAX_ARTIFACT_SIZE = 4 << 20
+
+// run is a generation started by an A2A message, the execution of a task. It
+// acts as the approver of its own tool calls and the elicitor of the MCP
+// servers it calls, moving the task to input required with every question and
+// waiting for the follow-up message with the answer.
+type run struct {
+	Id        string
+	ContextId string
+
+	tasks  *tasks.Manager
+	cancel context.CancelFunc
+
+	answers chan *pb.Message
+	// Holds one question at a time, parallel tool calls ask in turns so each
+	// answer goes to the question the client saw
+	questions chan struct{}
+
+	mu      sync.Mutex
+	waiting bool
+}
+
+// input is a question of the run, the approval of a tool call or the
+// information elicited by an MCP server.
+type input struct {
+	approval    *tools.ApprovalRequest
+	elicitation *mcp_tool.ElicitationRequest
+}
+
+func (in input) String() string {
+	if in.approval != nil {
+		return fmt.Sprintf("approval of tool %s", in.approval.Tool)
+	}
+
+	return fmt.Sprintf("elicitation %q", in.elicitation.Params.Message)
+}
+
+var (
+	_ tools.Approver                = (*run)(nil)
+	_ mcp_client.ElicitationHandler = (*run)(nil)
+)
+
+func newRun(task *pb.Task, manager *tasks.Manager, cancel context.CancelFunc) *run {
+	return &run{
+		Id:        task.GetId(),
+		ContextId: task.GetContextId(),
+		tasks:     manager,
+		cancel:    cancel,
+		answers:   make(chan *pb.Message, 1),
+		questions: make(chan struct{}, 1),
+	}
+}
+
+// ask moves the task to input required and waits for the message answering
+// the question. The run waits only once the state is saved, answers never
+// resume a task the store does not show as waiting. Questions are asked one
+// at a time, the next one waits until the answer of the current one.
+func (r *run) ask(ctx context.Context, in input) (*pb.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r.questions <- struct{}{}:
+	}
+	defer func() { <-r.questions }()
+
+	r.mu.Lock()
+
+	if _, err := r.tasks.Update(ctx, r.Id, pb.TaskState_TASK_STATE_INPUT_REQUIRED, r.InputRequired(in)); err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+
+	r.waiting = true
+	r.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		// Answers of the abandoned question never reach the next one
+		r.mu.Lock()
+		r.waiting = false
+		select {
+		case <-r.answers:
+		default:
+		}
+		r.mu.Unlock()
+
+		return nil, ctx.Err()
+	case answer := <-r.answers:
+		return answer, nil
+	}
+}
+
+func (r *run) Approve(ctx context.Context, req tools.ApprovalRequest) (tools.ApprovalDecision, error) {
+	answer, err := r.ask(ctx, input{approval: &req})
+	if err != nil {
+		return tools.ApprovalDecision{}, err
+	}
+
+	return approvalDecision(answer), nil
+}
+
+func (r *run) Elicit(ctx context.Context, req mcp_tool.ElicitationRequest) (*mcp_tool.ElicitationResult, error) {
+	answer, err := r.ask(ctx, input{elicitation: &req})
+	if err != nil {
+		return nil, err
+	}
+
+	return elicitationResult(answer), nil
+}
+
+// InputRequired returns the message of the agent describing the question.
+func (r *run) InputRequired(in input) *pb.Message {
+	var text string
+	var values map[string]any
+
+	if req := in.approval; req != nil {
+		args, _ := json.Marshal(req.Arguments)
+
+		text = fmt.Sprintf("Tool %s requires approval to run with arguments %s. Reply \"approve\" or \"deny\" in this task.", req.Tool, args)
+		values = map[string]any{
+			"tool_call_id": req.ToolCallID,
+			"tool":         req.Tool,
+			"arguments":    req.Arguments,
+			"destructive":  req.Destructive,
+		}
+	} else {
+		schema := map[string]any{}
+		if raw, err := json.Marshal(in.elicitation.Params.RequestedSchema); err == nil {
+			json.Unmarshal(raw, &schema)
+		}
+
+		text = fmt.Sprintf("%s Reply in this task with a data part following the requested schema, or \"decline\" or \"cancel\".", in.elicitation.Params.Message)
+		values = map[string]any{
+			"message":          in.elicitation.Params.Message,
+			"requested_schema": schema,
+		}
+	}
+
+	content := []*pb.Part{
+		{
+			Part: &pb.Part_Text{
+				Text: text,
+			},
+		},
+	}
+
+	data, err := structpb.NewStruct(values)
+
+	if err == nil {
+		content = append(content, &pb.Part{
+			Part: &pb.Part_Data{
+				Data: &pb.DataPart{Data: data},
+			},
+		})
+	}
+
+	return &pb.Message{
+		MessageId: uuid.NewString(),
+		Role:      pb.Role_ROLE_AGENT,
+		Content:   content,
+	}
+}
+
+// Resolve records the answer in the task, moves it back to working and hands
+// the answer to the question waiting for it.
+func (r *run) Resolve(ctx context.Context, answer *pb.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.waiting {
+		return fmt.Errorf("task %s is not waiting for input", r.Id)
+	}
+
+	if _, err := r.tasks.AddMessage(ctx, r.Id, answer); err != nil {
+		return err
+	}
+
+	if _, err := r.tasks.Update(ctx, r.Id, pb.TaskState_TASK_STATE_WORKING, nil); err != nil {
+		return err
+	}
+
+	r.waiting = false
+	r.answers <- answer
+
+	return nil
+}
+
+type runRegistry struct {
+	mu   sync.Mutex
+	runs map[string]*run
+}
+
+func newRunRegistry() *runRegistry {
+	return &runRegistry{
+		runs: map[string]*run{},
+	}
+}
+
+func (r *runRegistry) Add(run *run) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs[run.Id] = run
+}
+
+func (r *runRegistry) Get(id string) (*run, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, ok := r.runs[id]
+
+	return run, ok
+}
+
+func (r *runRegistry) Delete(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.runs, id)
+}
+
+func messageText(message *pb.Message) string {
+	var buffer bytes.Buffer
+	for _, part := range message.GetContent() {
+
+		switch p := part.GetPart().(type) {
+		case *pb.Part_Text:
+			buffer.WriteString(p.Text + "\n")
+		case *pb.Part_Data:
+			buffer.WriteString(p.Data.GetData().String() + "\n")
+		case *pb.Part_File:
+			buffer.WriteString(p.File.GetFileWithUri() + "\n")
+		}
+	}
+
+	return buffer.String()
+}
+
+// approvalDecision reads the answer to an input required task. A data part
+// {"approved": bool, "arguments": {...}, "reason": "..."} allows editing the
+// arguments, a text part approves with "approve" or "yes" and denies with
+// anything else, using the text as reason.
+func approvalDecision(message *pb.Message) tools.ApprovalDecision {
+	for _, part := range message.GetContent() {
+		data, ok := part.GetPart().(*pb.Part_Data)
+		if !ok {
+			continue
+		}
+
+		values := data.Data.GetData().AsMap()
+
+		decision := tools.ApprovalDecision{}
+		decision.Approved, _ = values["approved"].(bool)
+		decision.Arguments, _ = values["arguments"].(map[string]any)
+		decision.Reason, _ = values["reason"].(string)
+
+		if !decision.Approved && decision.Reason == "" {
+			decision.Reason = "denied by the user"
+		}
+
+		return decision
+	}
+
+	text := strings.TrimSpace(messageText(message))
+
+	switch strings.ToLower(text) {
+	case "approve", "approved", "yes", "y", "ok":
+		return tools.ApprovalDecision{Approved: true}
+	case "", "deny", "denied", "no", "n":
+		return tools.ApprovalDecision{Reason: "denied by the user"}
+	}
+
+	return tools.ApprovalDecision{Reason: text}
+}
+
+// elicitationResult reads the answer to an elicitation, a data part accepts
+// with its content and a text part declines or cancels.
+func elicitationResult(message *pb.Message) *mcp_tool.ElicitationResult {
+	result := &mcp_tool.ElicitationResult{}
+
+	for _, part := range message.GetContent() {
+		if data, ok := part.GetPart().(*pb.Part_Data); ok {
+			result.Action = mcp_tool.ElicitationResponseActionAccept
+			result.Content = data.Data.GetData().AsMap()
+
+			return result
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(messageText(message))) {
+	case "decline", "declined", "no", "n":
+		result.Action = mcp_tool.ElicitationResponseActionDecline
+	default:
+		result.Action = mcp_tool.ElicitationResponseActionCancel
+	}
+
+	return result
+}
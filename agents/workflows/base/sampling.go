@@ -0,0 +1,42 @@
+package base
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/tools"
+
+	mcp_client "github.com/mark3labs/mcp-go/client"
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// approvedSampler asks the approval policies before answering the sampling
+// requests of a server, as calls of the tool server/sampling. It runs with the
+// context of the tool call, see mcp.MCPServer.CreateMessage.
+type approvedSampler struct {
+	sampler mcp_client.SamplingHandler
+}
+
+func (s approvedSampler) CreateMessage(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error) {
+	server := mcp.ServerName(ctx)
+
+	decision, err := tools.Approve(ctx, tools.ApprovalRequest{
+		Tool: "sampling",
+		Path: server + "/sampling",
+		Arguments: map[string]any{
+			"system_prompt": request.SystemPrompt,
+			"messages":      request.Messages,
+			"max_tokens":    request.MaxTokens,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error approve sampling of mcp server %s, %w", server, err)
+	}
+
+	if !decision.Approved {
+		return nil, fmt.Errorf("sampling of mcp server %s not approved, %s", server, decision.Reason)
+	}
+
+	return s.sampler.CreateMessage(ctx, request)
+}
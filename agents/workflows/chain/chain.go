@@ -48,13 +48,16 @@ func (a *ChainAgent) Initialize() error {
 	)
 
 	if a.Url == "" {
-		a.Url = fmt.Sprintf("unix:///tmp/go-agent-%s.sock", a.Name)
+		a.Url = agents.DefaultUrl(a.Name)
 	}
 
-	if a.Protocol == "" {
-		a.Protocol = agents.PROTOCOL_UNIX
+	protocol, _, err := agents.ParseAddress(a.ListenAddress())
+	if err != nil {
+		return fmt.Errorf("error agent %s listen address, %w", a.Name, err)
 	}
 
+	a.Protocol = protocol
+
 	grpcPanicRecoveryHandler := func(p any) (err error) {
 		a.Logger.Error("recovered from panic")
 		return status.Errorf(codes.Internal, "%s", p)
@@ -66,7 +69,7 @@ func (a *ChainAgent) Initialize() error {
 		})
 	}
 
-	a.Server = grpc.NewServer(
+	a.GRPCServerOptions = []grpc.ServerOption{
 		grpc.ChainStreamInterceptor(
 			logging.StreamServerInterceptor(
 				interceptorLogger(a.Logger),
@@ -82,7 +85,7 @@ func (a *ChainAgent) Initialize() error {
 			),
 			recovery.UnaryServerInterceptor(recovery.WithRecoveryHandler(grpcPanicRecoveryHandler)),
 		),
-	)
+	}
 
 	return nil
 }
@@ -110,9 +113,7 @@ func (a *ChainAgent) Start() error {
 
 	a.Logger.Debug("start SERVER")
 
-	err := a.StartServer(func(server *grpc.Server) {
-		pb.RegisterA2AServiceServer(a.Server, a)
-	})
+	err := a.StartServer(a)
 
 	if err != nil {
 		return fmt.Errorf("error start agent %s server, %w", a.GetName(), err)
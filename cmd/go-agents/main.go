@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/jlrosende/go-agents/agents/workflows/base"
 	"github.com/jlrosende/go-agents/agents/workflows/chain"
 	"github.com/jlrosende/go-agents/controller"
 	"github.com/jlrosende/go-agents/llm/providers"
+	"github.com/jlrosende/go-agents/tools"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
 )
 
 func main() {
@@ -18,6 +23,25 @@ func main() {
 		panic(err)
 	}
 
+	clock, err := tools.NewFunctionTool(
+		"current_time",
+		"Current date and time in a IANA time zone",
+		func(ctx context.Context, args struct {
+			TimeZone string `json:"time_zone" jsonschema_description:"IANA time zone, e.g. Europe/Madrid"`
+		}) (string, error) {
+			location, err := time.LoadLocation(args.TimeZone)
+			if err != nil {
+				return "", err
+			}
+			return time.Now().In(location).Format(time.RFC1123), nil
+		},
+		mcp_tool.WithReadOnlyHintAnnotation(true),
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
 	swarm.AddAgent(&base.BaseAgent{
 		Name:          "base_agent",
 		Description:   "texting model",
@@ -25,6 +49,7 @@ func main() {
 		Model:         "openai.o4-mini.high",
 		Instructions:  "Yo are a AI assystant",
 		RequestParams: providers.NewRequestParams(),
+		Tools:         []tools.Tool{clock},
 	})
 
 	swarm.AddAgent(chain.NewChainAgent(
@@ -3,15 +3,22 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/jlrosende/go-agents/agents/tasks"
 	"github.com/jlrosende/go-agents/llm/providers"
 	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/tools"
 	"github.com/spf13/viper"
 )
 
 type AgentsConfig struct {
 	Agents map[string]Agent `mapstructure:"agents"`
 	MCP    MCP              `mapstructure:"mcp"`
+	Server Server           `mapstructure:"server"`
 
 	OpenAI     OpenAI     `mapstructure:"openai"`
 	Anthropic  Anthropic  `mapstructure:"anthropic"`
@@ -27,6 +34,21 @@ type AgentsConfig struct {
 
 type MCP struct {
 	Servers map[string]MCPServer `mapstructure:"servers"`
+	Serve   MCPServe             `mapstructure:"serve"`
+
+	// Tools are sent to the model as server<separator>tool, empty keeps the
+	// tool names
+	ToolSeparator  string                `mapstructure:"tool_separator"`
+	ToolCollisions tools.CollisionPolicy `mapstructure:"tool_collisions"`
+}
+
+// MCPServe exposes the agents as tools of an MCP server, disabled when the
+// transport is empty.
+type MCPServe struct {
+	Transport mcp.Transport `mapstructure:"transport"`
+	Address   string        `mapstructure:"address"`
+	Endpoint  string        `mapstructure:"endpoint"`
+	Agents    []string      `mapstructure:"agents"`
 }
 
 type MCPServer struct {
@@ -36,27 +58,261 @@ type MCPServer struct {
 	Args         []string          `mapstructure:"args"`
 	Headers      map[string]string `mapstructure:"headers"`
 	Environments map[string]string `mapstructure:"env"`
+	OAuth        *MCPOAuth         `mapstructure:"oauth"`
+	Sandbox      MCPSandbox        `mapstructure:"sandbox"`
+
+	// Lazy servers start on the first request of an agent
+	Lazy           bool          `mapstructure:"lazy"`
+	StartupTimeout time.Duration `mapstructure:"startup_timeout"`
+
+	// Timeout of every tool call, 5m when 0, and of the tools matching the
+	// names or globs of ToolTimeouts
+	ToolTimeout  time.Duration `mapstructure:"tool_timeout"`
+	ToolTimeouts []ToolTimeout `mapstructure:"tool_timeouts"`
+
+	// Health checks, zero values keep the defaults
+	PingInterval time.Duration `mapstructure:"ping_interval"`
+	PingTimeout  time.Duration `mapstructure:"ping_timeout"`
+	MaxBackoff   time.Duration `mapstructure:"max_backoff"`
+}
+
+// ToolTimeout is the timeout of the tools matching the name or glob, a list
+// since the keys of maps are lowercased.
+type ToolTimeout struct {
+	Name    string        `mapstructure:"name"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// MCPOAuth enables the OAuth authorization of remote servers, the client is
+// registered dynamically when ClientID is empty.
+type MCPOAuth struct {
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+	RedirectURI  string   `mapstructure:"redirect_uri"`
+	MetadataURL  string   `mapstructure:"metadata_url"`
+	TokenFile    string   `mapstructure:"token_file"`
+}
+
+// MCPSandbox restricts the process of stdio servers.
+type MCPSandbox struct {
+	Cwd string `mapstructure:"cwd"`
+	// Inherited environment: "none", "allowlist" or "full" (default)
+	EnvMode      mcp.EnvMode `mapstructure:"env_mode"`
+	EnvAllowlist []string    `mapstructure:"env_allowlist"`
+
+	CPUTime   time.Duration `mapstructure:"cpu_time"`
+	MemoryMB  int           `mapstructure:"memory_mb"`
+	OpenFiles int           `mapstructure:"open_files"`
+
+	// Linux namespaces: user, pid, ipc, uts, net, cgroup
+	Namespaces []string `mapstructure:"namespaces"`
 }
 
 type Agent struct {
-	Url           string         `mapstructure:"url"`
-	Description   string         `mapstructure:"description"`
-	Model         string         `mapstructure:"model"`
-	Instructions  string         `mapstructure:"instructions"`
-	Servers       []string       `mapstructure:"servers"`
-	IncludeTools  []string       `mapstructure:"include_tools"`
-	ExcludeTools  []string       `mapstructure:"exclude_tools"`
-	RequestParams *RequestParams `mapstructure:"request_params"`
+	Url          string         `mapstructure:"url"`
+	Description  string         `mapstructure:"description"`
+	InputSchema  map[string]any `mapstructure:"input_schema"`
+	Model        string         `mapstructure:"model"`
+	Instructions string         `mapstructure:"instructions"`
+	// Model summarizing long tool results, the model of the agent when empty
+	SummaryModel string `mapstructure:"summary_model"`
+	// MCP prompt used as instructions, server/prompt_name
+	InstructionsPrompt     string            `mapstructure:"instructions_prompt"`
+	InstructionsPromptArgs map[string]string `mapstructure:"instructions_prompt_args"`
+	Servers                []string          `mapstructure:"servers"`
+	Roots                  []string          `mapstructure:"roots"`
+	IncludeTools           []string          `mapstructure:"include_tools"`
+	ExcludeTools           []string          `mapstructure:"exclude_tools"`
+	AgentTools             []string          `mapstructure:"agent_tools"`
+	RequestParams          *RequestParams    `mapstructure:"request_params"`
+	ToolApproval           ToolApproval      `mapstructure:"tool_approval"`
+	ToolCache              *ToolCache        `mapstructure:"tool_cache"`
+	Tasks                  Tasks             `mapstructure:"tasks"`
+	// Servers reading any uri of the File parts of A2A messages, the rest
+	// read only the resources they list
+	FileServers []string `mapstructure:"file_servers"`
+	// Time the history of an A2A context is kept after its last message
+	ConversationTimeout time.Duration `mapstructure:"conversation_timeout"`
+	// Time an A2A task may run, waiting for approvals included
+	RunTimeout time.Duration `mapstructure:"run_timeout"`
+	Card       AgentCard     `mapstructure:"card"`
+	// Bytes of the parts of an artifact of an A2A task, the rest is truncated
+	MaxArtifactSize int `mapstructure:"max_artifact_size"`
+	// Address of the A2A JSON-RPC over HTTP binding, also serving the card
+	HTTPAddr string `mapstructure:"http_addr"`
+	// Serves the REST gateway on the port of the gRPC server
+	Gateway bool `mapstructure:"gateway"`
+	// Address the server listens at, the url when empty
+	Listen string `mapstructure:"listen"`
+	TLS    *TLS   `mapstructure:"tls"`
+	// Secrets of the security schemes of the card and the policy
+	Auth *Auth `mapstructure:"auth"`
+	// Credentials sent by the client of the agent
+	ClientCredentials *ClientCredentials `mapstructure:"client_credentials"`
+}
+
+// Auth verifies the requests with the security schemes of the card of the
+// agent, API keys for apiKey and JWT checked with a JWKS file for http
+// bearer, oauth2 and openIdConnect. The policy restricts the principals and
+// their skills, the paths of the tools, every one may call the agent without
+// it.
+type Auth struct {
+	APIKeys  []APIKey   `mapstructure:"api_keys"`
+	JWKSFile string     `mapstructure:"jwks_file"`
+	Issuer   string     `mapstructure:"issuer"`
+	Audience string     `mapstructure:"audience"`
+	Policy   []AuthRule `mapstructure:"policy"`
+}
+
+// APIKey is the key of a principal, a list since the keys of maps are
+// lowercased.
+type APIKey struct {
+	Principal string `mapstructure:"principal"`
+	Key       string `mapstructure:"key"`
+}
+
+// AuthRule allows principals, "*" for any, to use skills, every skill when
+// empty.
+type AuthRule struct {
+	Principals []string `mapstructure:"principals"`
+	Skills     []string `mapstructure:"skills"`
+}
+
+// ClientCredentials authenticate the calls to the agent, an API key in
+// Header (X-API-Key by default) and a bearer token, read from TokenFile on
+// every call when it is set.
+type ClientCredentials struct {
+	Header    string `mapstructure:"header"`
+	APIKey    string `mapstructure:"api_key"`
+	Token     string `mapstructure:"token"`
+	TokenFile string `mapstructure:"token_file"`
+}
+
+// Server hosts the agents on one gRPC server, disabled when Listen is empty.
+// Without Agents it hosts every agent without url, listen, http_addr or
+// gateway, those keep their own servers. The host serves only gRPC, hosted
+// agents have no HTTP agent card, JSON-RPC nor REST binding.
+type Server struct {
+	Listen string   `mapstructure:"listen"`
+	TLS    *TLS     `mapstructure:"tls"`
+	Agents []string `mapstructure:"agents"`
+}
+
+// TLS of the servers and the client of an agent, client_ca_file enables mTLS.
+type TLS struct {
+	CertFile       string `mapstructure:"cert_file"`
+	KeyFile        string `mapstructure:"key_file"`
+	ClientCAFile   string `mapstructure:"client_ca_file"`
+	CAFile         string `mapstructure:"ca_file"`
+	ClientCertFile string `mapstructure:"client_cert_file"`
+	ClientKeyFile  string `mapstructure:"client_key_file"`
+	ServerName     string `mapstructure:"server_name"`
+}
+
+// AgentCard declares the fields of the A2A card of the agent, the rest are
+// filled with what the agent implements.
+type AgentCard struct {
+	Url              string         `mapstructure:"url"`
+	Version          string         `mapstructure:"version"`
+	DocumentationUrl string         `mapstructure:"documentation_url"`
+	Provider         *AgentProvider `mapstructure:"provider"`
+	InputModes       []string       `mapstructure:"input_modes"`
+	OutputModes      []string       `mapstructure:"output_modes"`
+	// Skills of the agent, its tools when empty
+	Skills          []AgentSkill              `mapstructure:"skills"`
+	SecuritySchemes map[string]SecurityScheme `mapstructure:"security_schemes"`
+	// Alternatives of the schemes required, with their scopes
+	Security []map[string][]string `mapstructure:"security"`
+}
+
+type AgentProvider struct {
+	Organization string `mapstructure:"organization"`
+	Url          string `mapstructure:"url"`
+}
+
+type AgentSkill struct {
+	Id          string   `mapstructure:"id"`
+	Name        string   `mapstructure:"name"`
+	Description string   `mapstructure:"description"`
+	Tags        []string `mapstructure:"tags"`
+	Examples    []string `mapstructure:"examples"`
+	InputModes  []string `mapstructure:"input_modes"`
+	OutputModes []string `mapstructure:"output_modes"`
+}
+
+// SecurityScheme follows the OpenAPI security schemes: apiKey, http, oauth2
+// with the authorization_code or client_credentials flow and openIdConnect,
+// and mutualTLS.
+type SecurityScheme struct {
+	Type        string `mapstructure:"type"`
+	Description string `mapstructure:"description"`
+	// apiKey, the location is header, query or cookie
+	Location string `mapstructure:"location"`
+	Name     string `mapstructure:"name"`
+	// http, the scheme is bearer or basic
+	Scheme       string `mapstructure:"scheme"`
+	BearerFormat string `mapstructure:"bearer_format"`
+	// oauth2
+	Flow             string            `mapstructure:"flow"`
+	AuthorizationUrl string            `mapstructure:"authorization_url"`
+	TokenUrl         string            `mapstructure:"token_url"`
+	RefreshUrl       string            `mapstructure:"refresh_url"`
+	Scopes           map[string]string `mapstructure:"scopes"`
+	// openIdConnect
+	OpenIdConnectUrl string `mapstructure:"open_id_connect_url"`
+}
+
+// Tasks selects the store of the A2A tasks of the agent, "memory" by default
+// or "file" to keep them in Path across restarts. The memory store forgets
+// terminal tasks after Retention, 24h when 0.
+type Tasks struct {
+	Store     tasks.StoreType `mapstructure:"store"`
+	Path      string          `mapstructure:"path"`
+	Retention time.Duration   `mapstructure:"retention"`
+	// Hosts of push notification webhooks allowed at loopback, private or
+	// link-local addresses
+	PushAllowedHosts []string `mapstructure:"push_allowed_hosts"`
+}
+
+type ToolApproval struct {
+	Default tools.ApprovalPolicy `mapstructure:"default"`
+	// Policy of the tools hinted destructive without a policy of their own
+	Destructive tools.ApprovalPolicy `mapstructure:"destructive"`
+	Tools       []ToolPolicy         `mapstructure:"tools"`
+}
+
+// ToolPolicy is the approval policy of the tools matching the name or glob.
+type ToolPolicy struct {
+	Name   string               `mapstructure:"name"`
+	Policy tools.ApprovalPolicy `mapstructure:"policy"`
+}
+
+// ToolCache enables caching the results of the read-only tools of the agent,
+// and of the tools listed with their TTL, zero uses TTL.
+type ToolCache struct {
+	TTL   time.Duration `mapstructure:"ttl"`
+	Tools []ToolTTL     `mapstructure:"tools"`
+}
+
+// ToolTTL caches the results of the tools matching the name or glob for TTL.
+type ToolTTL struct {
+	Name string        `mapstructure:"name"`
+	TTL  time.Duration `mapstructure:"ttl"`
 }
 
 type RequestParams struct {
 	UseHistory        *bool                      `mapstructure:"use_history"`
 	ParallelToolCalls *bool                      `mapstructure:"parallel_tool_calls"`
 	MaxIterations     *int                       `mapstructure:"max_iterations"`
+	MaxRepeatedCalls  *int                       `mapstructure:"max_repeated_calls"`
 	MaxTokens         *int64                     `mapstructure:"max_tokens"`
 	Temperature       *float64                   `mapstructure:"temperature"`
 	Reasoning         *bool                      `mapstructure:"reasoning"`
 	ReasoningEffort   *providers.ReasoningEffort `mapstructure:"reasoning_effort"`
+
+	MaxToolResultSize    *int              `mapstructure:"max_tool_result_size"`
+	ToolResultTruncation *tools.Truncation `mapstructure:"tool_result_truncation"`
 }
 
 type Anthropic struct {
@@ -122,6 +378,12 @@ func LoadConfig() (*AgentsConfig, error) {
 	config.SetDefault("openrouter.base_url", "https://openrouter.ai/api/v1/")
 	config.SetDefault("google.base_url", "https://generativelanguage.googleapis.com/v1beta/openai/")
 
+	// MCP server defaults
+	config.SetDefault("mcp.tool_separator", tools.DEFAULT_SEPARATOR)
+	config.SetDefault("mcp.tool_collisions", tools.COLLISION_ERROR)
+	config.SetDefault("mcp.serve.address", ":8090")
+	config.SetDefault("mcp.serve.endpoint", "/mcp")
+
 	// logger defaults
 	config.SetDefault("logger.type", "console")
 	config.SetDefault("logger.level", "warn")
@@ -164,5 +426,103 @@ func LoadConfig() (*AgentsConfig, error) {
 		return nil, fmt.Errorf("error load agents.config.yaml. %w", err)
 	}
 
+	for name, server := range agentsConfig.MCP.Servers {
+		if err := server.interpolate(); err != nil {
+			return nil, fmt.Errorf("error load mcp server %s, %w", name, err)
+		}
+
+		agentsConfig.MCP.Servers[name] = server
+	}
+
+	for name, agent := range agentsConfig.Agents {
+		if err := agent.interpolate(); err != nil {
+			return nil, fmt.Errorf("error load agent %s, %w", name, err)
+		}
+
+		agentsConfig.Agents[name] = agent
+	}
+
 	return &agentsConfig, nil
 }
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Interpolate replaces ${VAR} with the environment variable VAR, failing when
+// it is not set so secrets are never sent empty.
+func Interpolate(value string) (string, error) {
+	var missing []string
+
+	result := envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := envPattern.FindStringSubmatch(match)[1]
+
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+
+		return env
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variables not set %s", strings.Join(missing, ", "))
+	}
+
+	return result, nil
+}
+
+// interpolate expands the values that usually hold secrets.
+func (s *MCPServer) interpolate() error {
+	var err error
+
+	expand := func(value *string) {
+		if err != nil {
+			return
+		}
+		*value, err = Interpolate(*value)
+	}
+
+	expand(&s.Url)
+
+	for i := range s.Args {
+		expand(&s.Args[i])
+	}
+
+	for _, values := range []map[string]string{s.Headers, s.Environments} {
+		for key, value := range values {
+			expand(&value)
+			values[key] = value
+		}
+	}
+
+	if s.OAuth != nil {
+		expand(&s.OAuth.ClientID)
+		expand(&s.OAuth.ClientSecret)
+	}
+
+	return err
+}
+
+// interpolate expands the API keys and the client credentials.
+func (a *Agent) interpolate() error {
+	var err error
+
+	expand := func(value *string) {
+		if err != nil {
+			return
+		}
+		*value, err = Interpolate(*value)
+	}
+
+	if a.Auth != nil {
+		for i := range a.Auth.APIKeys {
+			expand(&a.Auth.APIKeys[i].Key)
+		}
+	}
+
+	if a.ClientCredentials != nil {
+		expand(&a.ClientCredentials.APIKey)
+		expand(&a.ClientCredentials.Token)
+	}
+
+	return err
+}
@@ -2,7 +2,9 @@ package config_test
 
 import (
 	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/jlrosende/go-agents/config"
 	"github.com/stretchr/testify/assert"
@@ -18,3 +20,63 @@ func TestLoadConfig(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestLoadConfigKeepsCase(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("GO_AGENTS_KEY", "S3cret-Key")
+
+	yaml := `
+agents:
+  one:
+    auth:
+      api_keys:
+        - principal: CI
+          key: "${GO_AGENTS_KEY}"
+    tool_approval:
+      tools:
+        - name: Write_File
+          policy: ask
+    tool_cache:
+      tools:
+        - name: List_Directory
+          ttl: 30s
+mcp:
+  servers:
+    fetch:
+      tool_timeouts:
+        - name: Fetch
+          timeout: 10s
+`
+	assert.NoError(t, os.WriteFile("agents.config.yaml", []byte(yaml), 0o600))
+
+	conf, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	agent := conf.Agents["one"]
+
+	assert.Equal(t, []config.APIKey{{Principal: "CI", Key: "S3cret-Key"}}, agent.Auth.APIKeys)
+	assert.Equal(t, "Write_File", agent.ToolApproval.Tools[0].Name)
+	assert.Equal(t, config.ToolTTL{Name: "List_Directory", TTL: 30 * time.Second}, agent.ToolCache.Tools[0])
+	assert.Equal(t, config.ToolTimeout{Name: "Fetch", Timeout: 10 * time.Second}, conf.MCP.Servers["fetch"].ToolTimeouts[0])
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("GO_AGENTS_TOKEN", "secret")
+
+	t.Run("replace variables", func(t *testing.T) {
+		value, err := config.Interpolate("Bearer ${GO_AGENTS_TOKEN}")
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer secret", value)
+	})
+
+	t.Run("keep other dollars", func(t *testing.T) {
+		value, err := config.Interpolate("pa$$word $GO_AGENTS_TOKEN")
+		assert.NoError(t, err)
+		assert.Equal(t, "pa$$word $GO_AGENTS_TOKEN", value)
+	})
+
+	t.Run("fail on missing variables", func(t *testing.T) {
+		_, err := config.Interpolate("${GO_AGENTS_MISSING}")
+		assert.ErrorContains(t, err, "GO_AGENTS_MISSING")
+	})
+}
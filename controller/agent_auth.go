@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jlrosende/go-agents/agents/auth"
+	"github.com/jlrosende/go-agents/config"
+)
+
+// agentAuthorizer enforces the security schemes of the card of an agent and
+// its policy, nil when it has neither.
+func agentAuthorizer(conf config.Agent) (*auth.Authorizer, error) {
+	secrets := conf.Auth
+	if secrets == nil {
+		secrets = &config.Auth{}
+	}
+
+	// Declared schemes are never left open
+	if len(conf.Card.SecuritySchemes) > 0 && len(conf.Card.Security) == 0 {
+		return nil, fmt.Errorf("error auth security, card.security is empty, declare the requirements of the schemes")
+	}
+
+	if len(conf.Card.Security) == 0 && len(secrets.Policy) == 0 {
+		return nil, nil
+	}
+
+	authorizer := &auth.Authorizer{Schemes: map[string]auth.Authenticator{}}
+
+	var jwks *auth.JWKS
+
+	for name, scheme := range conf.Card.SecuritySchemes {
+		var authenticator auth.Authenticator
+
+		switch scheme.Type {
+		case "apiKey":
+			// Query parameters and cookies do not reach the gRPC server
+			if scheme.Location != "header" {
+				return nil, fmt.Errorf("error auth scheme %s, api keys are checked in headers", name)
+			}
+
+			if len(secrets.APIKeys) == 0 {
+				return nil, fmt.Errorf("error auth scheme %s, auth.api_keys is empty", name)
+			}
+
+			keys := map[string]string{}
+
+			for _, key := range secrets.APIKeys {
+				if key.Key == "" || key.Principal == "" {
+					return nil, fmt.Errorf("error auth scheme %s, api keys need key and principal", name)
+				}
+
+				keys[key.Key] = key.Principal
+			}
+
+			authenticator = &auth.APIKey{Name: scheme.Name, Keys: keys}
+
+		case "http", "oauth2", "openIdConnect":
+			if scheme.Type == "http" && scheme.Scheme != "bearer" {
+				return nil, fmt.Errorf("error auth scheme %s, http scheme %q not supported, use bearer", name, scheme.Scheme)
+			}
+
+			if secrets.JWKSFile == "" {
+				return nil, fmt.Errorf("error auth scheme %s, auth.jwks_file is empty", name)
+			}
+
+			if jwks == nil {
+				var err error
+				if jwks, err = auth.LoadJWKS(secrets.JWKSFile); err != nil {
+					return nil, fmt.Errorf("error auth scheme %s, %w", name, err)
+				}
+			}
+
+			authenticator = &auth.JWT{Keys: jwks, Issuer: secrets.Issuer, Audience: secrets.Audience}
+
+		case "mutualTLS":
+			if conf.TLS == nil || conf.TLS.ClientCAFile == "" {
+				return nil, fmt.Errorf("error auth scheme %s, mutualTLS needs tls.client_ca_file", name)
+			}
+
+			authenticator = &auth.MutualTLS{}
+
+		default:
+			return nil, fmt.Errorf("error auth scheme %s, type %q not supported", name, scheme.Type)
+		}
+
+		authorizer.Schemes[name] = authenticator
+	}
+
+	for _, requirement := range conf.Card.Security {
+		names := []string{}
+
+		for name := range requirement {
+			if _, ok := authorizer.Schemes[name]; !ok {
+				return nil, fmt.Errorf("error auth security, unknown scheme %s", name)
+			}
+
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+
+		authorizer.Security = append(authorizer.Security, names)
+	}
+
+	for _, rule := range secrets.Policy {
+		if len(rule.Principals) == 0 {
+			return nil, fmt.Errorf("error auth policy, rules need principals")
+		}
+
+		authorizer.Policy = append(authorizer.Policy, auth.Rule{
+			Principals: rule.Principals,
+			Skills:     rule.Skills,
+		})
+	}
+
+	return authorizer, nil
+}
+
+// clientCredentials converts the credentials of the client of an agent, nil
+// when it has none.
+func clientCredentials(conf *config.ClientCredentials) *auth.ClientCredentials {
+	if conf == nil {
+		return nil
+	}
+
+	return &auth.ClientCredentials{
+		Header:    conf.Header,
+		APIKey:    conf.APIKey,
+		Token:     conf.Token,
+		TokenFile: conf.TokenFile,
+	}
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/jlrosende/go-agents/config"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+// agentCard converts the declared fields of the card of an agent.
+func agentCard(conf config.AgentCard) (*pb.AgentCard, error) {
+	card := &pb.AgentCard{
+		Url:                conf.Url,
+		Version:            conf.Version,
+		DocumentationUrl:   conf.DocumentationUrl,
+		DefaultInputModes:  conf.InputModes,
+		DefaultOutputModes: conf.OutputModes,
+	}
+
+	if conf.Provider != nil {
+		card.Provider = &pb.AgentProvider{
+			Organization: conf.Provider.Organization,
+			Url:          conf.Provider.Url,
+		}
+	}
+
+	for _, skill := range conf.Skills {
+		if skill.Id == "" || skill.Name == "" {
+			return nil, fmt.Errorf("error agent card, skills need an id and a name")
+		}
+
+		card.Skills = append(card.Skills, &pb.AgentSkill{
+			Id:          skill.Id,
+			Name:        skill.Name,
+			Description: skill.Description,
+			Tags:        skill.Tags,
+			Examples:    skill.Examples,
+			InputModes:  skill.InputModes,
+			OutputModes: skill.OutputModes,
+		})
+	}
+
+	if len(conf.SecuritySchemes) > 0 {
+		card.SecuritySchemes = map[string]*pb.SecurityScheme{}
+	}
+
+	for name, scheme := range conf.SecuritySchemes {
+		securityScheme, err := securityScheme(scheme)
+		if err != nil {
+			return nil, fmt.Errorf("error agent card security scheme %s, %w", name, err)
+		}
+
+		if securityScheme != nil {
+			card.SecuritySchemes[name] = securityScheme
+		}
+	}
+
+	for _, requirement := range conf.Security {
+		security := &pb.Security{Schemes: map[string]*pb.StringList{}}
+
+		for name, scopes := range requirement {
+			if _, ok := conf.SecuritySchemes[name]; !ok {
+				return nil, fmt.Errorf("error agent card security, unknown scheme %s", name)
+			}
+
+			if _, ok := card.SecuritySchemes[name]; ok {
+				security.Schemes[name] = &pb.StringList{List: scopes}
+			}
+		}
+
+		card.Security = append(card.Security, security)
+	}
+
+	return card, nil
+}
+
+func securityScheme(scheme config.SecurityScheme) (*pb.SecurityScheme, error) {
+	switch scheme.Type {
+	case "apiKey":
+		switch scheme.Location {
+		case "header", "query", "cookie":
+		default:
+			return nil, fmt.Errorf("api key location %q not supported, use header, query or cookie", scheme.Location)
+		}
+
+		return &pb.SecurityScheme{
+			Scheme: &pb.SecurityScheme_ApiKeySecurityScheme{
+				ApiKeySecurityScheme: &pb.APIKeySecurityScheme{
+					Description: scheme.Description,
+					Location:    scheme.Location,
+					Name:        scheme.Name,
+				},
+			},
+		}, nil
+
+	case "http":
+		return &pb.SecurityScheme{
+			Scheme: &pb.SecurityScheme_HttpAuthSecurityScheme{
+				HttpAuthSecurityScheme: &pb.HTTPAuthSecurityScheme{
+					Description:  scheme.Description,
+					Scheme:       scheme.Scheme,
+					BearerFormat: scheme.BearerFormat,
+				},
+			},
+		}, nil
+
+	case "oauth2":
+		flows := &pb.OAuthFlows{}
+
+		switch scheme.Flow {
+		case "authorization_code":
+			flows.Flow = &pb.OAuthFlows_AuthorizationCode{
+				AuthorizationCode: &pb.AuthorizationCodeOAuthFlow{
+					AuthorizationUrl: scheme.AuthorizationUrl,
+					TokenUrl:         scheme.TokenUrl,
+					RefreshUrl:       scheme.RefreshUrl,
+					Scopes:           scheme.Scopes,
+				},
+			}
+		case "client_credentials":
+			flows.Flow = &pb.OAuthFlows_ClientCredentials{
+				ClientCredentials: &pb.ClientCredentialsOAuthFlow{
+					TokenUrl:   scheme.TokenUrl,
+					RefreshUrl: scheme.RefreshUrl,
+					Scopes:     scheme.Scopes,
+				},
+			}
+		default:
+			return nil, fmt.Errorf("oauth2 flow %q not supported, use authorization_code or client_credentials", scheme.Flow)
+		}
+
+		return &pb.SecurityScheme{
+			Scheme: &pb.SecurityScheme_Oauth2SecurityScheme{
+				Oauth2SecurityScheme: &pb.OAuth2SecurityScheme{
+					Description: scheme.Description,
+					Flows:       flows,
+				},
+			},
+		}, nil
+
+	case "openIdConnect":
+		return &pb.SecurityScheme{
+			Scheme: &pb.SecurityScheme_OpenIdConnectSecurityScheme{
+				OpenIdConnectSecurityScheme: &pb.OpenIdConnectSecurityScheme{
+					Description:      scheme.Description,
+					OpenIdConnectUrl: scheme.OpenIdConnectUrl,
+				},
+			},
+		}, nil
+
+	case "mutualTLS":
+		// This version of the card has no mutual TLS scheme, the TLS handshake
+		// asks the clients for their certificate
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("type %q not supported, use apiKey, http, oauth2, openIdConnect or mutualTLS", scheme.Type)
+}
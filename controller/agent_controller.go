@@ -2,18 +2,26 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/agents/host"
+	"github.com/jlrosende/go-agents/agents/tasks"
 	"github.com/jlrosende/go-agents/agents/workflows/base"
 	"github.com/jlrosende/go-agents/agents/workflows/chain"
 	"github.com/jlrosende/go-agents/config"
 	"github.com/jlrosende/go-agents/llm"
 	"github.com/jlrosende/go-agents/llm/providers"
 	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/tools"
 	"golang.org/x/sync/errgroup"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
 )
 
 type AgentsController struct {
@@ -21,6 +29,9 @@ type AgentsController struct {
 	Config     *config.AgentsConfig
 	Agents     map[string]agents.Agent
 	MCPServers map[string]*mcp.MCPServer
+
+	// Agents not run, with the reason, see Run
+	disabled map[string]error
 }
 
 func NewAgentsController() (*AgentsController, error) {
@@ -78,6 +89,11 @@ func NewAgentsController() (*AgentsController, error) {
 				reqParams.MaxIterations = *agent.RequestParams.MaxIterations
 			}
 
+			// 	providers.WithMaxRepeatedCalls(agent.RequestParams.MaxRepeatedCalls),
+			if agent.RequestParams.MaxRepeatedCalls != nil {
+				reqParams.MaxRepeatedCalls = *agent.RequestParams.MaxRepeatedCalls
+			}
+
 			// 	providers.WithMaxTokens(agent.RequestParams.MaxTokens),
 			if agent.RequestParams.MaxTokens != nil {
 				reqParams.MaxTokens = *agent.RequestParams.MaxTokens
@@ -97,18 +113,90 @@ func NewAgentsController() (*AgentsController, error) {
 			if agent.RequestParams.ReasoningEffort != nil {
 				reqParams.ReasoningEffort = *agent.RequestParams.ReasoningEffort
 			}
+
+			if agent.RequestParams.MaxToolResultSize != nil {
+				reqParams.MaxToolResultSize = *agent.RequestParams.MaxToolResultSize
+			}
+
+			if agent.RequestParams.ToolResultTruncation != nil {
+				if err := agent.RequestParams.ToolResultTruncation.Validate(); err != nil {
+					return nil, fmt.Errorf("error load agent %s, %w", name, err)
+				}
+
+				reqParams.ToolResultTruncation = *agent.RequestParams.ToolResultTruncation
+			}
+		}
+
+		var toolCache *tools.ResultCache
+
+		if agent.ToolCache != nil {
+			ttls := map[string]time.Duration{}
+
+			for _, tool := range agent.ToolCache.Tools {
+				ttls[tool.Name] = tool.TTL
+			}
+
+			toolCache = tools.NewResultCache(agent.ToolCache.TTL, ttls)
+		}
+
+		taskStore, err := tasks.NewStore(agent.Tasks.Store, agent.Tasks.Path, agent.Tasks.Retention)
+		if err != nil {
+			return nil, fmt.Errorf("error load agent %s, %w", name, err)
+		}
+
+		card, err := agentCard(agent.Card)
+		if err != nil {
+			return nil, fmt.Errorf("error load agent %s, %w", name, err)
+		}
+
+		authorizer, err := agentAuthorizer(agent)
+		if err != nil {
+			return nil, fmt.Errorf("error load agent %s, %w", name, err)
+		}
+
+		policies := map[string]tools.ApprovalPolicy{}
+
+		for _, tool := range agent.ToolApproval.Tools {
+			policies[tool.Name] = tool.Policy
 		}
 
 		agentsMap[name] = &base.BaseAgent{
-			Name:          name,
-			Url:           agent.Url,
-			Description:   agent.Description,
-			Model:         agent.Model,
-			Instructions:  agent.Instructions,
-			Servers:       agent.Servers,
-			IncludeTools:  agent.IncludeTools,
-			ExcludeTools:  agent.ExcludeTools,
-			RequestParams: reqParams,
+			Name:                   name,
+			Url:                    agent.Url,
+			Listen:                 agent.Listen,
+			TLS:                    agentTLS(agent.TLS),
+			Authorizer:             authorizer,
+			Credentials:            clientCredentials(agent.ClientCredentials),
+			Description:            agent.Description,
+			InputSchema:            agent.InputSchema,
+			Model:                  agent.Model,
+			Instructions:           agent.Instructions,
+			SummaryModel:           agent.SummaryModel,
+			InstructionsPrompt:     agent.InstructionsPrompt,
+			InstructionsPromptArgs: agent.InstructionsPromptArgs,
+			Servers:                agent.Servers,
+			Roots:                  agent.Roots,
+			FileServers:            agent.FileServers,
+			IncludeTools:           agent.IncludeTools,
+			ExcludeTools:           agent.ExcludeTools,
+			ToolSeparator:          conf.MCP.ToolSeparator,
+			ToolCollisions:         conf.MCP.ToolCollisions,
+			AgentTools:             agent.AgentTools,
+			RequestParams:          reqParams,
+			ToolCache:              toolCache,
+			Tasks:                  taskStore,
+			PushAllowedHosts:       agent.Tasks.PushAllowedHosts,
+			ConversationTimeout:    agent.ConversationTimeout,
+			RunTimeout:             agent.RunTimeout,
+			MaxArtifactSize:        agent.MaxArtifactSize,
+			Card:                   card,
+			HTTPAddr:               agent.HTTPAddr,
+			Gateway:                agent.Gateway,
+			ToolApproval: tools.ApprovalPolicies{
+				Default:     agent.ToolApproval.Default,
+				Destructive: agent.ToolApproval.Destructive,
+				Tools:       policies,
+			},
 		}
 
 	}
@@ -117,7 +205,66 @@ func NewAgentsController() (*AgentsController, error) {
 
 	mcpServers := map[string]*mcp.MCPServer{}
 	for name, serverConfig := range conf.MCP.Servers {
-		server, err := mcp.NewMCPServer(ctx, name, serverConfig.Transport, serverConfig.Url, serverConfig.Command, serverConfig.Environments, serverConfig.Args...)
+		options := []func(*mcp.MCPServer){}
+
+		options = append(options, mcp.WithLazy(serverConfig.Lazy))
+
+		if len(serverConfig.Headers) > 0 {
+			options = append(options, mcp.WithHeaders(serverConfig.Headers))
+		}
+
+		if oauth := serverConfig.OAuth; oauth != nil {
+			options = append(options, mcp.WithOAuth(mcp.OAuth{
+				ClientID:     oauth.ClientID,
+				ClientSecret: oauth.ClientSecret,
+				Scopes:       oauth.Scopes,
+				RedirectURI:  oauth.RedirectURI,
+				MetadataURL:  oauth.MetadataURL,
+				TokenFile:    oauth.TokenFile,
+			}))
+		}
+
+		options = append(options, mcp.WithSandbox(mcp.Sandbox{
+			Dir:          serverConfig.Sandbox.Cwd,
+			Env:          serverConfig.Sandbox.EnvMode,
+			EnvAllowlist: serverConfig.Sandbox.EnvAllowlist,
+			CPUTime:      serverConfig.Sandbox.CPUTime,
+			MemoryMB:     serverConfig.Sandbox.MemoryMB,
+			OpenFiles:    serverConfig.Sandbox.OpenFiles,
+			Namespaces:   serverConfig.Sandbox.Namespaces,
+		}))
+
+		if serverConfig.StartupTimeout > 0 {
+			options = append(options, mcp.WithStartupTimeout(serverConfig.StartupTimeout))
+		}
+
+		if serverConfig.ToolTimeout > 0 {
+			options = append(options, mcp.WithToolTimeout(serverConfig.ToolTimeout))
+		}
+
+		if len(serverConfig.ToolTimeouts) > 0 {
+			timeouts := map[string]time.Duration{}
+
+			for _, tool := range serverConfig.ToolTimeouts {
+				timeouts[tool.Name] = tool.Timeout
+			}
+
+			options = append(options, mcp.WithToolTimeouts(timeouts))
+		}
+
+		if serverConfig.PingInterval > 0 {
+			options = append(options, mcp.WithPingInterval(serverConfig.PingInterval))
+		}
+
+		if serverConfig.PingTimeout > 0 {
+			options = append(options, mcp.WithPingTimeout(serverConfig.PingTimeout))
+		}
+
+		if serverConfig.MaxBackoff > 0 {
+			options = append(options, mcp.WithMaxBackoff(serverConfig.MaxBackoff))
+		}
+
+		server, err := mcp.NewMCPServer(ctx, name, serverConfig.Transport, serverConfig.Url, serverConfig.Command, serverConfig.Environments, serverConfig.Args, options...)
 
 		if err != nil {
 			return nil, fmt.Errorf("error load mcp server %s, %w", name, err)
@@ -158,20 +305,63 @@ func (controller *AgentsController) GetAgent(name string) (agents.Agent, error)
 		return nil, fmt.Errorf("agent %s not found", name)
 	}
 
+	if err, ok := controller.disabled[name]; ok {
+		return nil, fmt.Errorf("agent %s disabled, %w", name, err)
+	}
+
 	return agent, nil
 }
 
+// Close closes the connections to the mcp servers, terminating the processes
+// of stdio servers.
+func (controller *AgentsController) Close() error {
+	errs := []error{}
+
+	for name, server := range controller.MCPServers {
+		if err := server.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error close mcp server %s, %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func (controller *AgentsController) Run(agentName string) error {
 
 	slog.Info("start controller")
 
+	// Stop mcp servers and their processes when the agents stop
+	defer controller.Close()
+
 	slog.Info("load mcp servers")
 
-	// Start all mcp servers
-	for name, server := range controller.MCPServers {
-		err := server.Start()
-		if err != nil {
-			return fmt.Errorf("error starting mcp server %s, %w", name, err)
+	failedServers := controller.StartMCPServers()
+
+	// Agents depending on failed servers are disabled, the rest can run
+	disabled := map[string]error{}
+
+	for _, agent := range controller.Agents {
+		for _, name := range agent.GetServers() {
+			if err, ok := failedServers[name]; ok {
+				disabled[agent.GetName()] = err
+				slog.Error(fmt.Sprintf("agent %s disabled, mcp server %s failed", agent.GetName(), name), "error", err)
+			}
+		}
+	}
+
+	controller.disableCallers(disabled)
+	controller.disabled = disabled
+
+	if err, ok := disabled[agentName]; ok {
+		return fmt.Errorf("agent %s disabled, %w", agentName, err)
+	}
+
+	// Disabled agents are never initialized, so they can not be called
+	enabled := map[string]agents.Agent{}
+
+	for name, agent := range controller.Agents {
+		if _, ok := disabled[name]; !ok {
+			enabled[name] = agent
 		}
 	}
 
@@ -180,18 +370,26 @@ func (controller *AgentsController) Run(agentName string) error {
 	// Start all Agents
 	for _, agent := range controller.Agents {
 
+		if _, ok := disabled[agent.GetName()]; ok {
+			continue
+		}
+
 		slog.Debug(fmt.Sprintf("Initialize: %s: %T", agent.GetName(), agent))
 
 		// Check agent type and init the specific need of each one
 		switch a := agent.(type) {
 
 		case *chain.ChainAgent:
-			a.AttachAgents(controller.Agents)
+			a.AttachAgents(enabled)
 
 		case *base.BaseAgent:
 			// Check
 			agent.AttachMCPServers(controller.MCPServers)
 
+			if err := a.AttachAgents(enabled); err != nil {
+				return err
+			}
+
 			if agent.GetModel() != "" {
 
 				newLLM, err := llm.NewLLM(controller.ctx, agent.GetModel(), agent.GetInstructions(), agent.GetRequestParams(), controller.Config)
@@ -202,6 +400,15 @@ func (controller *AgentsController) Run(agentName string) error {
 				agent.AttachLLM(newLLM)
 			}
 
+			if a.SummaryModel != "" {
+				summaryLLM, err := llm.NewLLM(controller.ctx, a.SummaryModel, "", providers.NewRequestParams(), controller.Config)
+				if err != nil {
+					return err
+				}
+
+				a.AttachSummaryLLM(summaryLLM)
+			}
+
 		}
 
 		// Init agent custom funtion for each type
@@ -221,12 +428,29 @@ func (controller *AgentsController) Run(agentName string) error {
 		return err
 	}
 
+	if controller.Config.MCP.Serve.Transport != "" {
+		eg.Go(controller.ServeMCP)
+	}
+
+	if controller.Config.Server.Listen != "" {
+		agentsHost, err := controller.hostAgents(disabled)
+		if err != nil {
+			return err
+		}
+
+		eg.Go(agentsHost.Serve)
+	}
+
 	for _, agent := range controller.Agents {
 		slog.Debug(agent.GetName())
 		if agent.GetName() == defaultAgent.GetName() {
 			continue
 		}
 
+		if _, ok := disabled[agent.GetName()]; ok {
+			continue
+		}
+
 		eg.Go(func() error {
 			return agent.Start()
 		})
@@ -244,3 +468,108 @@ func (controller *AgentsController) Run(agentName string) error {
 
 	return nil
 }
+
+// disableCallers disables the agents calling disabled agents, as tools or as
+// chain steps, and the agents calling those, until no agent is left to
+// disable.
+func (controller *AgentsController) disableCallers(disabled map[string]error) {
+	for changed := true; changed; {
+		changed = false
+
+		for name, agent := range controller.Agents {
+			if _, off := disabled[name]; off {
+				continue
+			}
+
+			for _, callee := range callees(agent) {
+				if err, ok := disabled[callee]; ok {
+					disabled[name] = fmt.Errorf("agent %s disabled, %w", callee, err)
+					slog.Error(fmt.Sprintf("agent %s disabled, it calls the disabled agent %s", name, callee), "error", err)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// callees are the agents called by the agent, as agent tools or as steps of
+// a chain.
+func callees(agent agents.Agent) []string {
+	switch a := agent.(type) {
+	case *chain.ChainAgent:
+		return a.AgentsChain
+	case interface{ GetAgentTools() []string }:
+		return a.GetAgentTools()
+	}
+
+	return nil
+}
+
+func agentTLS(conf *config.TLS) *agents.TLS {
+	if conf == nil {
+		return nil
+	}
+
+	return &agents.TLS{
+		CertFile:       conf.CertFile,
+		KeyFile:        conf.KeyFile,
+		ClientCAFile:   conf.ClientCAFile,
+		CAFile:         conf.CAFile,
+		ClientCertFile: conf.ClientCertFile,
+		ClientKeyFile:  conf.ClientKeyFile,
+		ServerName:     conf.ServerName,
+	}
+}
+
+// hostAgents moves the agents to the shared server of the config, the rest
+// keep their own servers.
+func (controller *AgentsController) hostAgents(disabled map[string]error) (*host.Host, error) {
+	conf := controller.Config.Server
+
+	agentsHost := host.NewHost(conf.Listen,
+		host.WithTLS(agentTLS(conf.TLS)),
+		host.WithLogger(slog.Default().With(slog.String("type", "HOST"))),
+	)
+
+	hostedBy := func(name string) bool {
+		if len(conf.Agents) > 0 {
+			return slices.Contains(conf.Agents, name)
+		}
+
+		agent, ok := controller.Config.Agents[name]
+
+		// Agents with a url are reached there, they keep their own server
+		return !ok || (agent.Url == "" && agent.Listen == "" && agent.HTTPAddr == "" && !agent.Gateway)
+	}
+
+	for _, agent := range controller.Agents {
+		if _, ok := disabled[agent.GetName()]; ok || !hostedBy(agent.GetName()) {
+			continue
+		}
+
+		hostable, ok := agent.(interface {
+			HostOn(h *host.Host, service pb.A2AServiceServer) error
+		})
+		if !ok {
+			return nil, fmt.Errorf("error host agent %s, %T can not be hosted", agent.GetName(), agent)
+		}
+
+		service, ok := agent.(pb.A2AServiceServer)
+		if !ok {
+			return nil, fmt.Errorf("error host agent %s, %T has no a2a service", agent.GetName(), agent)
+		}
+
+		// The host serves gRPC only, the agent card over HTTP and the JSON-RPC
+		// and REST bindings of the agent are not served
+		if agentConfig, ok := controller.Config.Agents[agent.GetName()]; ok && (agentConfig.HTTPAddr != "" || agentConfig.Gateway) {
+			slog.Warn(fmt.Sprintf("agent %s is hosted, its http_addr and gateway are not served", agent.GetName()))
+		}
+
+		if err := hostable.HostOn(agentsHost, service); err != nil {
+			return nil, err
+		}
+	}
+
+	return agentsHost, nil
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/mcp"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+	mcp_server "github.com/mark3labs/mcp-go/server"
+)
+
+// NewMCPServer exposes the agents as tools of an MCP server, all of them or
+// the ones listed in mcp.serve.agents, except the disabled ones.
+func (controller *AgentsController) NewMCPServer() (*mcp_server.MCPServer, error) {
+
+	serve := controller.Config.MCP.Serve
+
+	server := mcp_server.NewMCPServer(
+		"go-agents",
+		agents.Version,
+		mcp_server.WithToolCapabilities(false),
+		mcp_server.WithRecovery(),
+	)
+
+	for _, name := range serve.Agents {
+		if _, ok := controller.Agents[name]; !ok {
+			return nil, fmt.Errorf("agent %s served by mcp not found", name)
+		}
+	}
+
+	for name, agent := range controller.Agents {
+		if len(serve.Agents) > 0 && !slices.Contains(serve.Agents, name) {
+			continue
+		}
+
+		if err, ok := controller.disabled[name]; ok {
+			slog.Warn(fmt.Sprintf("agent %s disabled, not served by mcp", name), "error", err)
+			continue
+		}
+
+		tool, err := agents.NewAgentTool(agent)
+		if err != nil {
+			return nil, fmt.Errorf("error serve agent %s by mcp, %w", name, err)
+		}
+
+		server.AddTool(tool.Definition(), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+			result, err := tool.Call(ctx, request.GetArguments())
+			if err != nil {
+				return mcp_tool.NewToolResultErrorFromErr(fmt.Sprintf("error call agent %s", name), err), nil
+			}
+
+			return result, nil
+		})
+	}
+
+	return server, nil
+}
+
+// ServeMCP serves the agents over the transport configured in mcp.serve and
+// blocks until the server stops.
+func (controller *AgentsController) ServeMCP() error {
+
+	serve := controller.Config.MCP.Serve
+
+	server, err := controller.NewMCPServer()
+	if err != nil {
+		return err
+	}
+
+	switch serve.Transport {
+	case mcp.TRANSPORT_STDIO:
+		slog.Info("serving agents by mcp on stdio")
+
+		if err := mcp_server.ServeStdio(server); err != nil {
+			return fmt.Errorf("error serve mcp on stdio, %w", err)
+		}
+
+	case mcp.TRANSPORT_HTTP:
+		httpServer := mcp_server.NewStreamableHTTPServer(server, mcp_server.WithEndpointPath(serve.Endpoint))
+
+		ctx, stop := signal.NotifyContext(controller.ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		go func() {
+			<-ctx.Done()
+			httpServer.Shutdown(context.WithoutCancel(ctx))
+		}()
+
+		slog.Info(fmt.Sprintf("serving agents by mcp at %s%s", serve.Address, serve.Endpoint))
+
+		if err := httpServer.Start(serve.Address); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("error serve mcp on %s, %w", serve.Address, err)
+		}
+
+	default:
+		return fmt.Errorf("transport %s not supported to serve mcp", serve.Transport)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package controller_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jlrosende/go-agents/agents"
+	"github.com/jlrosende/go-agents/agents/workflows/base"
+	"github.com/jlrosende/go-agents/config"
+	"github.com/jlrosende/go-agents/controller"
+	"github.com/jlrosende/go-agents/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcp_client "github.com/mark3labs/mcp-go/client"
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// echoLLM answers every message with the message.
+type echoLLM struct{}
+
+func (echoLLM) Initialize() error                      { return nil }
+func (echoLLM) GetModel(name string) (any, error)      { return name, nil }
+func (echoLLM) ListModels() (any, error)               { return nil, nil }
+func (echoLLM) AttachTools(toolset []tools.Tool) error { return nil }
+func (echoLLM) SetInstructions(instructions string)    {}
+func (echoLLM) Generate(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+	return []mcp_tool.Content{mcp_tool.NewTextContent("echo " + message)}, nil
+}
+func (e echoLLM) Structured(ctx context.Context, message string, responseStruct any) ([]mcp_tool.Content, error) {
+	return e.Generate(ctx, message)
+}
+func (echoLLM) CreateMessage(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error) {
+	return nil, nil
+}
+
+func newController(t *testing.T, serve config.MCPServe) *controller.AgentsController {
+	t.Helper()
+
+	swarm := &controller.AgentsController{
+		Config: &config.AgentsConfig{MCP: config.MCP{Serve: serve}},
+		Agents: map[string]agents.Agent{},
+	}
+
+	for _, name := range []string{"echo", "other"} {
+		agent := &base.BaseAgent{Name: name, Description: name + " agent", Model: "fake"}
+		agent.AttachLLM(echoLLM{})
+		require.NoError(t, agent.Initialize())
+
+		swarm.AddAgent(agent)
+	}
+
+	return swarm
+}
+
+func TestNewMCPServer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("call agents as tools", func(t *testing.T) {
+		server, err := newController(t, config.MCPServe{Agents: []string{"echo"}}).NewMCPServer()
+		require.NoError(t, err)
+
+		client, err := mcp_client.NewInProcessClient(server)
+		require.NoError(t, err)
+		defer client.Close()
+
+		require.NoError(t, client.Start(ctx))
+
+		_, err = client.Initialize(ctx, mcp_tool.InitializeRequest{})
+		require.NoError(t, err)
+
+		listed, err := client.ListTools(ctx, mcp_tool.ListToolsRequest{})
+		require.NoError(t, err)
+		require.Len(t, listed.Tools, 1, "only the served agents")
+		assert.Equal(t, "echo", listed.Tools[0].Name)
+
+		request := mcp_tool.CallToolRequest{}
+		request.Params.Name = "echo"
+		request.Params.Arguments = map[string]any{"message": "hello"}
+
+		result, err := client.CallTool(ctx, request)
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Equal(t, "echo hello", result.Content[0].(mcp_tool.TextContent).Text)
+
+		request.Params.Arguments = map[string]any{}
+
+		result, err = client.CallTool(ctx, request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "the message is required")
+	})
+
+	t.Run("unknown agents", func(t *testing.T) {
+		_, err := newController(t, config.MCPServe{Agents: []string{"missing"}}).NewMCPServer()
+		assert.ErrorContains(t, err, "missing")
+	})
+}
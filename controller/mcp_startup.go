@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// StartMCPServers starts concurrently the mcp servers used by the agents,
+// except the lazy ones, and returns the servers that failed. Servers no agent
+// uses are not started.
+func (controller *AgentsController) StartMCPServers() map[string]error {
+
+	referenced := map[string]struct{}{}
+
+	for _, agent := range controller.Agents {
+		for _, name := range agent.GetServers() {
+			referenced[name] = struct{}{}
+		}
+	}
+
+	mu := sync.Mutex{}
+	failed := map[string]error{}
+	wg := sync.WaitGroup{}
+
+	for name := range referenced {
+		server, err := controller.GetMCPServer(name)
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+
+		if server.Lazy {
+			slog.Debug(fmt.Sprintf("mcp server %s starts on first use", name))
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+
+			if err := server.Start(); err != nil {
+				mu.Lock()
+				failed[name] = err
+				mu.Unlock()
+
+				return
+			}
+
+			slog.Info(fmt.Sprintf("mcp server %s started in %s", name, time.Since(start)))
+		}()
+	}
+
+	wg.Wait()
+
+	for name := range controller.MCPServers {
+		if _, ok := referenced[name]; !ok {
+			slog.Debug(fmt.Sprintf("mcp server %s not used by any agent, skipped", name))
+		}
+	}
+
+	for name, err := range failed {
+		slog.Error(fmt.Sprintf("mcp server %s failed to start", name), "error", err)
+	}
+
+	return failed
+}
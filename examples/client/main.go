@@ -61,6 +61,11 @@ func main() {
 		log.Fatalf("could send message: %v", err)
 	}
 
+	if task := r.GetTask(); task != nil {
+		log.Printf("Task %s %s: %s", task.GetId(), task.GetStatus().GetState(), task.GetStatus().GetUpdate())
+		return
+	}
+
 	log.Printf("MSG Response: %s", r.GetMsg())
 
 }
@@ -0,0 +1,78 @@
+// Package main implements a webhook receiving the push notifications of A2A
+// tasks, to check them locally.
+//
+//	go run ./examples/webhook -addr :9000 -token my-token -secret my-secret
+//
+// and send messages with the push notification config
+//
+//	{"url": "http://localhost:9000/", "token": "my-token",
+//	 "authentication": {"schemes": ["HMAC-SHA256"], "credentials": "my-secret"}}
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jlrosende/go-agents/agents/tasks"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jlrosende/go-agents/proto/a2a/v1"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	token := flag.String("token", "", "expected notification token, any when empty")
+	bearer := flag.String("bearer", "", "expected bearer token, none when empty")
+	secret := flag.String("secret", "", "key of the HMAC-SHA256 signature, unsigned when empty")
+	flag.Parse()
+
+	http.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if *token != "" && r.Header.Get(tasks.HEADER_TOKEN) != *token {
+			log.Printf("rejected notification, invalid token")
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		if *bearer != "" && r.Header.Get("Authorization") != "Bearer "+*bearer {
+			log.Printf("rejected notification, invalid bearer token")
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		if *secret != "" {
+			if err := tasks.VerifySignature(*secret, r.Header, body, 5*time.Minute); err != nil {
+				log.Printf("rejected notification, %s", err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		event := &pb.StreamResponse{}
+		if err := protojson.Unmarshal(body, event); err != nil {
+			log.Printf("rejected notification, %s", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if update := event.GetStatusUpdate(); update != nil {
+			log.Printf("Task %s %s: %s", update.GetTaskId(), update.GetStatus().GetState(), update.GetStatus().GetUpdate())
+		} else {
+			log.Printf("Event: %s", body)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("webhook listening on %s", *addr)
+
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -1,7 +1,10 @@
 package providers
 
 import (
-	"github.com/jlrosende/go-agents/mcp"
+	"context"
+	"slices"
+
+	"github.com/jlrosende/go-agents/tools"
 	mcp_tool "github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -10,7 +13,41 @@ type LLM interface {
 	Initialize() error
 	GetModel(name string) (any, error)
 	ListModels() (any, error)
-	AttachTools(mcpServers map[string]*mcp.MCPServer, includeTools, excludeTools []string) error
-	Generate(message string) ([]mcp_tool.Content, error)
-	Structured(message string, reponseStruct any) ([]mcp_tool.Content, error)
+	AttachTools(toolset []tools.Tool) error
+	SetInstructions(instructions string)
+	Generate(ctx context.Context, message string) ([]mcp_tool.Content, error)
+	Structured(ctx context.Context, message string, reponseStruct any) ([]mcp_tool.Content, error)
+	// CreateMessage answers the sampling requests of MCP servers
+	CreateMessage(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error)
+}
+
+// Modes is implemented by the LLMs declaring the MIME types of the messages
+// they read and of the answers they write.
+type Modes interface {
+	InputModes() []string
+	OutputModes() []string
+}
+
+// DEFAULT_MODES are the modes of the LLMs not declaring them, text, and JSON
+// sent as text.
+var DEFAULT_MODES = []string{"text/plain", "application/json"}
+
+// InputModes returns the input modes of the LLM, DEFAULT_MODES when it does
+// not declare them.
+func InputModes(llm LLM) []string {
+	if modes, ok := llm.(Modes); ok && len(modes.InputModes()) > 0 {
+		return modes.InputModes()
+	}
+
+	return slices.Clone(DEFAULT_MODES)
+}
+
+// OutputModes returns the output modes of the LLM, DEFAULT_MODES when it does
+// not declare them.
+func OutputModes(llm LLM) []string {
+	if modes, ok := llm.(Modes); ok && len(modes.OutputModes()) > 0 {
+		return modes.OutputModes()
+	}
+
+	return slices.Clone(DEFAULT_MODES)
 }
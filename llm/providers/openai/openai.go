@@ -7,12 +7,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"slices"
+	"strings"
+	"sync"
 
 	"github.com/jlrosende/go-agents/config"
 	"github.com/jlrosende/go-agents/llm/providers"
-	"github.com/jlrosende/go-agents/mcp"
 	"github.com/jlrosende/go-agents/memory"
+	"github.com/jlrosende/go-agents/tools"
 	mcp_tool "github.com/mark3labs/mcp-go/mcp"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -26,8 +27,6 @@ type OpenAILLM struct {
 
 	Provider string
 
-	Tools []openai.ChatCompletionToolParam
-
 	ModelName string
 	Model     *openai.Model
 
@@ -39,13 +38,35 @@ type OpenAILLM struct {
 
 	Memory *memory.Memory
 
-	ToolsServers map[string]*mcp.MCPServer
+	// Tools can be replaced while requests run, when a server changes them
+	toolbox *toolbox
 
 	RequestParams *providers.RequestParams
 }
 
 var _ providers.LLM = (*OpenAILLM)(nil)
 
+type toolbox struct {
+	mu      sync.RWMutex
+	params  []openai.ChatCompletionToolParam
+	toolset map[string]tools.Tool
+}
+
+func (t *toolbox) get(name string) (tools.Tool, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tool, ok := t.toolset[name]
+	return tool, ok
+}
+
+func (t *toolbox) definitions() []openai.ChatCompletionToolParam {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.params
+}
+
 func NewOpenAILLM(ctx context.Context, modelName, effort, instructions string, req *providers.RequestParams, config *config.AgentsConfig) (*OpenAILLM, error) {
 
 	cli := openai.NewClient(
@@ -67,7 +88,7 @@ func NewOpenAILLM(ctx context.Context, modelName, effort, instructions string, r
 func (llm *OpenAILLM) Initialize() error {
 
 	llm.Memory = new(memory.Memory)
-	llm.ToolsServers = map[string]*mcp.MCPServer{}
+	llm.toolbox = &toolbox{toolset: map[string]tools.Tool{}}
 
 	model, err := llm.GetModel(llm.ModelName)
 
@@ -85,35 +106,23 @@ func (llm *OpenAILLM) Initialize() error {
 	return nil
 }
 
-func (llm *OpenAILLM) AttachTools(mcpServers map[string]*mcp.MCPServer, includeTools, excludeTools []string) error {
+// AttachTools sends the tools with every request. The API rejects repeated
+// function names, so only the first tool of a name is attached.
+func (llm *OpenAILLM) AttachTools(toolset []tools.Tool) error {
 
-	attach := []mcp_tool.Tool{}
+	toolset, collisions := tools.Deduplicate(toolset)
 
-	for _, server := range mcpServers {
-		tools, err := server.ListTools()
-		if err != nil {
-			return err
-		}
-		for _, tool := range tools {
-			include := slices.Contains(includeTools, tool.Name)
-			if !include && len(includeTools) > 0 {
-				continue
-			}
-
-			exclude := slices.Contains(excludeTools, tool.Name)
-			if exclude && len(excludeTools) > 0 {
-				continue
-			}
-
-			llm.ToolsServers[tool.Name] = server
-
-			attach = append(attach, tool)
-		}
+	for name, paths := range collisions {
+		llm.Logger.Warn(fmt.Sprintf("tool name %s used by %s, using %s", name, strings.Join(paths, ", "), paths[0]))
 	}
 
+	index := tools.Index(toolset)
+
 	attached := []openai.ChatCompletionToolParam{}
 
-	for _, tool := range attach {
+	for _, t := range toolset {
+		tool := t.Definition()
+
 		attached = append(attached, openai.ChatCompletionToolParam{
 			Function: openai.FunctionDefinitionParam{
 				Name:        tool.Name,
@@ -128,11 +137,19 @@ func (llm *OpenAILLM) AttachTools(mcpServers map[string]*mcp.MCPServer, includeT
 		})
 	}
 
-	llm.Tools = attached
+	llm.toolbox.mu.Lock()
+	defer llm.toolbox.mu.Unlock()
+
+	llm.toolbox.toolset = index
+	llm.toolbox.params = attached
 
 	return nil
 }
 
+func (llm *OpenAILLM) SetInstructions(instructions string) {
+	llm.Instructions = instructions
+}
+
 func (llm OpenAILLM) GetModel(name string) (any, error) {
 	model, err := llm.Client.Models.Get(
 		llm.Ctx,
@@ -163,22 +180,67 @@ func (llm OpenAILLM) ListModels() (any, error) {
 	return models, nil
 }
 
-func (llm OpenAILLM) Generate(message string) ([]mcp_tool.Content, error) {
+func (llm OpenAILLM) Generate(ctx context.Context, message string) ([]mcp_tool.Content, error) {
+
+	query := llm.newQuery(ctx, message)
+
+	return llm.run(ctx, &query)
+}
+
+func (llm OpenAILLM) Structured(ctx context.Context, message string, reponseStruct any) ([]mcp_tool.Content, error) {
+
+	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
+		Name:        "structured_response",
+		Description: openai.String("A well defined json reponse"),
+		Schema:      reponseStruct,
+		Strict:      openai.Bool(true),
+	}
+
+	query := llm.newQuery(ctx, message)
+
+	query.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: schemaParam,
+		},
+	}
+
+	return llm.run(ctx, &query)
+}
+
+// history returns the memory of the conversation of the call, the memory of
+// the model when the call has none.
+func (llm OpenAILLM) history(ctx context.Context) *memory.Memory {
+	if conversation, ok := memory.FromContext(ctx); ok {
+		return conversation
+	}
+
+	return llm.Memory
+}
+
+// useHistory reports whether the call continues a conversation, always for
+// the conversations of A2A contexts, and with UseHistory for the rest.
+func (llm OpenAILLM) useHistory(ctx context.Context) bool {
+	_, ok := memory.FromContext(ctx)
+
+	return ok || llm.RequestParams.UseHistory
+}
+
+func (llm OpenAILLM) newQuery(ctx context.Context, message string) openai.ChatCompletionNewParams {
 
 	messages := []openai.ChatCompletionMessageParamUnion{}
 
 	messages = append(messages, openai.SystemMessage(llm.Instructions))
 
-	if llm.RequestParams.UseHistory {
-		for _, message := range llm.Memory.Get() {
+	if llm.useHistory(ctx) {
+		for _, message := range llm.history(ctx).Get() {
 			messages = append(messages, message.(openai.ChatCompletionMessageParamUnion))
 		}
 	}
 
 	messages = append(messages, openai.UserMessage(message))
 
-	if llm.RequestParams.UseHistory {
-		llm.Memory.Append(openai.UserMessage(message))
+	if llm.useHistory(ctx) {
+		llm.history(ctx).Append(openai.UserMessage(message))
 	}
 
 	query := openai.ChatCompletionNewParams{
@@ -190,14 +252,6 @@ func (llm OpenAILLM) Generate(message string) ([]mcp_tool.Content, error) {
 		query.Temperature = param.NewOpt(llm.RequestParams.Temperature)
 	}
 
-	if len(llm.Tools) > 0 {
-		query.Tools = llm.Tools
-
-		if llm.RequestParams.ParallelToolCalls {
-			query.ParallelToolCalls = param.NewOpt(llm.RequestParams.ParallelToolCalls)
-		}
-	}
-
 	if llm.RequestParams.Reasoning {
 		query.MaxCompletionTokens = param.NewOpt(llm.RequestParams.MaxTokens)
 		if llm.Effort != "" {
@@ -209,12 +263,40 @@ func (llm OpenAILLM) Generate(message string) ([]mcp_tool.Content, error) {
 		query.MaxTokens = param.NewOpt(llm.RequestParams.MaxTokens)
 	}
 
+	return query
+}
+
+// setTools sends the current tools with the query, they change while the
+// query runs when a server changes its tools or a lazy server starts.
+func (llm OpenAILLM) setTools(query *openai.ChatCompletionNewParams) {
+	definitions := llm.toolbox.definitions()
+
+	if len(definitions) == 0 {
+		query.Tools = nil
+		query.ParallelToolCalls = param.Opt[bool]{}
+		return
+	}
+
+	query.Tools = definitions
+
+	if llm.RequestParams.ParallelToolCalls {
+		query.ParallelToolCalls = param.NewOpt(llm.RequestParams.ParallelToolCalls)
+	}
+}
+
+// run sends the query and resolves tool calls until the model stops or the
+// iteration budget is exhausted.
+func (llm OpenAILLM) run(ctx context.Context, query *openai.ChatCompletionNewParams) ([]mcp_tool.Content, error) {
+
 	response := []mcp_tool.Content{}
 
-stop_iter:
+	guard := providers.NewToolCallGuard(llm.RequestParams.MaxRepeatedCalls)
+
 	for range llm.RequestParams.MaxIterations {
 
-		completion, err := llm.Client.Chat.Completions.New(llm.Ctx, query)
+		llm.setTools(query)
+
+		completion, err := llm.Client.Chat.Completions.New(ctx, *query)
 
 		if err != nil {
 			var apierr *openai.Error
@@ -226,182 +308,146 @@ stop_iter:
 			return nil, fmt.Errorf("error sending completion %w", err)
 		}
 
-		llm.Logger.Info(fmt.Sprintf("%s", completion.Choices[0].Message.Content))
-
-		query.Messages = append(query.Messages, completion.Choices[0].Message.ToParam())
-
-		response = append(response, mcp_tool.NewTextContent(completion.Choices[0].Message.Content))
-
-		for _, toolCall := range completion.Choices[0].Message.ToolCalls {
-
-			if server, ok := llm.ToolsServers[toolCall.Function.Name]; ok {
-
-				var args map[string]interface{}
-
-				err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
+		choice := completion.Choices[0]
 
-				if err != nil {
-					return nil, fmt.Errorf("error unmarshal args %w", err)
-				}
+		llm.Logger.Info(choice.Message.Content)
 
-				llm.Logger.Info(fmt.Sprintf("Call tool [%s] %+v", toolCall.Function.Name, args))
+		query.Messages = append(query.Messages, choice.Message.ToParam())
 
-				toolRes, err := server.CallTool(toolCall.Function.Name, args)
+		if llm.useHistory(ctx) {
+			llm.history(ctx).Append(choice.Message.ToParam())
+		}
 
-				if err != nil {
-					return nil, fmt.Errorf("error call tool %s, %w", toolCall.Function.Name, err)
-				}
+		if choice.Message.Content != "" {
+			response = append(response, mcp_tool.NewTextContent(choice.Message.Content))
+		}
 
-				for _, c := range toolRes.Content {
+		results := llm.callTools(ctx, choice.Message.ToolCalls, guard)
 
-					jsonBytes, _ := json.Marshal(c)
-					content := string(jsonBytes)
+		for i, toolCall := range choice.Message.ToolCalls {
 
-					if llm.RequestParams.UseHistory {
-						llm.Memory.Append(openai.ToolMessage(content, toolCall.ID))
-					}
+			toolMessage := llm.toolResultMessage(ctx, results[i], toolCall.ID)
 
-					query.Messages = append(query.Messages, openai.ToolMessage(content, toolCall.ID))
+			if llm.useHistory(ctx) {
+				llm.history(ctx).Append(toolMessage)
+			}
 
-					response = append(response, c)
-				}
+			query.Messages = append(query.Messages, toolMessage)
 
+			if !results[i].IsError {
+				response = append(response, results[i].Content...)
 			}
 		}
 
-		switch completion.Choices[0].FinishReason {
+		switch choice.FinishReason {
 		case "stop", "length", "content_filter":
-			break stop_iter
+			return response, nil
 		}
-
 	}
 
-	return response, nil
+	return response, fmt.Errorf("model %s did not finish after %d iterations, %w", llm.ModelName, llm.RequestParams.MaxIterations, providers.ErrMaxIterations)
 }
 
-func (llm OpenAILLM) Structured(message string, reponseStruct any) ([]mcp_tool.Content, error) {
-
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:        "structured_response",
-		Description: openai.String("A well defined json reponse"),
-		Schema:      reponseStruct,
-		Strict:      openai.Bool(true),
-	}
-
-	messages := []openai.ChatCompletionMessageParamUnion{}
+// callTools executes the tool calls of one completion, concurrently when
+// parallel tool calls are enabled. Results keep the order of the calls.
+func (llm OpenAILLM) callTools(ctx context.Context, toolCalls []openai.ChatCompletionMessageToolCall, guard *providers.ToolCallGuard) []*mcp_tool.CallToolResult {
 
-	messages = append(messages, openai.SystemMessage(llm.Instructions))
+	results := make([]*mcp_tool.CallToolResult, len(toolCalls))
 
-	if llm.RequestParams.UseHistory {
-		for _, message := range llm.Memory.Get() {
-			messages = append(messages, message.(openai.ChatCompletionMessageParamUnion))
+	if !llm.RequestParams.ParallelToolCalls || len(toolCalls) < 2 {
+		for i, toolCall := range toolCalls {
+			results[i] = llm.callTool(ctx, toolCall, guard)
 		}
-	}
-
-	messages = append(messages, openai.UserMessage(message))
 
-	if llm.RequestParams.UseHistory {
-		llm.Memory.Append(openai.UserMessage(message))
+		return results
 	}
 
-	query := openai.ChatCompletionNewParams{
-		Messages: messages,
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
-		},
-		Model: llm.Model.ID,
-	}
-
-	if llm.RequestParams.Temperature > 0 {
-		query.Temperature = param.NewOpt(llm.RequestParams.Temperature)
-	}
+	wg := sync.WaitGroup{}
 
-	if len(llm.Tools) > 0 {
-		query.Tools = llm.Tools
+	for i, toolCall := range toolCalls {
+		wg.Add(1)
 
-		if llm.RequestParams.ParallelToolCalls {
-			query.ParallelToolCalls = param.NewOpt(llm.RequestParams.ParallelToolCalls)
-		}
+		go func() {
+			defer wg.Done()
+			results[i] = llm.callTool(ctx, toolCall, guard)
+		}()
 	}
 
-	if llm.RequestParams.Reasoning {
-		query.MaxCompletionTokens = param.NewOpt(llm.RequestParams.MaxTokens)
-		if llm.Effort != "" {
-			query.ReasoningEffort = shared.ReasoningEffort(llm.Effort)
-		} else {
-			query.ReasoningEffort = shared.ReasoningEffort(llm.RequestParams.ReasoningEffort)
-		}
-	} else {
-		query.MaxTokens = param.NewOpt(llm.RequestParams.MaxTokens)
-	}
-
-	response := []mcp_tool.Content{}
-
-stop_iter_structured:
-	for range llm.RequestParams.MaxIterations {
-
-		completion, err := llm.Client.Chat.Completions.New(llm.Ctx, query)
-
-		if err != nil {
-			var apierr *openai.Error
-			if errors.As(err, &apierr) {
-				fmt.Fprintln(os.Stderr, string(apierr.DumpRequest(true)))
-				fmt.Fprintln(os.Stderr, string(apierr.DumpResponse(true)))
-			}
-
-			return nil, fmt.Errorf("error sending completion %w", err)
-		}
+	wg.Wait()
 
-		llm.Logger.Info(fmt.Sprintf("%s", completion.Choices[0].Message.Content))
+	return results
+}
 
-		query.Messages = append(query.Messages, completion.Choices[0].Message.ToParam())
+// callTool never fails, errors are returned to the model as error results so
+// it can correct the call.
+func (llm OpenAILLM) callTool(ctx context.Context, toolCall openai.ChatCompletionMessageToolCall, guard *providers.ToolCallGuard) *mcp_tool.CallToolResult {
 
-		response = append(response, mcp_tool.NewTextContent(completion.Choices[0].Message.Content))
+	name := toolCall.Function.Name
 
-		for _, toolCall := range completion.Choices[0].Message.ToolCalls {
+	tool, ok := llm.toolbox.get(name)
+	if !ok {
+		llm.Logger.Warn(fmt.Sprintf("model called unknown tool [%s]", name))
+		return mcp_tool.NewToolResultErrorf("unknown tool %s, use one of the available tools", name)
+	}
 
-			if server, ok := llm.ToolsServers[toolCall.Function.Name]; ok {
+	var args map[string]any
 
-				var args map[string]interface{}
+	if toolCall.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+			return mcp_tool.NewToolResultErrorFromErr("invalid JSON arguments", err)
+		}
+	}
 
-				err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
+	if !guard.Allow(name, args) {
+		llm.Logger.Warn(fmt.Sprintf("repeated tool call [%s] %+v", name, args))
+		return mcp_tool.NewToolResultErrorf("tool %s was already called %d times with the same arguments, use the previous results or change the arguments", name, llm.RequestParams.MaxRepeatedCalls)
+	}
 
-				if err != nil {
-					return nil, fmt.Errorf("error unmarshal args %w", err)
-				}
+	decision, err := tools.Approve(ctx, tools.ApprovalRequest{
+		ToolCallID:  toolCall.ID,
+		Tool:        name,
+		Path:        tools.Path(tool),
+		Arguments:   args,
+		Destructive: tools.Destructive(tool.Definition()),
+	})
 
-				llm.Logger.Info(fmt.Sprintf("Call tool [%s] %+v", toolCall.Function.Name, args))
+	if err != nil {
+		llm.Logger.Error(fmt.Sprintf("error approve tool [%s]", name), "error", err)
+		return mcp_tool.NewToolResultErrorFromErr(fmt.Sprintf("error approve tool %s", name), err)
+	}
 
-				toolRes, err := server.CallTool(toolCall.Function.Name, args)
+	if !decision.Approved {
+		llm.Logger.Info(fmt.Sprintf("tool call [%s] denied, %s", name, decision.Reason))
+		return mcp_tool.NewToolResultErrorf("tool call %s denied, %s", name, decision.Reason)
+	}
 
-				if err != nil {
-					return nil, fmt.Errorf("error call tool %s, %w", toolCall.Function.Name, err)
-				}
+	if decision.Arguments != nil {
+		args = decision.Arguments
+	}
 
-				for _, c := range toolRes.Content {
+	llm.Logger.Info(fmt.Sprintf("Call tool [%s] %+v", name, args))
 
-					jsonBytes, _ := json.Marshal(c)
-					content := string(jsonBytes)
+	result, err := tool.Call(ctx, args)
 
-					if llm.RequestParams.UseHistory {
-						llm.Memory.Append(openai.ToolMessage(content, toolCall.ID))
-					}
+	if err != nil {
+		llm.Logger.Error(fmt.Sprintf("error call tool [%s]", name), "error", err)
+		return mcp_tool.NewToolResultErrorFromErr(fmt.Sprintf("error call tool %s", name), err)
+	}
 
-					query.Messages = append(query.Messages, openai.ToolMessage(content, toolCall.ID))
+	return result
+}
 
-					response = append(response, c)
-				}
-			}
-		}
+// toolResultMessage answers a tool call with exactly one tool message, as the
+// API rejects conversations with unanswered tool calls. The result is sent as
+// text, shrunk to the size limit of the request.
+func (llm OpenAILLM) toolResultMessage(ctx context.Context, result *mcp_tool.CallToolResult, toolCallID string) openai.ChatCompletionMessageParamUnion {
 
-		switch completion.Choices[0].FinishReason {
-		case "stop", "length", "content_filter":
-			break stop_iter_structured
-		}
+	text := tools.ResultText(result)
 
+	if limited := llm.RequestParams.ToolResultLimit().Apply(ctx, text); len(limited) != len(text) {
+		llm.Logger.Debug(fmt.Sprintf("tool result shrunk from %d to %d characters", len(text), len(limited)))
+		text = limited
 	}
 
-	return nil, nil
+	return openai.ToolMessage(text, toolCallID)
 }
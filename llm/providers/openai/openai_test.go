@@ -0,0 +1,273 @@
+package openai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/config"
+	"github.com/jlrosende/go-agents/llm/providers"
+	"github.com/jlrosende/go-agents/llm/providers/openai"
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/memory"
+	"github.com/jlrosende/go-agents/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type toolCall struct {
+	Id   string
+	Name string
+	Args string
+}
+
+// completion is one scripted answer of the model, calling tools or stopping
+// with content.
+type completion struct {
+	Content   string
+	ToolCalls []toolCall
+}
+
+type chatMessage struct {
+	Role       string `json:"role"`
+	Content    any    `json:"content"`
+	ToolCallId string `json:"tool_call_id"`
+}
+
+// fakeAPI answers the chat completions with the scripted completions in
+// order, recording the messages of every request.
+type fakeAPI struct {
+	mu          sync.Mutex
+	completions []completion
+	requests    [][]chatMessage
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if strings.HasPrefix(r.URL.Path, "/models/") {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":       strings.TrimPrefix(r.URL.Path, "/models/"),
+			"object":   "model",
+			"created":  0,
+			"owned_by": "test",
+		})
+		return
+	}
+
+	var body struct {
+		Messages []chatMessage `json:"messages"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, body.Messages)
+
+	next := completion{Content: "done"}
+	if len(f.completions) > 0 {
+		next, f.completions = f.completions[0], f.completions[1:]
+	}
+
+	message := map[string]any{"role": "assistant", "content": next.Content}
+	finishReason := "stop"
+
+	if len(next.ToolCalls) > 0 {
+		calls := []map[string]any{}
+		for _, call := range next.ToolCalls {
+			calls = append(calls, map[string]any{
+				"id":       call.Id,
+				"type":     "function",
+				"function": map[string]any{"name": call.Name, "arguments": call.Args},
+			})
+		}
+
+		message["tool_calls"] = calls
+		finishReason = "tool_calls"
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":      "completion",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   "test",
+		"choices": []map[string]any{
+			{"index": 0, "finish_reason": finishReason, "message": message},
+		},
+	})
+}
+
+// toolMessages returns the content of the tool messages of the request by
+// tool call id.
+func (f *fakeAPI) toolMessages(request int) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := map[string]string{}
+
+	for _, message := range f.requests[request] {
+		if message.Role == "tool" {
+			text, _ := message.Content.(string)
+			messages[message.ToolCallId] = text
+		}
+	}
+
+	return messages
+}
+
+func newLLM(t *testing.T, api *fakeAPI, toolset []tools.Tool, options ...func(*providers.RequestParams)) *openai.OpenAILLM {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	cfg := &config.AgentsConfig{OpenAI: config.OpenAI{ApiKey: "test", BaseUrl: server.URL + "/"}}
+
+	options = append([]func(*providers.RequestParams){providers.WithReasoning(false)}, options...)
+
+	llm, err := openai.NewOpenAILLM(context.Background(), "test", "", "instructions", providers.NewRequestParams(options...), cfg)
+	require.NoError(t, err)
+	require.NoError(t, llm.Initialize())
+	require.NoError(t, llm.AttachTools(toolset))
+
+	return llm
+}
+
+type empty struct{}
+
+func TestToolLoop(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("concurrent tool calls", func(t *testing.T) {
+		// Each tool waits for the other, so they only finish when they run
+		// at the same time
+		started := sync.WaitGroup{}
+		started.Add(2)
+
+		wait := func(name string) tools.Tool {
+			tool, err := tools.NewFunctionTool(name, name, func(ctx context.Context, _ empty) (string, error) {
+				started.Done()
+
+				done := make(chan struct{})
+				go func() {
+					started.Wait()
+					close(done)
+				}()
+
+				select {
+				case <-done:
+					return name + " result", nil
+				case <-time.After(5 * time.Second):
+					return "", errors.New("tools did not run concurrently")
+				}
+			})
+			require.NoError(t, err)
+			return tool
+		}
+
+		api := &fakeAPI{completions: []completion{
+			{ToolCalls: []toolCall{{"call_a", "tool_a", "{}"}, {"call_b", "tool_b", "{}"}}},
+		}}
+
+		llm := newLLM(t, api, []tools.Tool{wait("tool_a"), wait("tool_b")})
+
+		response, err := llm.Generate(ctx, "call the tools")
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"call_a": "tool_a result", "call_b": "tool_b result"}, api.toolMessages(1))
+		assert.Contains(t, mcp.Result(response).AllText(), "done")
+	})
+
+	t.Run("unknown tools", func(t *testing.T) {
+		api := &fakeAPI{completions: []completion{
+			{ToolCalls: []toolCall{{"call_1", "missing", "{}"}}},
+		}}
+
+		llm := newLLM(t, api, nil)
+
+		_, err := llm.Generate(ctx, "call a missing tool")
+		require.NoError(t, err)
+
+		assert.Contains(t, api.toolMessages(1)["call_1"], "unknown tool missing")
+	})
+
+	t.Run("tool errors are sent to the model", func(t *testing.T) {
+		failing, err := tools.NewFunctionTool("failing", "fails", func(ctx context.Context, _ empty) (string, error) {
+			return "", errors.New("disk full")
+		})
+		require.NoError(t, err)
+
+		api := &fakeAPI{completions: []completion{
+			{ToolCalls: []toolCall{{"call_1", "failing", "{}"}}},
+		}}
+
+		llm := newLLM(t, api, []tools.Tool{failing})
+
+		response, err := llm.Generate(ctx, "call the failing tool")
+		require.NoError(t, err)
+
+		assert.Contains(t, api.toolMessages(1)["call_1"], "disk full")
+		assert.Contains(t, mcp.Result(response).AllText(), "done")
+	})
+
+	t.Run("max iterations keep the response", func(t *testing.T) {
+		echo, err := tools.NewFunctionTool("echo", "echo", func(ctx context.Context, _ empty) (string, error) {
+			return "echo", nil
+		})
+		require.NoError(t, err)
+
+		api := &fakeAPI{completions: []completion{
+			{Content: "first", ToolCalls: []toolCall{{"call_1", "echo", `{"n":1}`}}},
+			{Content: "second", ToolCalls: []toolCall{{"call_2", "echo", `{"n":2}`}}},
+		}}
+
+		llm := newLLM(t, api, []tools.Tool{echo}, providers.WithMaxIterations(2))
+
+		response, err := llm.Generate(ctx, "loop")
+		assert.ErrorIs(t, err, providers.ErrMaxIterations)
+		assert.Contains(t, mcp.Result(response).AllText(), "second")
+	})
+}
+
+func TestConversationHistory(t *testing.T) {
+	api := &fakeAPI{completions: []completion{{Content: "hello alice"}, {Content: "again"}, {Content: "alone"}}}
+
+	llm := newLLM(t, api, nil)
+
+	// Messages of one A2A context share its conversation without use_history
+	ctx := memory.WithMemory(context.Background(), new(memory.Memory))
+
+	_, err := llm.Generate(ctx, "I am alice")
+	require.NoError(t, err)
+
+	_, err = llm.Generate(ctx, "who am I?")
+	require.NoError(t, err)
+
+	contents := func(request int) []any {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+
+		values := []any{}
+		for _, message := range api.requests[request] {
+			values = append(values, message.Content)
+		}
+
+		return values
+	}
+
+	assert.Equal(t, []any{"instructions", "I am alice", "hello alice", "who am I?"}, contents(1))
+
+	_, err = llm.Generate(context.Background(), "who am I?")
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{"instructions", "who am I?"}, contents(2))
+}
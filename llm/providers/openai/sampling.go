@@ -0,0 +1,85 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jlrosende/go-agents/mcp"
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/param"
+)
+
+// CreateMessage answers the sampling requests of MCP servers. It is a single
+// completion without tools nor history, the system prompt of the server
+// replaces the instructions of the agent.
+func (llm OpenAILLM) CreateMessage(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error) {
+
+	messages := []openai.ChatCompletionMessageParamUnion{}
+
+	if request.SystemPrompt != "" {
+		messages = append(messages, openai.SystemMessage(request.SystemPrompt))
+	}
+
+	for _, message := range request.Messages {
+		content, ok := message.Content.(mcp_tool.Content)
+		if !ok {
+			return nil, fmt.Errorf("error sampling, unsupported content %T", message.Content)
+		}
+
+		ok, text := mcp.GetText(content)
+		if !ok {
+			return nil, fmt.Errorf("error sampling, model %s only supports text content", llm.ModelName)
+		}
+
+		switch message.Role {
+		case mcp_tool.RoleAssistant:
+			messages = append(messages, openai.AssistantMessage(text))
+		default:
+			messages = append(messages, openai.UserMessage(text))
+		}
+	}
+
+	query := openai.ChatCompletionNewParams{
+		Messages: messages,
+		Model:    llm.Model.ID,
+	}
+
+	if request.MaxTokens > 0 {
+		if llm.RequestParams.Reasoning {
+			query.MaxCompletionTokens = param.NewOpt(int64(request.MaxTokens))
+		} else {
+			query.MaxTokens = param.NewOpt(int64(request.MaxTokens))
+		}
+	}
+
+	if request.Temperature > 0 {
+		query.Temperature = param.NewOpt(request.Temperature)
+	}
+
+	if len(request.StopSequences) > 0 {
+		query.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: request.StopSequences}
+	}
+
+	completion, err := llm.Client.Chat.Completions.New(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error sampling completion %w", err)
+	}
+
+	choice := completion.Choices[0]
+
+	stopReason := "endTurn"
+
+	if choice.FinishReason == "length" {
+		stopReason = "maxTokens"
+	}
+
+	return &mcp_tool.CreateMessageResult{
+		SamplingMessage: mcp_tool.SamplingMessage{
+			Role:    mcp_tool.RoleAssistant,
+			Content: mcp_tool.NewTextContent(choice.Message.Content),
+		},
+		Model:      completion.Model,
+		StopReason: stopReason,
+	}, nil
+}
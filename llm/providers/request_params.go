@@ -1,5 +1,7 @@
 package providers
 
+import "github.com/jlrosende/go-agents/tools"
+
 type ReasoningEffort string
 
 const (
@@ -13,10 +15,16 @@ type RequestParams struct {
 	UseHistory        bool
 	ParallelToolCalls bool
 	MaxIterations     int
+	MaxRepeatedCalls  int
 	MaxTokens         int64
 	Temperature       float64
 	Reasoning         bool
 	ReasoningEffort   ReasoningEffort
+
+	// Tool results longer than MaxToolResultSize characters are shrunk with
+	// ToolResultTruncation
+	MaxToolResultSize    int
+	ToolResultTruncation tools.Truncation
 }
 
 func NewRequestParams(options ...func(*RequestParams)) *RequestParams {
@@ -24,10 +32,14 @@ func NewRequestParams(options ...func(*RequestParams)) *RequestParams {
 		UseHistory:        false,
 		ParallelToolCalls: true,
 		MaxIterations:     20,
+		MaxRepeatedCalls:  3,
 		MaxTokens:         8196,
 		Temperature:       0.7,
 		Reasoning:         true,
 		ReasoningEffort:   REASONING_EFFORT_MEDIUM,
+
+		MaxToolResultSize:    tools.DEFAULT_MAX_RESULT_SIZE,
+		ToolResultTruncation: tools.TRUNCATE_HEAD_TAIL,
 	}
 	for _, o := range options {
 		o(req)
@@ -53,6 +65,12 @@ func WithMaxIterations(iterations int) func(*RequestParams) {
 	}
 }
 
+func WithMaxRepeatedCalls(calls int) func(*RequestParams) {
+	return func(req *RequestParams) {
+		req.MaxRepeatedCalls = calls
+	}
+}
+
 func WithMaxTokens(tokens int64) func(*RequestParams) {
 	return func(req *RequestParams) {
 		req.MaxTokens = tokens
@@ -76,3 +94,23 @@ func WithReasoningEffort(reasoning ReasoningEffort) func(*RequestParams) {
 		req.ReasoningEffort = reasoning
 	}
 }
+
+func WithMaxToolResultSize(size int) func(*RequestParams) {
+	return func(req *RequestParams) {
+		req.MaxToolResultSize = size
+	}
+}
+
+func WithToolResultTruncation(truncation tools.Truncation) func(*RequestParams) {
+	return func(req *RequestParams) {
+		req.ToolResultTruncation = truncation
+	}
+}
+
+// ToolResultLimit returns the size limit of the tool results.
+func (req *RequestParams) ToolResultLimit() tools.ResultLimit {
+	return tools.ResultLimit{
+		MaxSize:    req.MaxToolResultSize,
+		Truncation: req.ToolResultTruncation,
+	}
+}
@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/jlrosende/go-agents/tools"
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+const summaryPrompt = `You shorten the results of tool calls made by another assistant.
+Keep every fact, identifier, number, path and error the assistant may need, drop repetition and boilerplate.
+Answer only with the shortened result, in less than %d characters.`
+
+// Summarizer summarizes the tool results over the size limit with the model,
+// usually a cheaper one than the model of the agent.
+func Summarizer(llm LLM) tools.Summarizer {
+	return func(ctx context.Context, text string, maxSize int) (string, error) {
+		result, err := llm.CreateMessage(ctx, mcp_tool.CreateMessageRequest{
+			CreateMessageParams: mcp_tool.CreateMessageParams{
+				SystemPrompt: fmt.Sprintf(summaryPrompt, maxSize),
+				Messages: []mcp_tool.SamplingMessage{
+					{
+						Role:    mcp_tool.RoleUser,
+						Content: mcp_tool.NewTextContent(text),
+					},
+				},
+				// A token is about four characters
+				MaxTokens: max(maxSize/4, 1),
+			},
+		})
+
+		if err != nil {
+			return "", fmt.Errorf("error summarize tool result, %w", err)
+		}
+
+		content, ok := result.Content.(mcp_tool.Content)
+		if !ok {
+			return "", fmt.Errorf("error summarize tool result, unsupported content %T", result.Content)
+		}
+
+		ok, summary := mcp.GetText(content)
+		if !ok {
+			return "", fmt.Errorf("error summarize tool result, the summary is not text")
+		}
+
+		return summary, nil
+	}
+}
@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrMaxIterations is returned when the tool loop exhausts
+// RequestParams.MaxIterations without the model finishing its answer.
+var ErrMaxIterations = errors.New("max iterations reached")
+
+// ToolCallGuard tracks the tool calls made during one request and detects
+// the model looping on the same call with the same arguments.
+type ToolCallGuard struct {
+	mu    sync.Mutex
+	limit int
+	seen  map[string]int
+}
+
+func NewToolCallGuard(limit int) *ToolCallGuard {
+	return &ToolCallGuard{
+		limit: limit,
+		seen:  map[string]int{},
+	}
+}
+
+// Allow records the call and reports whether it may run. A limit lower than
+// one disables the guard.
+func (g *ToolCallGuard) Allow(name string, args map[string]any) bool {
+	if g.limit < 1 {
+		return true
+	}
+
+	// json.Marshal sorts map keys, so equal arguments give equal keys
+	key, err := json.Marshal(args)
+	if err != nil {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := name + string(key)
+	g.seen[id]++
+
+	return g.seen[id] <= g.limit
+}
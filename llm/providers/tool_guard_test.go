@@ -0,0 +1,36 @@
+package providers_test
+
+import (
+	"testing"
+
+	"github.com/jlrosende/go-agents/llm/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolCallGuard(t *testing.T) {
+	t.Run("block repeated identical calls", func(t *testing.T) {
+		guard := providers.NewToolCallGuard(2)
+
+		args := map[string]any{"path": "README.md", "lines": 10}
+
+		assert.True(t, guard.Allow("read_file", args))
+		assert.True(t, guard.Allow("read_file", map[string]any{"lines": 10, "path": "README.md"}))
+		assert.False(t, guard.Allow("read_file", args))
+	})
+
+	t.Run("different arguments are not repeated calls", func(t *testing.T) {
+		guard := providers.NewToolCallGuard(1)
+
+		assert.True(t, guard.Allow("read_file", map[string]any{"path": "a"}))
+		assert.True(t, guard.Allow("read_file", map[string]any{"path": "b"}))
+		assert.True(t, guard.Allow("write_file", map[string]any{"path": "a"}))
+	})
+
+	t.Run("disabled guard", func(t *testing.T) {
+		guard := providers.NewToolCallGuard(0)
+
+		for range 5 {
+			assert.True(t, guard.Allow("read_file", nil))
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/client"
+	mcp_transport "github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// A server is shared by the agents using it, so sampling and elicitation
+// requests are answered by the handlers in the context of the tool call that
+// caused them, set by the calling agent.
+
+type samplerKey struct{}
+
+type elicitorKey struct{}
+
+type tokenKey struct{}
+
+type serverKey struct{}
+
+// WithSampler answers the sampling requests of the servers called with ctx.
+func WithSampler(ctx context.Context, sampler client.SamplingHandler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, sampler)
+}
+
+// WithElicitor answers the elicitation requests of the servers called with
+// ctx.
+func WithElicitor(ctx context.Context, elicitor client.ElicitationHandler) context.Context {
+	return context.WithValue(ctx, elicitorKey{}, elicitor)
+}
+
+// ServerName returns the name of the server whose request is answered with
+// ctx, for the sampling and elicitation handlers.
+func ServerName(ctx context.Context) string {
+	name, _ := ctx.Value(serverKey{}).(string)
+	return name
+}
+
+var (
+	_ client.SamplingHandler    = (*MCPServer)(nil)
+	_ client.ElicitationHandler = (*MCPServer)(nil)
+	_ client.RootsHandler       = (*MCPServer)(nil)
+)
+
+// track registers a tool call in progress with a new progress token until
+// done is called.
+func (server *MCPServer) track(ctx context.Context) (token string, done func()) {
+	token = uuid.NewString()
+
+	server.mu.Lock()
+	server.calls[token] = ctx
+	server.mu.Unlock()
+
+	return token, func() {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+
+		delete(server.calls, token)
+	}
+}
+
+// call returns the context of the tool call the request answered with ctx
+// belongs to: the call of the progress token in the _meta of the request, or
+// the only call in progress when the request has no token. Requests of other
+// calls, or made while several calls run, are not matched.
+func (server *MCPServer) call(ctx context.Context) (context.Context, bool) {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	var call context.Context
+
+	if token, ok := ctx.Value(tokenKey{}).(string); ok {
+		call = server.calls[token]
+	} else if len(server.calls) == 1 {
+		for _, c := range server.calls {
+			call = c
+		}
+	}
+
+	if call == nil {
+		return nil, false
+	}
+
+	return context.WithValue(call, serverKey{}, server.Name), true
+}
+
+// The handlers run with the context of the call, so they stop with it and see
+// the approver and policies of the calling agent.
+
+func (server *MCPServer) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	call, ok := server.call(ctx)
+	if !ok {
+		return nil, fmt.Errorf("mcp server %s requested sampling outside an agent tool call", server.Name)
+	}
+
+	sampler, ok := call.Value(samplerKey{}).(client.SamplingHandler)
+	if !ok {
+		return nil, fmt.Errorf("mcp server %s requested sampling without a model to answer", server.Name)
+	}
+
+	server.Logger.Info("sampling requested", "messages", len(request.Messages))
+
+	return sampler.CreateMessage(call, request)
+}
+
+func (server *MCPServer) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	call, ok := server.call(ctx)
+	if !ok {
+		return nil, fmt.Errorf("mcp server %s requested elicitation outside an agent tool call", server.Name)
+	}
+
+	elicitor, ok := call.Value(elicitorKey{}).(client.ElicitationHandler)
+	if !ok {
+		server.Logger.Warn("elicitation requested without a user to ask", "message", request.Params.Message)
+
+		return &mcp.ElicitationResult{
+			ElicitationResponse: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline},
+		}, nil
+	}
+
+	return elicitor.Elicit(call, request)
+}
+
+// callTransport passes the progress token in the _meta of the requests of the
+// server to the handlers, to find the call they belong to. mcp-go drops _meta
+// when it parses the requests.
+type callTransport struct {
+	mcp_transport.Interface
+}
+
+func (t callTransport) SetRequestHandler(handler mcp_transport.RequestHandler) {
+	bidirectional, ok := t.Interface.(mcp_transport.BidirectionalInterface)
+	if !ok {
+		return
+	}
+
+	bidirectional.SetRequestHandler(func(ctx context.Context, request mcp_transport.JSONRPCRequest) (*mcp_transport.JSONRPCResponse, error) {
+		if token, ok := progressToken(request.Params); ok {
+			ctx = context.WithValue(ctx, tokenKey{}, token)
+		}
+
+		return handler(ctx, request)
+	})
+}
+
+func (t callTransport) SetConnectionLostHandler(handler func(error)) {
+	if setter, ok := t.Interface.(interface{ SetConnectionLostHandler(func(error)) }); ok {
+		setter.SetConnectionLostHandler(handler)
+	}
+}
+
+func (t callTransport) SetProtocolVersion(version string) {
+	if conn, ok := t.Interface.(mcp_transport.HTTPConnection); ok {
+		conn.SetProtocolVersion(version)
+	}
+}
+
+func progressToken(params any) (string, bool) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+
+	var request struct {
+		Meta struct {
+			ProgressToken any `json:"progressToken"`
+		} `json:"_meta"`
+	}
+
+	if err := json.Unmarshal(data, &request); err != nil || request.Meta.ProgressToken == nil {
+		return "", false
+	}
+
+	return fmt.Sprint(request.Meta.ProgressToken), true
+}
+
+// NewRoot builds a root from a directory path or a file:// uri.
+func NewRoot(path string) mcp.Root {
+	if strings.HasPrefix(path, "file://") {
+		return mcp.Root{URI: path, Name: filepath.Base(strings.TrimPrefix(path, "file://"))}
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	uri := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+
+	return mcp.Root{URI: uri.String(), Name: filepath.Base(path)}
+}
+
+// AddRoots declares the roots of an agent, the server sees the roots of all
+// the agents using it.
+func (server *MCPServer) AddRoots(roots ...mcp.Root) {
+	server.mu.Lock()
+
+	added := false
+
+	for _, root := range roots {
+		if slices.ContainsFunc(server.roots, func(r mcp.Root) bool { return r.URI == root.URI }) {
+			continue
+		}
+
+		server.roots = append(server.roots, root)
+		added = true
+	}
+
+	cli := server.client
+
+	server.mu.Unlock()
+
+	if !added || cli == nil {
+		return
+	}
+
+	if err := cli.RootListChanges(server.ctx); err != nil {
+		server.Logger.Error("error notify roots changes", "error", err)
+	}
+}
+
+func (server *MCPServer) ListRoots(ctx context.Context, request mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	return &mcp.ListRootsResult{Roots: slices.Clone(server.roots)}, nil
+}
@@ -0,0 +1,161 @@
+package mcp_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcp_client "github.com/mark3labs/mcp-go/client"
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+	mcp_server "github.com/mark3labs/mcp-go/server"
+)
+
+type samplerFunc func(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error)
+
+func (f samplerFunc) CreateMessage(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error) {
+	return f(ctx, request)
+}
+
+type elicitorFunc func(ctx context.Context, request mcp_tool.ElicitationRequest) (*mcp_tool.ElicitationResult, error)
+
+func (f elicitorFunc) Elicit(ctx context.Context, request mcp_tool.ElicitationRequest) (*mcp_tool.ElicitationResult, error) {
+	return f(ctx, request)
+}
+
+var (
+	_ mcp_client.SamplingHandler    = samplerFunc(nil)
+	_ mcp_client.ElicitationHandler = elicitorFunc(nil)
+)
+
+func TestMCPServerClientFeatures(t *testing.T) {
+	server := mcp_server.NewMCPServer("test", "0.0.1", mcp_server.WithElicitation(), mcp_server.WithRoots())
+	server.EnableSampling()
+
+	server.AddTool(mcp_tool.NewTool("summarise"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		sampling := mcp_tool.CreateMessageRequest{}
+		sampling.Messages = []mcp_tool.SamplingMessage{
+			{Role: mcp_tool.RoleUser, Content: mcp_tool.NewTextContent("summarise this")},
+		}
+		sampling.MaxTokens = 10
+
+		result, err := server.RequestSampling(ctx, sampling)
+		if err != nil {
+			return nil, err
+		}
+
+		return mcp_tool.NewToolResultText(result.Content.(mcp_tool.TextContent).Text), nil
+	})
+
+	server.AddTool(mcp_tool.NewTool("ask"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		elicitation := mcp_tool.ElicitationRequest{}
+		elicitation.Params.Message = "name?"
+
+		result, err := server.RequestElicitation(ctx, elicitation)
+		if err != nil {
+			return nil, err
+		}
+
+		return mcp_tool.NewToolResultText(string(result.Action)), nil
+	})
+
+	release := make(chan struct{})
+
+	server.AddTool(mcp_tool.NewTool("wait"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		<-release
+		return mcp_tool.NewToolResultText("done"), nil
+	})
+
+	server.AddTool(mcp_tool.NewTool("roots"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		result, err := server.RequestRoots(ctx, mcp_tool.ListRootsRequest{})
+		if err != nil {
+			return nil, err
+		}
+
+		return mcp_tool.NewToolResultText(result.Roots[0].URI), nil
+	})
+
+	httpServer := httptest.NewServer(mcp_server.NewStreamableHTTPServer(server))
+	defer httpServer.Close()
+
+	client, err := mcp.NewMCPServer(context.Background(), "test", mcp.TRANSPORT_HTTP, httpServer.URL+"/mcp", "", nil, nil)
+	require.NoError(t, err)
+
+	client.AddRoots(mcp.NewRoot("/workspace"))
+
+	require.NoError(t, client.Start())
+	defer client.Close()
+
+	t.Run("sampling uses the handler of the call", func(t *testing.T) {
+		ctx := mcp.WithSampler(context.Background(), samplerFunc(func(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error) {
+			result := &mcp_tool.CreateMessageResult{Model: "test"}
+			result.Role = mcp_tool.RoleAssistant
+			result.Content = mcp_tool.NewTextContent("summary")
+			return result, nil
+		}))
+
+		result, err := client.CallTool(ctx, "summarise", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "summary", mcp.Result(result.Content).FirstText())
+	})
+
+	t.Run("sampling without handler fails", func(t *testing.T) {
+		result, err := client.CallTool(context.Background(), "summarise", nil)
+		assert.True(t, err != nil || result.IsError)
+	})
+
+	t.Run("requests of unknown calls are rejected", func(t *testing.T) {
+		sampled := atomic.Bool{}
+
+		ctx := mcp.WithSampler(context.Background(), samplerFunc(func(ctx context.Context, request mcp_tool.CreateMessageRequest) (*mcp_tool.CreateMessageResult, error) {
+			sampled.Store(true)
+			return nil, nil
+		}))
+
+		waited := make(chan error)
+
+		go func() {
+			_, err := client.CallTool(context.Background(), "wait", nil)
+			waited <- err
+		}()
+
+		// Two calls run and the request names none of them
+		assert.Eventually(t, func() bool {
+			result, err := client.CallTool(ctx, "summarise", nil)
+			return err != nil || result.IsError
+		}, time.Second, 10*time.Millisecond)
+
+		close(release)
+		require.NoError(t, <-waited)
+
+		assert.False(t, sampled.Load())
+	})
+
+	t.Run("elicitation", func(t *testing.T) {
+		ctx := mcp.WithElicitor(context.Background(), elicitorFunc(func(ctx context.Context, request mcp_tool.ElicitationRequest) (*mcp_tool.ElicitationResult, error) {
+			result := &mcp_tool.ElicitationResult{}
+			result.Action = mcp_tool.ElicitationResponseActionAccept
+			result.Content = map[string]any{"name": "go"}
+			return result, nil
+		}))
+
+		result, err := client.CallTool(ctx, "ask", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "accept", mcp.Result(result.Content).FirstText())
+
+		result, err = client.CallTool(context.Background(), "ask", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "decline", mcp.Result(result.Content).FirstText())
+	})
+
+	t.Run("roots", func(t *testing.T) {
+		result, err := client.CallTool(context.Background(), "roots", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "file:///workspace", mcp.Result(result.Content).FirstText())
+	})
+}
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Check asks the monitor to ping the server now instead of waiting for the
+// next interval, used when a request fails.
+func (server *MCPServer) Check() {
+	select {
+	case server.check <- struct{}{}:
+	default:
+	}
+}
+
+// monitor pings the server periodically and reconnects when it stops
+// answering, restarting the process of stdio servers.
+func (server *MCPServer) monitor() {
+	defer close(server.monitorDone)
+
+	ticker := time.NewTicker(server.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-server.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-server.check:
+		}
+
+		err := server.ping()
+		if err == nil {
+			continue
+		}
+
+		if server.ctx.Err() != nil {
+			return
+		}
+
+		server.Logger.Warn("mcp server not healthy, reconnecting", "error", err)
+
+		if !server.reconnect() {
+			return
+		}
+	}
+}
+
+func (server *MCPServer) ping() error {
+	cli, err := server.getClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(server.ctx, server.PingTimeout)
+	defer cancel()
+
+	if err := cli.Ping(ctx); err != nil {
+		return fmt.Errorf("error ping mcp server %s, %w", server.Name, err)
+	}
+
+	return nil
+}
+
+// reconnect retries with exponential backoff until it connects or the server
+// is closed, then publishes the refreshed tools.
+func (server *MCPServer) reconnect() bool {
+	backoff := time.Second
+
+	for {
+		server.connMu.Lock()
+		err := server.connectAuthorized()
+		server.connMu.Unlock()
+
+		server.mu.RLock()
+		tools := server.tools
+		listeners := server.toolsListeners
+		server.mu.RUnlock()
+
+		if err == nil {
+			server.Logger.Info("mcp server reconnected", "tools", len(tools))
+
+			server.resubscribe()
+
+			for _, listener := range listeners {
+				listener(tools)
+			}
+
+			return true
+		}
+
+		server.Logger.Error(fmt.Sprintf("error reconnect mcp server, retry in %s", backoff), "error", err)
+
+		select {
+		case <-server.ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, server.MaxBackoff)
+	}
+}
+
+// Started reports whether the server was started, lazy servers are not
+// until their first request.
+func (server *MCPServer) Started() bool {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	return server.started
+}
+
+// Tools returns the tools listed on the last (re)connection.
+func (server *MCPServer) Tools() []mcp.Tool {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	return server.tools
+}
@@ -3,15 +3,25 @@ package mcp
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"path"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/client"
 	mcp_transport "github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// ErrToolTimeout is returned when a tool call exceeds its timeout.
+var ErrToolTimeout = errors.New("tool call timed out")
+
 type Transport string
 
 const (
@@ -22,29 +32,168 @@ const (
 
 type MCPServer struct {
 	ctx    context.Context
+	cancel context.CancelFunc
 	Name   string
-	client *client.Client
-	tools  map[string]struct{}
+
+	// newTransport builds a fresh transport for every (re)connection
+	newTransport func() (mcp_transport.Interface, error)
+
+	// connMu serializes the connection attempts, done without holding mu so
+	// requests on the current client are not blocked by network I/O
+	connMu sync.Mutex
+
+	mu         sync.RWMutex
+	client     *client.Client
+	connCancel context.CancelFunc
+	tools      []mcp.Tool
+	started    bool
+
+	toolsListeners []func(tools []mcp.Tool)
+	refreshMu      sync.Mutex
+
+	// Resources subscribed, renewed on every connection
+	subscriptions     map[string]struct{}
+	resourceListeners []func(uri string)
+
+	// Client features, see client.go
+	calls map[string]context.Context
+	roots []mcp.Root
+
+	// Process of stdio servers, see sandbox.go
+	Sandbox Sandbox
+
+	// HTTP authentication, see oauth.go
+	Headers map[string]string
+	OAuth   *OAuth
+	tokens  *tokenStore
+
+	// Startup, lazy servers start on the first request instead of with the
+	// controller
+	Lazy           bool
+	StartupTimeout time.Duration
+
+	// Tool calls fail after ToolTimeout, 5 minutes by default, or the timeout
+	// of the longest pattern of ToolTimeouts matching the tool name
+	ToolTimeout  time.Duration
+	ToolTimeouts map[string]time.Duration
+
+	// Health checks
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+	MaxBackoff   time.Duration
+	check        chan struct{}
+	monitorDone  chan struct{}
+
+	Logger *slog.Logger
+}
+
+func WithHeaders(headers map[string]string) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.Headers = headers
+	}
+}
+
+func WithLazy(lazy bool) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.Lazy = lazy
+	}
+}
+
+func WithStartupTimeout(timeout time.Duration) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.StartupTimeout = timeout
+	}
+}
+
+func WithToolTimeout(timeout time.Duration) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.ToolTimeout = timeout
+	}
+}
+
+func WithToolTimeouts(timeouts map[string]time.Duration) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.ToolTimeouts = timeouts
+	}
+}
+
+func WithPingInterval(interval time.Duration) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.PingInterval = interval
+	}
+}
+
+func WithPingTimeout(timeout time.Duration) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.PingTimeout = timeout
+	}
+}
+
+func WithMaxBackoff(backoff time.Duration) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.MaxBackoff = backoff
+	}
 }
 
-func NewMCPServer(ctx context.Context, name string, transport Transport, url, command string, environments map[string]string, args ...string) (*MCPServer, error) {
+func NewMCPServer(ctx context.Context, name string, transport Transport, url, command string, environments map[string]string, args []string, options ...func(*MCPServer)) (*MCPServer, error) {
 
-	var t mcp_transport.Interface
-	var err error
+	ctx, cancel := context.WithCancel(ctx)
+
+	server := &MCPServer{
+		ctx:            ctx,
+		cancel:         cancel,
+		Name:           name,
+		StartupTimeout: time.Minute,
+		ToolTimeout:    5 * time.Minute,
+		PingInterval:   30 * time.Second,
+		PingTimeout:    10 * time.Second,
+		MaxBackoff:     time.Minute,
+		subscriptions:  map[string]struct{}{},
+		calls:          map[string]context.Context{},
+		check:          make(chan struct{}, 1),
+		monitorDone:    make(chan struct{}),
+		Logger:         slog.Default().With(slog.String("mcp_server", name)),
+	}
+
+	for _, o := range options {
+		o(server)
+	}
 
 	switch transport {
 
 	case TRANSPORT_HTTP:
-		t, err = mcp_transport.NewStreamableHTTP(url)
+		server.newTransport = func() (mcp_transport.Interface, error) {
+			// Listen for the requests and notifications of the server
+			httpOptions := []mcp_transport.StreamableHTTPCOption{
+				mcp_transport.WithContinuousListening(),
+				mcp_transport.WithHTTPHeaders(server.Headers),
+			}
+
+			if server.OAuth != nil {
+				httpOptions = append(httpOptions, mcp_transport.WithHTTPOAuth(server.oauthConfig()))
+			}
 
-		if err != nil {
-			return nil, fmt.Errorf("error create client http %s, %w", name, err)
+			t, err := mcp_transport.NewStreamableHTTP(url, httpOptions...)
+			if err != nil {
+				return nil, fmt.Errorf("error create client http %s, %w", name, err)
+			}
+			return t, nil
 		}
 	case TRANSPORT_SSE:
-		t, err = mcp_transport.NewSSE(url)
+		server.newTransport = func() (mcp_transport.Interface, error) {
+			sseOptions := []mcp_transport.ClientOption{
+				mcp_transport.WithHeaders(server.Headers),
+			}
 
-		if err != nil {
-			return nil, fmt.Errorf("error create client sse %s, %w", name, err)
+			if server.OAuth != nil {
+				sseOptions = append(sseOptions, mcp_transport.WithOAuth(server.oauthConfig()))
+			}
+
+			t, err := mcp_transport.NewSSE(url, sseOptions...)
+			if err != nil {
+				return nil, fmt.Errorf("error create client sse %s, %w", name, err)
+			}
+			return t, nil
 		}
 	case TRANSPORT_STDIO:
 		fallthrough
@@ -55,41 +204,262 @@ func NewMCPServer(ctx context.Context, name string, transport Transport, url, co
 			envs = append(envs, fmt.Sprintf("%s=%s", strings.ToUpper(key), value))
 		}
 
-		t = mcp_transport.NewStdio(command, envs, args...)
+		if err := server.Sandbox.validate(); err != nil {
+			cancel()
+			return nil, err
+		}
+
+		server.newTransport = func() (mcp_transport.Interface, error) {
+			return mcp_transport.NewStdioWithOptions(command, envs, args, mcp_transport.WithCommandFunc(server.Sandbox.command)), nil
+		}
+	}
+
+	if server.OAuth != nil {
+		if err := server.initOAuth(); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// Fail early on invalid configurations
+	if _, err := server.newTransport(); err != nil {
+		cancel()
+		return nil, err
 	}
 
-	return &MCPServer{
-		ctx:    ctx,
-		Name:   name,
-		client: client.NewClient(t),
-	}, nil
+	return server, nil
 }
 
+// Start connects to the server and keeps checking its health until Close.
+// Calling it on a started server does nothing.
 func (server *MCPServer) Start() error {
-	err := server.client.Start(server.ctx)
+	server.connMu.Lock()
+	defer server.connMu.Unlock()
+
+	server.mu.RLock()
+	started := server.started
+	server.mu.RUnlock()
+
+	if started {
+		return nil
+	}
+
+	if server.ctx.Err() != nil {
+		return fmt.Errorf("mcp server %s is closed", server.Name)
+	}
+
+	if err := server.connectAuthorized(); err != nil {
+		return err
+	}
+
+	server.mu.Lock()
+	server.started = true
+	server.mu.Unlock()
+
+	go server.monitor()
+
+	return nil
+}
+
+// connect replaces the client with a new connection. The caller holds
+// connMu, mu is only held to swap the clients.
+func (server *MCPServer) connect() error {
+	t, err := server.newTransport()
 	if err != nil {
-		return fmt.Errorf("error start mcp server %s, %w", server.Name, err)
+		return err
 	}
-	_, err = server.client.Initialize(server.ctx, mcp.InitializeRequest{})
 
+	connCtx, connCancel := context.WithCancel(server.ctx)
+
+	// The connection context outlives the startup (it owns the stdio process),
+	// so the timeout cancels it only while connecting
+	timer := time.AfterFunc(server.StartupTimeout, connCancel)
+
+	cli := client.NewClient(callTransport{t},
+		client.WithSamplingHandler(server),
+		client.WithElicitationHandler(server),
+		client.WithRootsHandler(server),
+	)
+	started := false
+
+	// The handler runs in the transport read loop, listing the tools there
+	// would wait for a response the loop never reads
+	cli.OnNotification(func(notification mcp.JSONRPCNotification) {
+		switch notification.Method {
+		case mcp.MethodNotificationToolsListChanged:
+			go server.toolsChanged(cli)
+		case mcp.MethodNotificationResourceUpdated:
+			uri, _ := notification.Params.AdditionalFields["uri"].(string)
+			go server.resourceUpdated(uri)
+		}
+	})
+
+	// Cancel before closing, closing waits for the stdio process to exit
+	fail := func(err error) error {
+		if !timer.Stop() {
+			err = fmt.Errorf("%w, timeout after %s", err, server.StartupTimeout)
+		}
+
+		connCancel()
+
+		if started {
+			cli.Close()
+		}
+
+		return err
+	}
+
+	if err := cli.Start(connCtx); err != nil {
+		return fail(fmt.Errorf("error start mcp server %s, %w", server.Name, err))
+	}
+
+	started = true
+
+	if stdio, ok := t.(interface{ Stderr() io.Reader }); ok && stdio.Stderr() != nil {
+		go server.logStderr(stdio.Stderr())
+	}
+
+	if _, err := cli.Initialize(connCtx, mcp.InitializeRequest{}); err != nil {
+		return fail(fmt.Errorf("error initialize mcp server %s, %w", server.Name, err))
+	}
+
+	tools, err := listTools(connCtx, cli)
 	if err != nil {
-		return fmt.Errorf("error initialize mcp server %s, %w", server.Name, err)
+		return fail(fmt.Errorf("error list tools mcp server %s, %w", server.Name, err))
+	}
+
+	if !timer.Stop() {
+		return fail(fmt.Errorf("error start mcp server %s", server.Name))
+	}
+
+	server.mu.Lock()
+
+	// Closed while connecting, Close already disconnected
+	if server.ctx.Err() != nil {
+		server.mu.Unlock()
+		return fail(fmt.Errorf("mcp server %s is closed", server.Name))
+	}
+
+	closePrevious := server.disconnect()
+
+	server.client = cli
+	server.connCancel = connCancel
+	server.tools = tools
+
+	server.mu.Unlock()
+
+	closePrevious()
+
+	return nil
+}
+
+// disconnect detaches the current client, the caller holds mu. The returned
+// function closes it, killing the stdio process if it does not exit by
+// itself, and is called after releasing mu.
+func (server *MCPServer) disconnect() func() {
+	cli, connCancel := server.client, server.connCancel
+
+	if cli == nil {
+		return func() {}
+	}
+
+	server.client = nil
+
+	return func() {
+		connCancel()
+
+		if err := cli.Close(); err != nil {
+			server.Logger.Debug("error close mcp client", "error", err)
+		}
+	}
+}
+
+// getClient returns the current client, starting lazy servers.
+func (server *MCPServer) getClient() (*client.Client, error) {
+	server.mu.RLock()
+	started := server.started
+	server.mu.RUnlock()
+
+	if !started {
+		if err := server.Start(); err != nil {
+			return nil, err
+		}
 	}
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	if server.client == nil {
+		return nil, fmt.Errorf("mcp server %s is not connected", server.Name)
+	}
+
+	return server.client, nil
+}
+
+// OnToolsChanged registers a listener called with the new tool list every
+// time it is refreshed.
+func (server *MCPServer) OnToolsChanged(listener func(tools []mcp.Tool)) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.toolsListeners = append(server.toolsListeners, listener)
+}
+
+// Close stops the health checks and closes the connection.
+func (server *MCPServer) Close() error {
+	server.cancel()
+
+	server.mu.Lock()
+	started := server.started
+	closeClient := server.disconnect()
+	server.mu.Unlock()
+
+	closeClient()
+
+	if started {
+		<-server.monitorDone
+	}
+
 	return nil
 }
 
 func (server *MCPServer) ListTools() ([]mcp.Tool, error) {
-	tools, err := server.client.ListTools(server.ctx, mcp.ListToolsRequest{})
+	cli, err := server.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	tools, err := listTools(server.ctx, cli)
 
 	if err != nil {
+		server.Check()
 		return nil, fmt.Errorf("error list tools mcp server %s, %w", server.Name, err)
 	}
 
-	return tools.Tools, nil
+	return tools, nil
 }
 
-func (server *MCPServer) CallTool(name string, args any) (*mcp.CallToolResult, error) {
-	result, err := server.client.CallTool(server.ctx, mcp.CallToolRequest{
+// CallTool calls a tool, sampling and elicitation requests made by the server
+// during the call are answered by the handlers in ctx. The call sends its own
+// progress token in _meta, see handler.
+func (server *MCPServer) CallTool(ctx context.Context, name string, args any) (*mcp.CallToolResult, error) {
+	cli, err := server.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := server.toolTimeout(name)
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrToolTimeout)
+		defer cancel()
+	}
+
+	token, done := server.track(ctx)
+	defer done()
+
+	result, err := cli.CallTool(ctx, mcp.CallToolRequest{
 		Params: struct {
 			Name      string    `json:"name"`
 			Arguments any       `json:"arguments,omitempty"`
@@ -97,16 +467,45 @@ func (server *MCPServer) CallTool(name string, args any) (*mcp.CallToolResult, e
 		}{
 			Name:      name,
 			Arguments: args,
+			Meta:      &mcp.Meta{ProgressToken: token},
 		},
 	})
 
+	if errors.Is(context.Cause(ctx), ErrToolTimeout) {
+		return nil, fmt.Errorf("error call tool %s on mcp server %s after %s, %w", name, server.Name, timeout, ErrToolTimeout)
+	}
+
 	if err != nil {
+		server.Check()
 		return nil, fmt.Errorf("error call tool %s on mcp server %s, %w", name, server.Name, err)
 	}
 
 	return result, nil
 }
 
+// toolTimeout returns the timeout of the tool, an exact name wins over the
+// glob patterns and zero disables it.
+func (server *MCPServer) toolTimeout(name string) time.Duration {
+	if timeout, ok := server.ToolTimeouts[name]; ok {
+		return timeout
+	}
+
+	// The longest pattern is the most specific one
+	match := ""
+
+	for _, pattern := range slices.Sorted(maps.Keys(server.ToolTimeouts)) {
+		if ok, _ := path.Match(pattern, name); ok && len(pattern) > len(match) {
+			match = pattern
+		}
+	}
+
+	if match != "" {
+		return server.ToolTimeouts[match]
+	}
+
+	return server.ToolTimeout
+}
+
 type Result []mcp.Content
 
 func (r Result) AllText() string {
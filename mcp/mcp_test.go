@@ -0,0 +1,359 @@
+package mcp_test
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+	mcp_server "github.com/mark3labs/mcp-go/server"
+)
+
+// serveTools serves an MCP server with the given tools on addr.
+func serveTools(t *testing.T, addr string, names ...string) (*http.Server, string) {
+	t.Helper()
+
+	server := mcp_server.NewMCPServer("test", "0.0.1")
+
+	for _, name := range names {
+		server.AddTool(mcp_tool.NewTool(name), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+			return mcp_tool.NewToolResultText(name), nil
+		})
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+
+	httpServer := &http.Server{Handler: mcp_server.NewStreamableHTTPServer(server)}
+
+	go httpServer.Serve(lis)
+
+	return httpServer, lis.Addr().String()
+}
+
+// logWatch closes seen when a log line contains match.
+type logWatch struct {
+	once  sync.Once
+	match string
+	seen  chan struct{}
+}
+
+func (w *logWatch) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), w.match) {
+		w.once.Do(func() { close(w.seen) })
+	}
+
+	return len(p), nil
+}
+
+func TestMCPServerReconnect(t *testing.T) {
+	httpServer, addr := serveTools(t, "127.0.0.1:0", "echo")
+
+	server, err := mcp.NewMCPServer(
+		context.Background(), "test", mcp.TRANSPORT_HTTP, "http://"+addr+"/mcp", "", nil, nil,
+		mcp.WithPingInterval(50*time.Millisecond),
+		mcp.WithPingTimeout(time.Second),
+	)
+	require.NoError(t, err)
+
+	unhealthy := &logWatch{match: "not healthy", seen: make(chan struct{})}
+	server.Logger = slog.New(slog.NewTextHandler(unhealthy, nil))
+
+	require.NoError(t, server.Start())
+	defer server.Close()
+
+	result, err := server.CallTool(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "echo", mcp.Result(result.Content).FirstText())
+
+	refreshed := make(chan []mcp_tool.Tool, 1)
+	server.OnToolsChanged(func(tools []mcp_tool.Tool) {
+		refreshed <- tools
+	})
+
+	// Restart the server with a new tool list on the same address
+	httpServer.Close()
+
+	// Restart once the monitor notices the server is down
+	select {
+	case <-unhealthy.seen:
+	case <-time.After(5 * time.Second):
+		t.Fatal("mcp server down not noticed")
+	}
+
+	restarted, _ := serveTools(t, addr, "echo", "reverse")
+	defer restarted.Close()
+
+	select {
+	case tools := <-refreshed:
+		assert.Len(t, tools, 2)
+	case <-time.After(10 * time.Second):
+		t.Fatal("mcp server did not reconnect")
+	}
+
+	result, err = server.CallTool(context.Background(), "reverse", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "reverse", mcp.Result(result.Content).FirstText())
+}
+
+func TestMCPServerClose(t *testing.T) {
+	httpServer, addr := serveTools(t, "127.0.0.1:0", "echo")
+	defer httpServer.Close()
+
+	server, err := mcp.NewMCPServer(context.Background(), "test", mcp.TRANSPORT_HTTP, "http://"+addr+"/mcp", "", nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, server.Start())
+	require.NoError(t, server.Close())
+
+	_, err = server.CallTool(context.Background(), "echo", nil)
+	assert.Error(t, err)
+}
+
+func TestMCPServerStartup(t *testing.T) {
+	t.Run("lazy server starts on first call", func(t *testing.T) {
+		httpServer, addr := serveTools(t, "127.0.0.1:0", "echo")
+		defer httpServer.Close()
+
+		server, err := mcp.NewMCPServer(context.Background(), "test", mcp.TRANSPORT_HTTP, "http://"+addr+"/mcp", "", nil, nil, mcp.WithLazy(true))
+		require.NoError(t, err)
+		defer server.Close()
+
+		assert.Empty(t, server.Tools())
+
+		result, err := server.CallTool(context.Background(), "echo", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "echo", mcp.Result(result.Content).FirstText())
+		assert.Len(t, server.Tools(), 1)
+	})
+
+	t.Run("startup timeout", func(t *testing.T) {
+		// Accept connections but never answer
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer lis.Close()
+
+		block := make(chan struct{})
+		defer close(block)
+
+		go http.Serve(lis, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+		}))
+
+		server, err := mcp.NewMCPServer(
+			context.Background(), "test", mcp.TRANSPORT_HTTP, "http://"+lis.Addr().String()+"/mcp", "", nil, nil,
+			mcp.WithStartupTimeout(100*time.Millisecond),
+		)
+		require.NoError(t, err)
+		defer server.Close()
+
+		err = server.Start()
+		assert.ErrorContains(t, err, "timeout")
+	})
+
+	t.Run("connections do not block requests", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer lis.Close()
+
+		requested := make(chan struct{}, 1)
+		block := make(chan struct{})
+
+		go http.Serve(lis, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case requested <- struct{}{}:
+			default:
+			}
+			<-block
+		}))
+
+		server, err := mcp.NewMCPServer(context.Background(), "test", mcp.TRANSPORT_HTTP, "http://"+lis.Addr().String()+"/mcp", "", nil, nil)
+		require.NoError(t, err)
+		defer server.Close()
+
+		started := make(chan error, 1)
+		go func() { started <- server.Start() }()
+
+		<-requested
+
+		listed := make(chan []mcp_tool.Tool, 1)
+		go func() { listed <- server.Tools() }()
+
+		select {
+		case tools := <-listed:
+			assert.Empty(t, tools)
+		case <-time.After(5 * time.Second):
+			t.Fatal("tools blocked by the connection")
+		}
+
+		close(block)
+		assert.Error(t, <-started)
+	})
+}
+
+func TestMCPServerToolsChanged(t *testing.T) {
+	server := mcp_server.NewMCPServer("test", "0.0.1",
+		mcp_server.WithToolCapabilities(true),
+		mcp_server.WithPaginationLimit(1),
+	)
+
+	handler := func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		return mcp_tool.NewToolResultText(request.Params.Name), nil
+	}
+
+	server.AddTool(mcp_tool.NewTool("echo"), handler)
+	server.AddTool(mcp_tool.NewTool("reverse"), handler)
+
+	httpServer := httptest.NewServer(mcp_server.NewSSEServer(server))
+	defer httpServer.Close()
+
+	client, err := mcp.NewMCPServer(context.Background(), "test", mcp.TRANSPORT_SSE, httpServer.URL+"/sse", "", nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Start())
+	defer client.Close()
+
+	t.Run("list all pages", func(t *testing.T) {
+		tools, err := client.ListTools()
+		require.NoError(t, err)
+		assert.Len(t, tools, 2)
+	})
+
+	t.Run("refresh on list changed", func(t *testing.T) {
+		refreshed := make(chan []mcp_tool.Tool, 1)
+		client.OnToolsChanged(func(tools []mcp_tool.Tool) {
+			refreshed <- tools
+		})
+
+		server.AddTool(mcp_tool.NewTool("upper"), handler)
+
+		select {
+		case tools := <-refreshed:
+			assert.Len(t, tools, 3)
+			assert.Len(t, client.Tools(), 3)
+		case <-time.After(5 * time.Second):
+			t.Fatal("tools not refreshed")
+		}
+	})
+}
+
+func TestMCPServerResourcesAndPrompts(t *testing.T) {
+	server := mcp_server.NewMCPServer("test", "0.0.1",
+		mcp_server.WithResourceCapabilities(true, false),
+		mcp_server.WithPromptCapabilities(false),
+	)
+
+	server.AddResource(mcp_tool.NewResource("docs://readme", "readme", mcp_tool.WithMIMEType("text/plain")),
+		func(ctx context.Context, request mcp_tool.ReadResourceRequest) ([]mcp_tool.ResourceContents, error) {
+			return []mcp_tool.ResourceContents{
+				mcp_tool.TextResourceContents{URI: request.Params.URI, MIMEType: "text/plain", Text: "hello"},
+			}, nil
+		})
+
+	server.AddResourceTemplate(mcp_tool.NewResourceTemplate("docs://{name}", "docs"),
+		func(ctx context.Context, request mcp_tool.ReadResourceRequest) ([]mcp_tool.ResourceContents, error) {
+			return []mcp_tool.ResourceContents{
+				mcp_tool.TextResourceContents{URI: request.Params.URI, Text: request.Params.URI},
+			}, nil
+		})
+
+	server.AddPrompt(mcp_tool.NewPrompt("reviewer", mcp_tool.WithArgument("language")),
+		func(ctx context.Context, request mcp_tool.GetPromptRequest) (*mcp_tool.GetPromptResult, error) {
+			return mcp_tool.NewGetPromptResult("reviewer", []mcp_tool.PromptMessage{
+				mcp_tool.NewPromptMessage(mcp_tool.RoleUser, mcp_tool.NewTextContent("You review code.")),
+				mcp_tool.NewPromptMessage(mcp_tool.RoleUser, mcp_tool.NewTextContent("Language: "+request.Params.Arguments["language"])),
+			}), nil
+		})
+
+	httpServer := httptest.NewServer(mcp_server.NewStreamableHTTPServer(server))
+	defer httpServer.Close()
+
+	client, err := mcp.NewMCPServer(context.Background(), "test", mcp.TRANSPORT_HTTP, httpServer.URL+"/mcp", "", nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Start())
+	defer client.Close()
+
+	t.Run("resources", func(t *testing.T) {
+		resources, err := client.ListResources()
+		require.NoError(t, err)
+		assert.Len(t, resources, 1)
+
+		templates, err := client.ListResourceTemplates()
+		require.NoError(t, err)
+		assert.Len(t, templates, 1)
+
+		contents, err := client.ReadResource("docs://readme")
+		require.NoError(t, err)
+		require.Len(t, contents, 1)
+		assert.Equal(t, "hello", contents[0].(mcp_tool.TextResourceContents).Text)
+
+		contents, err = client.ReadResource("docs://guide")
+		require.NoError(t, err)
+		require.Len(t, contents, 1)
+		assert.Equal(t, "docs://guide", contents[0].(mcp_tool.TextResourceContents).Text)
+	})
+
+	t.Run("prompts", func(t *testing.T) {
+		prompts, err := client.ListPrompts()
+		require.NoError(t, err)
+		assert.Len(t, prompts, 1)
+
+		prompt, err := client.GetPrompt("reviewer", map[string]string{"language": "go"})
+		require.NoError(t, err)
+		assert.Equal(t, "You review code.\n\nLanguage: go", mcp.PromptText(prompt))
+	})
+}
+
+func TestMCPServerToolTimeout(t *testing.T) {
+	server := mcp_server.NewMCPServer("test", "0.0.1")
+
+	for _, name := range []string{"fast", "slow"} {
+		server.AddTool(mcp_tool.NewTool(name), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+			if name == "slow" {
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+			return mcp_tool.NewToolResultText(name), nil
+		})
+	}
+
+	httpServer := httptest.NewServer(mcp_server.NewStreamableHTTPServer(server))
+	defer httpServer.Close()
+
+	client, err := mcp.NewMCPServer(
+		context.Background(), "test", mcp.TRANSPORT_HTTP, httpServer.URL+"/mcp", "", nil, nil,
+		mcp.WithToolTimeout(time.Minute),
+		mcp.WithToolTimeouts(map[string]time.Duration{"s*": 100 * time.Millisecond}),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Start())
+
+	t.Run("server timeout", func(t *testing.T) {
+		result, err := client.CallTool(context.Background(), "fast", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "fast", mcp.Result(result.Content).FirstText())
+	})
+
+	t.Run("tool timeout", func(t *testing.T) {
+		start := time.Now()
+
+		_, err := client.CallTool(context.Background(), "slow", nil)
+		assert.ErrorIs(t, err, mcp.ErrToolTimeout)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
@@ -0,0 +1,314 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	mcp_transport "github.com/mark3labs/mcp-go/client/transport"
+)
+
+const DEFAULT_REDIRECT_URI = "http://localhost:8085/oauth/callback"
+
+// OAuth configures the MCP authorization of HTTP and SSE servers: discovery
+// of the authorization server, dynamic client registration when ClientID is
+// empty, authorization code with PKCE in the browser and token refresh.
+type OAuth struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// RedirectURI is served locally to receive the authorization code
+	RedirectURI string
+
+	// MetadataURL skips the discovery of the authorization server
+	MetadataURL string
+
+	// TokenFile keeps the registered client and the tokens between runs,
+	// they are kept in memory when it is empty
+	TokenFile string
+
+	// OpenURL sends the user to the authorization page, the default prints
+	// the url to stderr. AuthorizationTimeout bounds the wait for the user.
+	OpenURL              func(url string) error
+	AuthorizationTimeout time.Duration
+}
+
+func WithOAuth(oauth OAuth) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.OAuth = &oauth
+	}
+}
+
+func printURL(url string) error {
+	_, err := fmt.Fprintf(os.Stderr, "\nOpen this url to authorize the mcp server:\n%s\n\n", url)
+	return err
+}
+
+func (server *MCPServer) initOAuth() error {
+	if server.OAuth.RedirectURI == "" {
+		server.OAuth.RedirectURI = DEFAULT_REDIRECT_URI
+	}
+
+	if err := mcp_transport.ValidateRedirectURI(server.OAuth.RedirectURI); err != nil {
+		return fmt.Errorf("error oauth mcp server %s, %w", server.Name, err)
+	}
+
+	if server.OAuth.OpenURL == nil {
+		server.OAuth.OpenURL = printURL
+	}
+
+	if server.OAuth.AuthorizationTimeout <= 0 {
+		server.OAuth.AuthorizationTimeout = 5 * time.Minute
+	}
+
+	tokens, err := newTokenStore(server.OAuth.TokenFile)
+	if err != nil {
+		return fmt.Errorf("error oauth mcp server %s, %w", server.Name, err)
+	}
+
+	server.tokens = tokens
+
+	// Reuse the client registered in a previous run
+	if server.OAuth.ClientID == "" {
+		server.OAuth.ClientID = tokens.state.ClientID
+		server.OAuth.ClientSecret = tokens.state.ClientSecret
+	}
+
+	return nil
+}
+
+func (server *MCPServer) oauthConfig() mcp_transport.OAuthConfig {
+	return mcp_transport.OAuthConfig{
+		ClientID:              server.OAuth.ClientID,
+		ClientSecret:          server.OAuth.ClientSecret,
+		RedirectURI:           server.OAuth.RedirectURI,
+		Scopes:                server.OAuth.Scopes,
+		TokenStore:            server.tokens,
+		AuthServerMetadataURL: server.OAuth.MetadataURL,
+		PKCEEnabled:           true,
+	}
+}
+
+// connectAuthorized connects, running the authorization flow when the server
+// requires it. The caller holds connMu and not mu, so while the user
+// authorizes, Close and the calls on the current connection do not wait.
+func (server *MCPServer) connectAuthorized() error {
+	err := server.connect()
+	if err == nil || !client.IsOAuthAuthorizationRequiredError(err) {
+		return err
+	}
+
+	server.Logger.Info("mcp server requires authorization")
+
+	if err := server.authorize(client.GetOAuthHandler(err)); err != nil {
+		return fmt.Errorf("error authorize mcp server %s, %w", server.Name, err)
+	}
+
+	return server.connect()
+}
+
+// authorize registers the client if needed and runs the authorization code
+// flow, saving the tokens in the store.
+func (server *MCPServer) authorize(handler *mcp_transport.OAuthHandler) error {
+	ctx, cancel := context.WithTimeout(server.ctx, server.OAuth.AuthorizationTimeout)
+	defer cancel()
+
+	if handler.GetClientID() == "" {
+		if err := handler.RegisterClient(ctx, "go-agents"); err != nil {
+			return err
+		}
+
+		server.OAuth.ClientID = handler.GetClientID()
+		server.OAuth.ClientSecret = handler.GetClientSecret()
+
+		if err := server.tokens.SaveClient(server.OAuth.ClientID, server.OAuth.ClientSecret); err != nil {
+			return err
+		}
+	}
+
+	verifier, err := mcp_transport.GenerateCodeVerifier()
+	if err != nil {
+		return err
+	}
+
+	state, err := mcp_transport.GenerateState()
+	if err != nil {
+		return err
+	}
+
+	redirect, err := url.Parse(server.OAuth.RedirectURI)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return fmt.Errorf("error listen redirect uri %s, %w", server.OAuth.RedirectURI, err)
+	}
+
+	type callback struct {
+		code string
+		err  error
+	}
+
+	callbacks := make(chan callback, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirect.Path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		if query.Get("state") != state {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+
+		result := callback{code: query.Get("code")}
+
+		if e := query.Get("error"); e != "" {
+			result.err = fmt.Errorf("authorization denied, %s %s", e, query.Get("error_description"))
+		}
+
+		select {
+		case callbacks <- result:
+		default:
+		}
+
+		fmt.Fprintln(w, "Authorization finished, you can close this window.")
+	})
+
+	callbackServer := &http.Server{Handler: mux}
+
+	go callbackServer.Serve(lis)
+	defer callbackServer.Close()
+
+	authURL, err := handler.GetAuthorizationURL(ctx, state, mcp_transport.GenerateCodeChallenge(verifier))
+	if err != nil {
+		return err
+	}
+
+	if err := server.OAuth.OpenURL(authURL); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("error wait authorization, %w", ctx.Err())
+	case result := <-callbacks:
+		if result.err != nil {
+			return result.err
+		}
+
+		return handler.ProcessAuthorizationResponse(ctx, result.code, state, verifier)
+	}
+}
+
+type oauthState struct {
+	ClientID     string               `json:"client_id,omitempty"`
+	ClientSecret string               `json:"client_secret,omitempty"`
+	Token        *mcp_transport.Token `json:"token,omitempty"`
+}
+
+// tokenStore keeps the tokens of a server, in a file readable only by the
+// user when path is set.
+type tokenStore struct {
+	mu    sync.Mutex
+	path  string
+	state oauthState
+}
+
+var _ mcp_transport.TokenStore = (*tokenStore)(nil)
+
+func newTokenStore(path string) (*tokenStore, error) {
+	store := &tokenStore{path: path}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error read token file %s, %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, fmt.Errorf("error parse token file %s, %w", path, err)
+	}
+
+	return store, nil
+}
+
+func (s *tokenStore) GetToken(ctx context.Context) (*mcp_transport.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.Token == nil {
+		return nil, mcp_transport.ErrNoToken
+	}
+
+	return s.state.Token, nil
+}
+
+func (s *tokenStore) SaveToken(ctx context.Context, token *mcp_transport.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Token = token
+
+	return s.save()
+}
+
+func (s *tokenStore) SaveClient(clientID, clientSecret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.ClientID = clientID
+	s.state.ClientSecret = clientSecret
+
+	return s.save()
+}
+
+// save writes the file atomically, the caller holds mu.
+func (s *tokenStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("error create token dir, %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("error write token file %s, %w", s.path, err)
+	}
+
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,244 @@
+package mcp_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+	mcp_server "github.com/mark3labs/mcp-go/server"
+)
+
+// fakeAuthServer is an authorization server with dynamic registration, PKCE
+// and refresh tokens.
+type fakeAuthServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	challenge string
+	tokens    map[string]bool
+	issued    int
+}
+
+func newFakeAuthServer(t *testing.T) *fakeAuthServer {
+	t.Helper()
+
+	auth := &fakeAuthServer{tokens: map[string]bool{}}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                   auth.URL,
+			"authorization_endpoint":   auth.URL + "/authorize",
+			"token_endpoint":           auth.URL + "/token",
+			"registration_endpoint":    auth.URL + "/register",
+			"response_types_supported": []string{"code"},
+		})
+	})
+
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"client_id": "client-1"})
+	})
+
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		if query.Get("client_id") != "client-1" || query.Get("code_challenge_method") != "S256" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		auth.mu.Lock()
+		auth.challenge = query.Get("code_challenge")
+		auth.mu.Unlock()
+
+		redirect, _ := url.Parse(query.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {"code-1"}, "state": {query.Get("state")}}.Encode()
+
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		auth.mu.Lock()
+		defer auth.mu.Unlock()
+
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "code-1" || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+			return
+		}
+
+		auth.issued++
+		token := "access-" + r.Form.Get("grant_type")
+		auth.tokens[token] = true
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  token,
+			"token_type":    "Bearer",
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+		})
+	})
+
+	auth.Server = httptest.NewServer(mux)
+	t.Cleanup(auth.Close)
+
+	return auth
+}
+
+// protect serves an MCP server accepting only the tokens of auth.
+func (auth *fakeAuthServer) protect(t *testing.T) string {
+	t.Helper()
+
+	server := mcp_server.NewMCPServer("test", "0.0.1")
+	server.AddTool(mcp_tool.NewTool("echo"), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+		return mcp_tool.NewToolResultText("echo"), nil
+	})
+
+	handler := mcp_server.NewStreamableHTTPServer(server)
+
+	var resource *httptest.Server
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"resource":              resource.URL,
+			"authorization_servers": []string{auth.URL},
+		})
+	})
+
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		auth.mu.Lock()
+		valid := len(r.Header.Get("Authorization")) > 7 && auth.tokens[r.Header.Get("Authorization")[7:]]
+		auth.mu.Unlock()
+
+		if !valid {
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+resource.URL+`/.well-known/oauth-protected-resource"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+
+	resource = httptest.NewServer(mux)
+	t.Cleanup(resource.Close)
+
+	return resource.URL + "/mcp"
+}
+
+func freeRedirectURI(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	return "http://" + lis.Addr().String() + "/callback"
+}
+
+func TestMCPServerOAuth(t *testing.T) {
+	auth := newFakeAuthServer(t)
+	serverURL := auth.protect(t)
+
+	tokenFile := filepath.Join(t.TempDir(), "oauth", "test.json")
+
+	// The browser follows the redirect to the local callback
+	browser := func(authURL string) error {
+		resp, err := http.Get(authURL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	newServer := func(openURL func(string) error) *mcp.MCPServer {
+		server, err := mcp.NewMCPServer(context.Background(), "test", mcp.TRANSPORT_HTTP, serverURL, "", nil, nil,
+			mcp.WithOAuth(mcp.OAuth{
+				RedirectURI:          freeRedirectURI(t),
+				TokenFile:            tokenFile,
+				OpenURL:              openURL,
+				AuthorizationTimeout: 5 * time.Second,
+			}),
+		)
+		require.NoError(t, err)
+
+		return server
+	}
+
+	t.Run("authorize with registration and pkce", func(t *testing.T) {
+		server := newServer(browser)
+		defer server.Close()
+
+		require.NoError(t, server.Start())
+
+		result, err := server.CallTool(context.Background(), "echo", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "echo", mcp.Result(result.Content).FirstText())
+
+		info, err := os.Stat(tokenFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		data, err := os.ReadFile(tokenFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"client_id": "client-1"`)
+	})
+
+	t.Run("refresh stored tokens", func(t *testing.T) {
+		data, err := os.ReadFile(tokenFile)
+		require.NoError(t, err)
+
+		stored := map[string]any{}
+		require.NoError(t, json.Unmarshal(data, &stored))
+
+		// Expire the stored access token
+		stored["token"].(map[string]any)["expires_at"] = time.Now().Add(-time.Minute).Format(time.RFC3339)
+
+		data, err = json.Marshal(stored)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(tokenFile, data, 0o600))
+
+		server := newServer(func(string) error {
+			t.Error("authorization not expected with a refresh token")
+			return nil
+		})
+		defer server.Close()
+
+		require.NoError(t, server.Start())
+
+		data, err = os.ReadFile(tokenFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "access-refresh_token")
+	})
+}
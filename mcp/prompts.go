@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (server *MCPServer) ListPrompts() ([]mcp.Prompt, error) {
+	cli, err := server.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := cli.ListPrompts(server.ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		server.Check()
+		return nil, fmt.Errorf("error list prompts mcp server %s, %w", server.Name, err)
+	}
+
+	return result.Prompts, nil
+}
+
+func (server *MCPServer) GetPrompt(name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	cli, err := server.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+
+	result, err := cli.GetPrompt(server.ctx, request)
+	if err != nil {
+		server.Check()
+		return nil, fmt.Errorf("error get prompt %s mcp server %s, %w", name, server.Name, err)
+	}
+
+	return result, nil
+}
+
+// PromptText joins the text of the prompt messages.
+func PromptText(prompt *mcp.GetPromptResult) string {
+	texts := []string{}
+
+	for _, message := range prompt.Messages {
+		if ok, text := GetText(message.Content); ok {
+			texts = append(texts, text)
+		}
+	}
+
+	return strings.Join(texts, "\n\n")
+}
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (server *MCPServer) ListResources() ([]mcp.Resource, error) {
+	cli, err := server.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers with only tools or prompts
+	if cli.GetServerCapabilities().Resources == nil {
+		return []mcp.Resource{}, nil
+	}
+
+	result, err := cli.ListResources(server.ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		server.Check()
+		return nil, fmt.Errorf("error list resources mcp server %s, %w", server.Name, err)
+	}
+
+	return result.Resources, nil
+}
+
+func (server *MCPServer) ListResourceTemplates() ([]mcp.ResourceTemplate, error) {
+	cli, err := server.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := cli.ListResourceTemplates(server.ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		server.Check()
+		return nil, fmt.Errorf("error list resource templates mcp server %s, %w", server.Name, err)
+	}
+
+	return result.ResourceTemplates, nil
+}
+
+// ReadResource reads a resource, the uri may be an expanded resource template.
+func (server *MCPServer) ReadResource(uri string) ([]mcp.ResourceContents, error) {
+	cli, err := server.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+
+	result, err := cli.ReadResource(server.ctx, request)
+	if err != nil {
+		server.Check()
+		return nil, fmt.Errorf("error read resource %s mcp server %s, %w", uri, server.Name, err)
+	}
+
+	return result.Contents, nil
+}
+
+// Subscribe asks the server for notifications when the resource changes, the
+// subscription survives reconnections. Use OnResourceUpdated to receive them.
+func (server *MCPServer) Subscribe(uri string) error {
+	cli, err := server.getClient()
+	if err != nil {
+		return err
+	}
+
+	request := mcp.SubscribeRequest{}
+	request.Params.URI = uri
+
+	if err := cli.Subscribe(server.ctx, request); err != nil {
+		return fmt.Errorf("error subscribe resource %s mcp server %s, %w", uri, server.Name, err)
+	}
+
+	server.mu.Lock()
+	server.subscriptions[uri] = struct{}{}
+	server.mu.Unlock()
+
+	return nil
+}
+
+func (server *MCPServer) Unsubscribe(uri string) error {
+	server.mu.Lock()
+	delete(server.subscriptions, uri)
+	server.mu.Unlock()
+
+	cli, err := server.getClient()
+	if err != nil {
+		return err
+	}
+
+	request := mcp.UnsubscribeRequest{}
+	request.Params.URI = uri
+
+	if err := cli.Unsubscribe(server.ctx, request); err != nil {
+		return fmt.Errorf("error unsubscribe resource %s mcp server %s, %w", uri, server.Name, err)
+	}
+
+	return nil
+}
+
+// OnResourceUpdated registers a listener called with the uri of every
+// subscribed resource the server reports as updated.
+func (server *MCPServer) OnResourceUpdated(listener func(uri string)) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.resourceListeners = append(server.resourceListeners, listener)
+}
+
+func (server *MCPServer) resourceUpdated(uri string) {
+	server.mu.RLock()
+	listeners := server.resourceListeners
+	server.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(uri)
+	}
+}
+
+// resubscribe renews the subscriptions on a new connection.
+func (server *MCPServer) resubscribe() {
+	server.mu.RLock()
+	uris := []string{}
+	for uri := range server.subscriptions {
+		uris = append(uris, uri)
+	}
+	server.mu.RUnlock()
+
+	for _, uri := range uris {
+		if err := server.Subscribe(uri); err != nil {
+			server.Logger.Error("error renew subscription", "error", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EnvMode selects the environment variables of the agent inherited by stdio
+// servers, the variables of the server configuration are always set.
+type EnvMode string
+
+const (
+	ENV_NONE      EnvMode = "none"
+	ENV_ALLOWLIST EnvMode = "allowlist"
+	ENV_FULL      EnvMode = "full"
+)
+
+// DEFAULT_ENV_ALLOWLIST are the variables inherited in ENV_ALLOWLIST mode,
+// enough to find and run commands as npx or uvx.
+var DEFAULT_ENV_ALLOWLIST = []string{
+	"HOME", "LOGNAME", "USER", "PATH", "SHELL", "TERM", "TMPDIR", "LANG", "LC_*", "TZ",
+}
+
+// Sandbox restricts the process of stdio servers, so untrusted servers run
+// with less access to the agent host. Zero values keep each restriction off,
+// Env defaults to ENV_FULL.
+type Sandbox struct {
+	// Working directory, the one of the agent when empty
+	Dir string
+
+	Env EnvMode
+	// Names or globs of the variables inherited in ENV_ALLOWLIST mode, added
+	// to DEFAULT_ENV_ALLOWLIST
+	EnvAllowlist []string
+
+	// Resource limits of the process and its children
+	CPUTime   time.Duration
+	MemoryMB  int
+	OpenFiles int
+
+	// Linux namespaces the process runs in: user, pid, ipc, uts, net and
+	// cgroup. A user namespace is added when the agent is not root.
+	Namespaces []string
+}
+
+func WithSandbox(sandbox Sandbox) func(*MCPServer) {
+	return func(server *MCPServer) {
+		server.Sandbox = sandbox
+	}
+}
+
+func (s Sandbox) validate() error {
+	switch s.Env {
+	case "", ENV_NONE, ENV_ALLOWLIST, ENV_FULL:
+	default:
+		return fmt.Errorf("error sandbox, unknown env mode %s", s.Env)
+	}
+
+	if s.CPUTime < 0 || s.MemoryMB < 0 || s.OpenFiles < 0 {
+		return fmt.Errorf("error sandbox, negative resource limit")
+	}
+
+	_, err := s.sysProcAttr()
+
+	return err
+}
+
+// command builds the process of a stdio server inside the sandbox.
+func (s Sandbox) command(ctx context.Context, command string, env []string, args []string) (*exec.Cmd, error) {
+	if limits := s.limits(); limits != "" {
+		// The shell sets the limits and is replaced by the server
+		args = append([]string{"-c", limits + `exec "$@"`, "mcp-sandbox", command}, args...)
+		command = "/bin/sh"
+	}
+
+	attr, err := s.sysProcAttr()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = s.Dir
+	cmd.Env = append(s.environ(), env...)
+	cmd.SysProcAttr = attr
+
+	return cmd, nil
+}
+
+// limits returns the ulimit commands of the resource limits, setting both the
+// soft and hard limits so the server can not raise them.
+func (s Sandbox) limits() string {
+	var limits strings.Builder
+
+	if s.CPUTime > 0 {
+		fmt.Fprintf(&limits, "ulimit -t %d || exit 126; ", max(int(s.CPUTime.Seconds()), 1))
+	}
+
+	if s.MemoryMB > 0 {
+		fmt.Fprintf(&limits, "ulimit -v %d || exit 126; ", s.MemoryMB*1024)
+	}
+
+	if s.OpenFiles > 0 {
+		fmt.Fprintf(&limits, "ulimit -n %d || exit 126; ", s.OpenFiles)
+	}
+
+	return limits.String()
+}
+
+func (s Sandbox) environ() []string {
+	switch s.Env {
+	case "", ENV_FULL:
+		return os.Environ()
+	case ENV_NONE:
+		return []string{}
+	}
+
+	allowlist := append(slices.Clone(DEFAULT_ENV_ALLOWLIST), s.EnvAllowlist...)
+
+	environ := []string{}
+
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+
+		if slices.ContainsFunc(allowlist, func(pattern string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		}) {
+			environ = append(environ, variable)
+		}
+	}
+
+	return environ
+}
+
+// logStderr forwards the stderr of a stdio server to the logger until the
+// process exits.
+func (server *MCPServer) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+
+	for scanner.Scan() {
+		server.Logger.Info(scanner.Text(), slog.String("stream", "stderr"))
+	}
+
+	// Keep reading after a line too long, a full pipe blocks the server
+	io.Copy(io.Discard, stderr)
+}
@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+var namespaces = map[string]uintptr{
+	"user":   syscall.CLONE_NEWUSER,
+	"pid":    syscall.CLONE_NEWPID,
+	"ipc":    syscall.CLONE_NEWIPC,
+	"uts":    syscall.CLONE_NEWUTS,
+	"net":    syscall.CLONE_NEWNET,
+	"cgroup": syscall.CLONE_NEWCGROUP,
+}
+
+func (s Sandbox) sysProcAttr() (*syscall.SysProcAttr, error) {
+	if len(s.Namespaces) == 0 {
+		return nil, nil
+	}
+
+	var flags uintptr
+
+	for _, name := range s.Namespaces {
+		flag, ok := namespaces[name]
+		if !ok {
+			return nil, fmt.Errorf("error sandbox, unknown namespace %s", name)
+		}
+
+		flags |= flag
+	}
+
+	attr := &syscall.SysProcAttr{}
+
+	// Only root creates namespaces outside a user namespace, the process
+	// keeps the ids of the agent inside it
+	if os.Getuid() != 0 {
+		flags |= syscall.CLONE_NEWUSER
+	}
+
+	if flags&syscall.CLONE_NEWUSER != 0 {
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+
+	attr.Cloneflags = flags
+
+	return attr, nil
+}
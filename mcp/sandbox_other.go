@@ -0,0 +1,17 @@
+//go:build !linux
+
+package mcp
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+func (s Sandbox) sysProcAttr() (*syscall.SysProcAttr, error) {
+	if len(s.Namespaces) > 0 {
+		return nil, fmt.Errorf("error sandbox, namespaces are not supported on %s", runtime.GOOS)
+	}
+
+	return nil, nil
+}
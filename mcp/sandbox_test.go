@@ -0,0 +1,169 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+	mcp_server "github.com/mark3labs/mcp-go/server"
+)
+
+// TestMain runs the test binary as a stdio MCP server reporting its process
+// when MCP_TEST_STDIO_SERVER is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("MCP_TEST_STDIO_SERVER") == "" {
+		os.Exit(m.Run())
+	}
+
+	server := mcp_server.NewMCPServer("process", "0.0.1")
+
+	report := func(name string, value func() string) {
+		server.AddTool(mcp_tool.NewTool(name), func(ctx context.Context, request mcp_tool.CallToolRequest) (*mcp_tool.CallToolResult, error) {
+			return mcp_tool.NewToolResultText(value()), nil
+		})
+	}
+
+	report("env", func() string { return strings.Join(os.Environ(), "\n") })
+	report("cwd", func() string { dir, _ := os.Getwd(); return dir })
+	report("pid", func() string { return fmt.Sprint(os.Getpid()) })
+	report("limits", func() string { limits, _ := os.ReadFile("/proc/self/limits"); return string(limits) })
+
+	fmt.Fprintln(os.Stderr, "process server ready")
+
+	mcp_server.ServeStdio(server)
+}
+
+func startProcessServer(t *testing.T, sandbox mcp.Sandbox, logger *slog.Logger) *mcp.MCPServer {
+	t.Helper()
+
+	server, err := mcp.NewMCPServer(context.Background(), "process", mcp.TRANSPORT_STDIO, "", os.Args[0],
+		map[string]string{"mcp_test_stdio_server": "1"}, nil,
+		mcp.WithSandbox(sandbox),
+		mcp.WithStartupTimeout(10*time.Second),
+	)
+	require.NoError(t, err)
+
+	if logger != nil {
+		server.Logger = logger
+	}
+
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { server.Close() })
+
+	return server
+}
+
+// syncBuffer is a buffer written by the logger while the test reads it.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.String()
+}
+
+func callText(t *testing.T, server *mcp.MCPServer, tool string) string {
+	t.Helper()
+
+	result, err := server.CallTool(context.Background(), tool, nil)
+	require.NoError(t, err)
+
+	return mcp.Result(result.Content).FirstText()
+}
+
+func TestSandbox(t *testing.T) {
+	t.Setenv("MCP_TEST_SECRET", "secret")
+	t.Setenv("MCP_TEST_ALLOWED", "allowed")
+
+	t.Run("env modes", func(t *testing.T) {
+		full := callText(t, startProcessServer(t, mcp.Sandbox{Env: mcp.ENV_FULL}, nil), "env")
+		assert.Contains(t, full, "MCP_TEST_SECRET=secret")
+
+		inherited := callText(t, startProcessServer(t, mcp.Sandbox{}, nil), "env")
+		assert.Contains(t, inherited, "MCP_TEST_SECRET=secret", "full by default")
+
+		allowlist := callText(t, startProcessServer(t, mcp.Sandbox{Env: mcp.ENV_ALLOWLIST, EnvAllowlist: []string{"MCP_TEST_ALLOW*"}}, nil), "env")
+		assert.NotContains(t, allowlist, "MCP_TEST_SECRET")
+		assert.Contains(t, allowlist, "MCP_TEST_ALLOWED=allowed")
+		assert.Contains(t, allowlist, "PATH=")
+
+		none := callText(t, startProcessServer(t, mcp.Sandbox{Env: mcp.ENV_NONE}, nil), "env")
+		assert.Equal(t, "MCP_TEST_STDIO_SERVER=1", none)
+	})
+
+	t.Run("working directory", func(t *testing.T) {
+		dir := t.TempDir()
+
+		cwd := callText(t, startProcessServer(t, mcp.Sandbox{Dir: dir}, nil), "cwd")
+
+		expected, _ := filepath.EvalSymlinks(dir)
+		assert.Equal(t, expected, cwd)
+	})
+
+	t.Run("stderr is logged", func(t *testing.T) {
+		var logs syncBuffer
+
+		startProcessServer(t, mcp.Sandbox{}, slog.New(slog.NewTextHandler(&logs, nil)))
+
+		assert.Eventually(t, func() bool {
+			return strings.Contains(logs.String(), "process server ready")
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("resource limits", func(t *testing.T) {
+		if _, err := os.Stat("/proc/self/limits"); err != nil {
+			t.Skip("resource limits are read from /proc")
+		}
+
+		limits := callText(t, startProcessServer(t, mcp.Sandbox{CPUTime: time.Minute, MemoryMB: 4096, OpenFiles: 64}, nil), "limits")
+
+		assert.Regexp(t, `Max cpu time\s+60\s+60`, limits)
+		assert.Regexp(t, `Max address space\s+4294967296\s+4294967296`, limits)
+		assert.Regexp(t, `Max open files\s+64\s+64`, limits)
+	})
+
+	t.Run("unknown namespace", func(t *testing.T) {
+		_, err := mcp.NewMCPServer(context.Background(), "process", mcp.TRANSPORT_STDIO, "", os.Args[0], nil, nil,
+			mcp.WithSandbox(mcp.Sandbox{Namespaces: []string{"time"}}),
+		)
+		assert.ErrorContains(t, err, "unknown namespace time")
+	})
+
+	t.Run("pid namespace", func(t *testing.T) {
+		server, err := mcp.NewMCPServer(context.Background(), "process", mcp.TRANSPORT_STDIO, "", os.Args[0],
+			map[string]string{"mcp_test_stdio_server": "1"}, nil,
+			mcp.WithSandbox(mcp.Sandbox{Namespaces: []string{"pid"}}),
+		)
+		if err != nil {
+			t.Skipf("namespaces not supported, %s", err)
+		}
+		defer server.Close()
+
+		if err := server.Start(); err != nil {
+			t.Skipf("namespaces not permitted, %s", err)
+		}
+
+		assert.Equal(t, "1", callText(t, server, "pid"))
+	})
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxToolPages bounds the pagination of tools/list against servers that keep
+// returning cursors.
+const maxToolPages = 100
+
+// listTools follows the cursors of tools/list until the last page.
+func listTools(ctx context.Context, cli *client.Client) ([]mcp.Tool, error) {
+	tools := []mcp.Tool{}
+
+	// Servers with only resources or prompts
+	if cli.GetServerCapabilities().Tools == nil {
+		return tools, nil
+	}
+	seen := map[mcp.Cursor]struct{}{}
+
+	request := mcp.ListToolsRequest{}
+
+	for range maxToolPages {
+		page, err := cli.ListToolsByPage(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		tools = append(tools, page.Tools...)
+
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+
+		if _, ok := seen[page.NextCursor]; ok {
+			return nil, fmt.Errorf("error list tools, cursor %s repeated", page.NextCursor)
+		}
+
+		seen[page.NextCursor] = struct{}{}
+		request.Params.Cursor = page.NextCursor
+	}
+
+	return nil, fmt.Errorf("error list tools, more than %d pages", maxToolPages)
+}
+
+// RefreshTools lists the tools again and publishes them to the listeners
+// registered with OnToolsChanged.
+func (server *MCPServer) RefreshTools() ([]mcp.Tool, error) {
+	server.refreshMu.Lock()
+	defer server.refreshMu.Unlock()
+
+	tools, err := server.ListTools()
+	if err != nil {
+		return nil, err
+	}
+
+	server.mu.Lock()
+	server.tools = tools
+	listeners := server.toolsListeners
+	server.mu.Unlock()
+
+	server.Logger.Info("mcp server tools refreshed", "tools", len(tools))
+
+	for _, listener := range listeners {
+		listener(tools)
+	}
+
+	return tools, nil
+}
+
+// toolsChanged handles notifications/tools/list_changed sent by cli, ignoring
+// the ones of replaced connections.
+func (server *MCPServer) toolsChanged(cli *client.Client) {
+	server.mu.RLock()
+	current := server.client == cli
+	server.mu.RUnlock()
+
+	if !current {
+		return
+	}
+
+	if _, err := server.RefreshTools(); err != nil {
+		server.Logger.Error("error refresh tools", "error", err)
+	}
+}
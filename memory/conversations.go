@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DEFAULT_IDLE_TIMEOUT is the time a conversation is kept after its last
+// message.
+const DEFAULT_IDLE_TIMEOUT = 30 * time.Minute
+
+// Conversations keeps a memory per conversation id, as the A2A context id, so
+// the callers of an agent never share their history. Conversations without
+// messages for longer than IdleTimeout are forgotten.
+type Conversations struct {
+	IdleTimeout time.Duration
+
+	mu            sync.Mutex
+	conversations map[string]*Memory
+	owners        map[string]string
+}
+
+func NewConversations(idleTimeout time.Duration) *Conversations {
+	if idleTimeout <= 0 {
+		idleTimeout = DEFAULT_IDLE_TIMEOUT
+	}
+
+	return &Conversations{
+		IdleTimeout:   idleTimeout,
+		conversations: map[string]*Memory{},
+		owners:        map[string]string{},
+	}
+}
+
+// Get returns the memory of the conversation, a new one when it does not
+// exist or expired.
+func (c *Conversations) Get(id string) *Memory {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(id)
+}
+
+// Open returns the memory of the conversation like Get, binding it to owner,
+// the principal of its first message. It reports false, with no memory, for
+// the conversations of other owners.
+func (c *Conversations) Open(id, owner string) (*Memory, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	memory := c.get(id)
+
+	if current, ok := c.owners[id]; ok && current != owner {
+		return nil, false
+	}
+
+	c.owners[id] = owner
+
+	return memory, true
+}
+
+func (c *Conversations) get(id string) *Memory {
+	for key, memory := range c.conversations {
+		if time.Since(memory.LastUsed()) > c.IdleTimeout {
+			delete(c.conversations, key)
+			delete(c.owners, key)
+		}
+	}
+
+	memory, ok := c.conversations[id]
+	if !ok {
+		memory = new(Memory)
+		c.conversations[id] = memory
+	}
+
+	memory.touch()
+
+	return memory
+}
+
+// Delete forgets the conversation.
+func (c *Conversations) Delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.conversations, id)
+	delete(c.owners, id)
+}
+
+// Len returns the number of conversations not expired.
+func (c *Conversations) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+
+	for _, memory := range c.conversations {
+		if time.Since(memory.LastUsed()) <= c.IdleTimeout {
+			count++
+		}
+	}
+
+	return count
+}
+
+type memoryKey struct{}
+
+// WithMemory sets the memory of the conversation of the call, models keep
+// the history there instead of in their own memory.
+func WithMemory(ctx context.Context, memory *Memory) context.Context {
+	return context.WithValue(ctx, memoryKey{}, memory)
+}
+
+func FromContext(ctx context.Context) (*Memory, bool) {
+	memory, ok := ctx.Value(memoryKey{}).(*Memory)
+	return memory, ok && memory != nil
+}
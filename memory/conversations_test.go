@@ -0,0 +1,53 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversations(t *testing.T) {
+	conversations := memory.NewConversations(50 * time.Millisecond)
+
+	first := conversations.Get("context-1")
+	first.Append("hello")
+
+	assert.Same(t, first, conversations.Get("context-1"))
+	assert.Empty(t, conversations.Get("context-2").Get())
+	assert.Equal(t, 2, conversations.Len())
+
+	// Messages keep the conversation
+	assert.Eventually(t, func() bool {
+		first.Append("again")
+		return conversations.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return conversations.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Empty(t, conversations.Get("context-1").Get(), "idle conversations expire")
+
+	// Conversations are bound to the principal of their first message
+	owned, ok := conversations.Open("context-3", "alice")
+	assert.True(t, ok)
+
+	again, ok := conversations.Open("context-3", "alice")
+	assert.True(t, ok)
+	assert.Same(t, owned, again)
+
+	_, ok = conversations.Open("context-3", "bob")
+	assert.False(t, ok)
+
+	ctx := memory.WithMemory(context.Background(), first)
+
+	found, ok := memory.FromContext(ctx)
+	assert.True(t, ok)
+	assert.Same(t, first, found)
+
+	_, ok = memory.FromContext(context.Background())
+	assert.False(t, ok)
+}
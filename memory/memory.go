@@ -1,27 +1,68 @@
 package memory
 
-import "slices"
+import (
+	"slices"
+	"sync"
+	"time"
+)
 
+// Memory keeps the messages of a conversation, it is safe for concurrent use.
 type Memory struct {
-	history []any
+	mu       sync.Mutex
+	history  []any
+	lastUsed time.Time
 }
 
 func (m *Memory) Extend(messages []any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.history = append(m.history, messages...)
+	m.lastUsed = time.Now()
 }
 
 func (m *Memory) Set(messages []any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.history = slices.Clone(messages)
+	m.lastUsed = time.Now()
 }
 
 func (m *Memory) Append(message any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.history = append(m.history, message)
+	m.lastUsed = time.Now()
+}
+
+// LastUsed returns the time of the last change of the messages, or of the
+// last Conversations.Get of the memory.
+func (m *Memory) LastUsed() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastUsed
 }
 
-func (m Memory) Get() []any {
+func (m *Memory) touch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastUsed = time.Now()
+}
+
+func (m *Memory) Get() []any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return slices.Clone(m.history)
 }
 
 func (m *Memory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.history = make([]any, 0)
 }
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+type ApprovalPolicy string
+
+const (
+	APPROVAL_ALWAYS ApprovalPolicy = "always"
+	APPROVAL_NEVER  ApprovalPolicy = "never"
+	APPROVAL_ASK    ApprovalPolicy = "ask"
+)
+
+// ApprovalRequest describes a tool call waiting for a decision.
+type ApprovalRequest struct {
+	ToolCallID string         `json:"tool_call_id"`
+	Tool       string         `json:"tool"`
+	Path       string         `json:"path,omitempty"`
+	Arguments  map[string]any `json:"arguments"`
+	// The tool may destroy data, see Destructive
+	Destructive bool `json:"destructive,omitempty"`
+}
+
+// ApprovalDecision is the answer to an ApprovalRequest. When Arguments is not
+// nil the tool is called with them instead of the arguments from the model.
+type ApprovalDecision struct {
+	Approved  bool           `json:"approved"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+}
+
+// Approver decides whether a tool call may run. Implementations may block
+// until a human answers, the context is cancelled when the caller gives up.
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+}
+
+type ApproverFunc func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+
+func (f ApproverFunc) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	return f(ctx, req)
+}
+
+// ApprovalPolicies holds the policy of each tool, keyed by tool path or glob
+// pattern as in Filter. Tools not matched use Destructive when they are
+// destructive and it is set, Default otherwise.
+type ApprovalPolicies struct {
+	Default     ApprovalPolicy
+	Destructive ApprovalPolicy
+	Tools       map[string]ApprovalPolicy
+}
+
+func (p ApprovalPolicies) Policy(toolPath string) ApprovalPolicy {
+	if policy, ok := Lookup(p.Tools, toolPath); ok {
+		return policy
+	}
+
+	if p.Default == "" {
+		return APPROVAL_ALWAYS
+	}
+
+	return p.Default
+}
+
+// policy returns the policy of the request, Destructive applies to the
+// destructive tools without a policy of their own.
+func (p ApprovalPolicies) policy(req ApprovalRequest, toolPath string) ApprovalPolicy {
+	if _, ok := Lookup(p.Tools, toolPath); !ok && req.Destructive && p.Destructive != "" {
+		return p.Destructive
+	}
+
+	return p.Policy(toolPath)
+}
+
+// Approver applies the policies and delegates the tools marked as ask to next.
+func (p ApprovalPolicies) Approver(next Approver) Approver {
+	return ApproverFunc(func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+		toolPath := req.Path
+		if toolPath == "" {
+			toolPath = req.Tool
+		}
+
+		switch policy := p.policy(req, toolPath); policy {
+		case APPROVAL_ALWAYS:
+			return ApprovalDecision{Approved: true}, nil
+		case APPROVAL_NEVER:
+			return ApprovalDecision{Reason: fmt.Sprintf("tool %s is not allowed", req.Tool)}, nil
+		case APPROVAL_ASK:
+			if next == nil {
+				return ApprovalDecision{Reason: fmt.Sprintf("tool %s requires approval and no approver is configured", req.Tool)}, nil
+			}
+
+			return next.Approve(ctx, req)
+		default:
+			return ApprovalDecision{}, fmt.Errorf("unknown approval policy %s for tool %s", policy, req.Tool)
+		}
+	})
+}
+
+type approverKey struct{}
+
+type policiesKey struct{}
+
+// WithApprover returns a copy of ctx carrying the approver asked by the tool
+// loop of the LLM for the tools with policy ask.
+func WithApprover(ctx context.Context, approver Approver) context.Context {
+	return context.WithValue(ctx, approverKey{}, approver)
+}
+
+func ApproverFromContext(ctx context.Context) Approver {
+	approver, _ := ctx.Value(approverKey{}).(Approver)
+	return approver
+}
+
+// WithApprovalPolicies returns a copy of ctx carrying the policies of the
+// agent running the tool loop. Agents called as tools replace them with their
+// own while keeping the approver.
+func WithApprovalPolicies(ctx context.Context, policies ApprovalPolicies) context.Context {
+	return context.WithValue(ctx, policiesKey{}, policies)
+}
+
+// Approve applies the policies in ctx and asks the approver in ctx for the
+// tools with policy ask. Without policies every call is approved.
+func Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	policies, _ := ctx.Value(policiesKey{}).(ApprovalPolicies)
+
+	return policies.Approver(ApproverFromContext(ctx)).Approve(ctx, req)
+}
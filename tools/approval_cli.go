@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// CLIApprover prompts in a terminal for every tool call that needs approval,
+// and for the information MCP servers elicit.
+type CLIApprover struct {
+	mu  sync.Mutex
+	in  *bufio.Reader
+	out io.Writer
+
+	// One reader of in for every prompt, the lines of cancelled prompts are
+	// kept for the next one
+	reading sync.Once
+	lines   chan line
+}
+
+type line struct {
+	text string
+	err  error
+}
+
+var _ Approver = (*CLIApprover)(nil)
+
+func NewCLIApprover(in io.Reader, out io.Writer) *CLIApprover {
+	return &CLIApprover{
+		in:    bufio.NewReader(in),
+		out:   out,
+		lines: make(chan line),
+	}
+}
+
+func (c *CLIApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	// Parallel tool calls must not interleave their prompts
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	args, err := json.MarshalIndent(req.Arguments, "", "  ")
+	if err != nil {
+		return ApprovalDecision{}, fmt.Errorf("error marshal arguments of tool %s, %w", req.Tool, err)
+	}
+
+	warning := ""
+	if req.Destructive {
+		warning = " (may destroy data)"
+	}
+
+	fmt.Fprintf(c.out, "\nTool %s%s wants to run with arguments:\n%s\n", req.Tool, warning, args)
+
+	for {
+		fmt.Fprint(c.out, "Allow? [y]es / [n]o / [e]dit arguments: ")
+
+		answer, err := c.readLine(ctx)
+		if err != nil {
+			return ApprovalDecision{}, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return ApprovalDecision{Approved: true}, nil
+		case "", "n", "no":
+			fmt.Fprint(c.out, "Reason (optional): ")
+
+			reason, err := c.readLine(ctx)
+			if err != nil {
+				return ApprovalDecision{}, err
+			}
+
+			if reason == "" {
+				reason = "denied by the user"
+			}
+
+			return ApprovalDecision{Reason: reason}, nil
+		case "e", "edit":
+			fmt.Fprint(c.out, "New arguments (JSON, one line): ")
+
+			line, err := c.readLine(ctx)
+			if err != nil {
+				return ApprovalDecision{}, err
+			}
+
+			var edited map[string]any
+			if err := json.Unmarshal([]byte(line), &edited); err != nil {
+				fmt.Fprintf(c.out, "invalid JSON, %s\n", err)
+				continue
+			}
+
+			return ApprovalDecision{Approved: true, Arguments: edited}, nil
+		}
+	}
+}
+
+func (c *CLIApprover) Elicit(ctx context.Context, req mcp_tool.ElicitationRequest) (*mcp_tool.ElicitationResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	schema, err := json.MarshalIndent(req.Params.RequestedSchema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal requested schema, %w", err)
+	}
+
+	fmt.Fprintf(c.out, "\n%s\nExpected answer:\n%s\n", req.Params.Message, schema)
+
+	for {
+		fmt.Fprint(c.out, "Answer (JSON, one line) / [d]ecline / [c]ancel: ")
+
+		answer, err := c.readLine(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "d", "decline":
+			return elicitationResult(mcp_tool.ElicitationResponseActionDecline, nil), nil
+		case "", "c", "cancel":
+			return elicitationResult(mcp_tool.ElicitationResponseActionCancel, nil), nil
+		}
+
+		var content map[string]any
+		if err := json.Unmarshal([]byte(answer), &content); err != nil {
+			fmt.Fprintf(c.out, "invalid JSON, %s\n", err)
+			continue
+		}
+
+		return elicitationResult(mcp_tool.ElicitationResponseActionAccept, content), nil
+	}
+}
+
+func elicitationResult(action mcp_tool.ElicitationResponseAction, content map[string]any) *mcp_tool.ElicitationResult {
+	result := &mcp_tool.ElicitationResult{}
+	result.Action = action
+
+	if content != nil {
+		result.Content = content
+	}
+
+	return result
+}
+
+// read sends the lines of in until it fails.
+func (c *CLIApprover) read() {
+	for {
+		text, err := c.in.ReadString('\n')
+		c.lines <- line{strings.TrimSpace(text), err}
+
+		if err != nil {
+			close(c.lines)
+			return
+		}
+	}
+}
+
+func (c *CLIApprover) readLine(ctx context.Context) (string, error) {
+	c.reading.Do(func() { go c.read() })
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case l, ok := <-c.lines:
+		if !ok {
+			return "", fmt.Errorf("error read approval answer, %w", io.EOF)
+		}
+
+		if l.err != nil && (l.err != io.EOF || l.text == "") {
+			return "", fmt.Errorf("error read approval answer, %w", l.err)
+		}
+
+		return l.text, nil
+	}
+}
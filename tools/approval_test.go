@@ -0,0 +1,130 @@
+package tools_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jlrosende/go-agents/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovalPolicies(t *testing.T) {
+	policies := tools.ApprovalPolicies{
+		Tools: map[string]tools.ApprovalPolicy{
+			"write_file":  tools.APPROVAL_ASK,
+			"delete_file": tools.APPROVAL_NEVER,
+		},
+	}
+
+	asked := []string{}
+
+	approver := policies.Approver(tools.ApproverFunc(func(ctx context.Context, req tools.ApprovalRequest) (tools.ApprovalDecision, error) {
+		asked = append(asked, req.Tool)
+		return tools.ApprovalDecision{Approved: true, Arguments: map[string]any{"path": "safe.txt"}}, nil
+	}))
+
+	t.Run("default policy is always", func(t *testing.T) {
+		decision, err := approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "read_file"})
+
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+		assert.Empty(t, asked)
+	})
+
+	t.Run("never denies without asking", func(t *testing.T) {
+		decision, err := approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "delete_file"})
+
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Empty(t, asked)
+	})
+
+	t.Run("ask delegates to the approver", func(t *testing.T) {
+		decision, err := approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "write_file"})
+
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, "safe.txt", decision.Arguments["path"])
+		assert.Equal(t, []string{"write_file"}, asked)
+	})
+
+	t.Run("ask without approver denies", func(t *testing.T) {
+		decision, err := policies.Approver(nil).Approve(context.Background(), tools.ApprovalRequest{Tool: "write_file"})
+
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+	})
+}
+
+func TestDestructivePolicy(t *testing.T) {
+	policies := tools.ApprovalPolicies{
+		Default:     tools.APPROVAL_ALWAYS,
+		Destructive: tools.APPROVAL_NEVER,
+		Tools: map[string]tools.ApprovalPolicy{
+			"filesystem/write_file": tools.APPROVAL_ALWAYS,
+		},
+	}
+
+	approver := policies.Approver(nil)
+
+	decision, err := approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "delete_file", Path: "filesystem/delete_file", Destructive: true})
+	assert.NoError(t, err)
+	assert.False(t, decision.Approved)
+
+	decision, err = approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "write_file", Path: "filesystem/write_file", Destructive: true})
+	assert.NoError(t, err)
+	assert.True(t, decision.Approved, "the policy of the tool wins")
+
+	decision, err = approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "read_file", Path: "filesystem/read_file"})
+	assert.NoError(t, err)
+	assert.True(t, decision.Approved)
+}
+
+func TestCLIApprover(t *testing.T) {
+	t.Run("edit arguments", func(t *testing.T) {
+		out := &strings.Builder{}
+
+		approver := tools.NewCLIApprover(strings.NewReader("maybe\ne\n{\"path\": \"b.txt\"}\n"), out)
+
+		decision, err := approver.Approve(context.Background(), tools.ApprovalRequest{
+			Tool:      "write_file",
+			Arguments: map[string]any{"path": "a.txt"},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, map[string]any{"path": "b.txt"}, decision.Arguments)
+		assert.Contains(t, out.String(), "write_file")
+	})
+
+	t.Run("deny with reason", func(t *testing.T) {
+		approver := tools.NewCLIApprover(strings.NewReader("n\nwrong file\n"), &strings.Builder{})
+
+		decision, err := approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "write_file"})
+
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, "wrong file", decision.Reason)
+	})
+
+	t.Run("cancelled prompts keep the line", func(t *testing.T) {
+		in, answers := io.Pipe()
+		defer answers.Close()
+
+		approver := tools.NewCLIApprover(in, &strings.Builder{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := approver.Approve(ctx, tools.ApprovalRequest{Tool: "write_file"})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		go answers.Write([]byte("y\n"))
+
+		decision, err := approver.Approve(context.Background(), tools.ApprovalRequest{Tool: "write_file"})
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+	})
+}
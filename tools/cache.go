@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// DEFAULT_CACHE_TTL is the time the results of cached tools are reused.
+const DEFAULT_CACHE_TTL = 5 * time.Minute
+
+// ResultCache reuses the results of tool calls with the same arguments. It
+// only caches the tools hinted read-only and the tools matching Tools, with
+// their own TTL or TTL when it is zero. The calls of the other tools may
+// change what the cached ones return, so they clear the cache. Agents own
+// their cache, so results never cross agents.
+type ResultCache struct {
+	TTL   time.Duration
+	Tools map[string]time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	result  *mcp_tool.CallToolResult
+	expires time.Time
+}
+
+func NewResultCache(ttl time.Duration, toolTTLs map[string]time.Duration) *ResultCache {
+	if ttl <= 0 {
+		ttl = DEFAULT_CACHE_TTL
+	}
+
+	return &ResultCache{
+		TTL:     ttl,
+		Tools:   toolTTLs,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// Wrap caches the calls of the cacheable tools of the toolset, the rest clear
+// the cache when they are called.
+func (c *ResultCache) Wrap(toolset []Tool) []Tool {
+	wrapped := make([]Tool, 0, len(toolset))
+
+	for _, tool := range toolset {
+		if ttl, ok := c.ttl(tool); ok {
+			wrapped = append(wrapped, &cachedTool{Tool: tool, cache: c, ttl: ttl})
+			continue
+		}
+
+		wrapped = append(wrapped, &clearingTool{Tool: tool, cache: c})
+	}
+
+	return wrapped
+}
+
+func (c *ResultCache) ttl(tool Tool) (time.Duration, bool) {
+	ttl, ok := Lookup(c.Tools, Path(tool))
+
+	if !ok && !ReadOnly(tool.Definition()) {
+		return 0, false
+	}
+
+	if ttl <= 0 {
+		ttl = c.TTL
+	}
+
+	return ttl, true
+}
+
+func (c *ResultCache) get(key string) (*mcp_tool.CallToolResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.result, true
+}
+
+func (c *ResultCache) set(key string, result *mcp_tool.CallToolResult, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = cacheEntry{result: result, expires: now.Add(ttl)}
+}
+
+// Clear drops every cached result.
+func (c *ResultCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]cacheEntry{}
+}
+
+type cachedTool struct {
+	Tool
+	cache *ResultCache
+	ttl   time.Duration
+}
+
+func (t *cachedTool) Path() string {
+	return Path(t.Tool)
+}
+
+// Call returns the cached result of the same call, errors are never cached.
+func (t *cachedTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	// json.Marshal sorts map keys, so equal arguments give equal keys
+	arguments, err := json.Marshal(args)
+	if err != nil {
+		return t.Tool.Call(ctx, args)
+	}
+
+	key := t.Path() + "\x00" + string(arguments)
+
+	if result, ok := t.cache.get(key); ok {
+		return result, nil
+	}
+
+	result, err := t.Tool.Call(ctx, args)
+
+	if err == nil && !result.IsError {
+		t.cache.set(key, result, t.ttl)
+	}
+
+	return result, err
+}
+
+type clearingTool struct {
+	Tool
+	cache *ResultCache
+}
+
+func (t *clearingTool) Path() string {
+	return Path(t.Tool)
+}
+
+// Call clears the cache once the call is done, failed calls may have changed
+// something too.
+func (t *clearingTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	defer t.cache.Clear()
+
+	return t.Tool.Call(ctx, args)
+}
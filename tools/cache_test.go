@@ -0,0 +1,108 @@
+package tools_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jlrosende/go-agents/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// countingTool answers every call with the number of calls made.
+type countingTool struct {
+	tool  mcp_tool.Tool
+	calls int
+}
+
+func (t *countingTool) Definition() mcp_tool.Tool {
+	return t.tool
+}
+
+func (t *countingTool) Path() string {
+	return "server/" + t.tool.Name
+}
+
+func (t *countingTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	t.calls++
+
+	if args["fail"] == true {
+		return mcp_tool.NewToolResultError("failed"), nil
+	}
+
+	return mcp_tool.NewToolResultText(fmt.Sprint(t.calls)), nil
+}
+
+func TestResultCache(t *testing.T) {
+	read := &countingTool{tool: mcp_tool.NewTool("read", mcp_tool.WithReadOnlyHintAnnotation(true))}
+	write := &countingTool{tool: mcp_tool.NewTool("write")}
+	search := &countingTool{tool: mcp_tool.NewTool("search")}
+	add := &countingTool{tool: mcp_tool.NewTool("add", mcp_tool.WithIdempotentHintAnnotation(true))}
+
+	cache := tools.NewResultCache(time.Minute, map[string]time.Duration{"server/search": 50 * time.Millisecond})
+
+	toolset := cache.Wrap([]tools.Tool{read, write, search, add})
+
+	call := func(tool tools.Tool, args map[string]any) string {
+		result, err := tool.Call(context.Background(), args)
+		require.NoError(t, err)
+		return tools.ResultText(result)
+	}
+
+	t.Run("read-only tools are cached by arguments", func(t *testing.T) {
+		assert.Equal(t, "1", call(toolset[0], map[string]any{"a": 1, "b": 2}))
+		assert.Equal(t, "1", call(toolset[0], map[string]any{"b": 2, "a": 1}))
+		assert.Equal(t, "2", call(toolset[0], map[string]any{"a": 2}))
+		assert.Equal(t, "server/read", tools.Path(toolset[0]))
+	})
+
+	t.Run("other tools are not cached", func(t *testing.T) {
+		assert.Equal(t, "1", call(toolset[1], nil))
+		assert.Equal(t, "2", call(toolset[1], nil))
+	})
+
+	t.Run("other tools clear the cache", func(t *testing.T) {
+		assert.Equal(t, "3", call(toolset[0], map[string]any{"a": 3}))
+		assert.Equal(t, "3", call(toolset[0], map[string]any{"a": 3}))
+
+		call(toolset[1], nil)
+
+		assert.Equal(t, "4", call(toolset[0], map[string]any{"a": 3}))
+	})
+
+	t.Run("idempotent tools are not cached", func(t *testing.T) {
+		assert.Equal(t, "1", call(toolset[3], nil))
+		assert.Equal(t, "2", call(toolset[3], nil))
+	})
+
+	t.Run("allowlisted tools expire", func(t *testing.T) {
+		assert.Equal(t, "1", call(toolset[2], nil))
+		assert.Equal(t, "1", call(toolset[2], nil))
+
+		assert.Eventually(t, func() bool {
+			return call(toolset[2], nil) != "1"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		cache.Clear()
+		calls := read.calls
+
+		call(toolset[0], map[string]any{"fail": true})
+		call(toolset[0], map[string]any{"fail": true})
+
+		assert.Equal(t, calls+2, read.calls)
+	})
+}
+
+func TestHints(t *testing.T) {
+	assert.True(t, tools.Destructive(mcp_tool.Tool{}), "tools without hints are destructive")
+	assert.False(t, tools.Destructive(mcp_tool.NewTool("read", mcp_tool.WithReadOnlyHintAnnotation(true))))
+	assert.False(t, tools.Destructive(mcp_tool.NewTool("add", mcp_tool.WithDestructiveHintAnnotation(false))))
+	assert.True(t, tools.Idempotent(mcp_tool.NewTool("set", mcp_tool.WithIdempotentHintAnnotation(true))))
+	assert.False(t, tools.Idempotent(mcp_tool.NewTool("append")))
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+type functionTool[T, R any] struct {
+	tool mcp_tool.Tool
+	fn   func(ctx context.Context, args T) (R, error)
+}
+
+// NewFunctionTool exposes a Go function as a tool. The input schema is
+// reflected from T, so the usual json and jsonschema struct tags apply. The
+// result is converted with ToContent. Without annotations the tool is
+// destructive as in the MCP specification, options such as
+// mcp.WithReadOnlyHintAnnotation hint it.
+func NewFunctionTool[T, R any](name, description string, fn func(ctx context.Context, args T) (R, error), options ...mcp_tool.ToolOption) (Tool, error) {
+
+	inputSchema, err := ReflectInputSchema(new(T))
+	if err != nil {
+		return nil, fmt.Errorf("error create tool %s, %w", name, err)
+	}
+
+	tool := mcp_tool.Tool{
+		Name:        name,
+		Description: description,
+		InputSchema: inputSchema,
+	}
+
+	for _, option := range options {
+		option(&tool)
+	}
+
+	return &functionTool[T, R]{
+		tool: tool,
+		fn:   fn,
+	}, nil
+}
+
+func (t *functionTool[T, R]) Definition() mcp_tool.Tool {
+	return t.tool
+}
+
+func (t *functionTool[T, R]) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	var typed T
+
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal arguments of tool %s, %w", t.tool.Name, err)
+	}
+
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return mcp_tool.NewToolResultErrorFromErr("invalid arguments", err), nil
+	}
+
+	result, err := t.fn(ctx, typed)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToContent(result)
+}
+
+// ToContent converts the result of a Go tool. Strings become text, MCP content
+// and results are kept and any other value is encoded as JSON text, kept as
+// the structured content of the result.
+func ToContent(result any) (*mcp_tool.CallToolResult, error) {
+	switch r := result.(type) {
+	case *mcp_tool.CallToolResult:
+		return r, nil
+	case []mcp_tool.Content:
+		return &mcp_tool.CallToolResult{Content: r}, nil
+	case mcp_tool.Content:
+		return &mcp_tool.CallToolResult{Content: []mcp_tool.Content{r}}, nil
+	case string:
+		return mcp_tool.NewToolResultText(r), nil
+	}
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal tool result, %w", err)
+	}
+
+	return mcp_tool.NewToolResultStructured(result, string(jsonBytes)), nil
+}
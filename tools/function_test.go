@@ -0,0 +1,196 @@
+package tools_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jlrosende/go-agents/tools"
+	"github.com/stretchr/testify/assert"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+type addArgs struct {
+	A int `json:"a" jsonschema_description:"First number"`
+	B int `json:"b" jsonschema_description:"Second number"`
+}
+
+type addResult struct {
+	Sum int `json:"sum"`
+}
+
+func TestFunctionTool(t *testing.T) {
+	add, err := tools.NewFunctionTool("add", "Add two numbers", func(ctx context.Context, args addArgs) (addResult, error) {
+		return addResult{Sum: args.A + args.B}, nil
+	})
+
+	assert.NoError(t, err)
+
+	t.Run("schema from arguments struct", func(t *testing.T) {
+		definition := add.Definition()
+
+		assert.Equal(t, "add", definition.Name)
+		assert.Equal(t, "object", definition.InputSchema.Type)
+		assert.Contains(t, definition.InputSchema.Properties, "a")
+		assert.Contains(t, definition.InputSchema.Properties, "b")
+		assert.ElementsMatch(t, []string{"a", "b"}, definition.InputSchema.Required)
+	})
+
+	t.Run("call with model arguments", func(t *testing.T) {
+		result, err := add.Call(context.Background(), map[string]any{"a": 2.0, "b": 3.0})
+
+		assert.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Equal(t, mcp_tool.NewTextContent(`{"sum":5}`), result.Content[0])
+	})
+
+	t.Run("invalid arguments are an error result", func(t *testing.T) {
+		result, err := add.Call(context.Background(), map[string]any{"a": "two"})
+
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		assert.True(t, tools.Destructive(add.Definition()), "tools without hints are destructive")
+
+		sum, err := tools.NewFunctionTool("sum", "", func(ctx context.Context, args addArgs) (addResult, error) {
+			return addResult{Sum: args.A + args.B}, nil
+		}, mcp_tool.WithReadOnlyHintAnnotation(true))
+
+		assert.NoError(t, err)
+		assert.False(t, tools.Destructive(sum.Definition()))
+		assert.True(t, tools.ReadOnly(sum.Definition()))
+	})
+
+	t.Run("arguments must be a struct", func(t *testing.T) {
+		_, err := tools.NewFunctionTool("echo", "", func(ctx context.Context, args string) (string, error) {
+			return args, nil
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestFilter(t *testing.T) {
+	toolset := []tools.Tool{}
+
+	for _, name := range []string{"read_file", "write_file", "search"} {
+		tool, err := tools.NewFunctionTool(name, "", func(ctx context.Context, args struct{}) (string, error) {
+			return name, nil
+		})
+		assert.NoError(t, err)
+
+		toolset = append(toolset, tool)
+	}
+
+	names := func(toolset []tools.Tool) []string {
+		result := []string{}
+		for _, tool := range toolset {
+			result = append(result, tool.Definition().Name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"read_file", "write_file", "search"}, names(tools.Filter(toolset, nil, nil)))
+	assert.Equal(t, []string{"read_file"}, names(tools.Filter(toolset, []string{"read_file"}, nil)))
+	assert.Equal(t, []string{"read_file", "search"}, names(tools.Filter(toolset, nil, []string{"write_file"})))
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"write_file", "filesystem/write_file", true},
+		{"write_file", "write_file", true},
+		{"*_file", "filesystem/read_file", true},
+		{"filesystem/*", "filesystem/read_file", true},
+		{"filesystem/*", "memory/read_graph", false},
+		{"filesystem/*", "current_time", false},
+		{"*/search", "web/search", true},
+		{"search", "web/search_code", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.path, func(t *testing.T) {
+			assert.Equal(t, test.match, tools.Match(test.pattern, test.path))
+		})
+	}
+}
+
+func TestApprovalPolicyPatterns(t *testing.T) {
+	policies := tools.ApprovalPolicies{
+		Default: tools.APPROVAL_ALWAYS,
+		Tools: map[string]tools.ApprovalPolicy{
+			"filesystem/*":          tools.APPROVAL_ASK,
+			"filesystem/read_*":     tools.APPROVAL_ALWAYS,
+			"filesystem/delete_dir": tools.APPROVAL_NEVER,
+		},
+	}
+
+	assert.Equal(t, tools.APPROVAL_ASK, policies.Policy("filesystem/write_file"))
+	assert.Equal(t, tools.APPROVAL_ALWAYS, policies.Policy("filesystem/read_file"))
+	assert.Equal(t, tools.APPROVAL_NEVER, policies.Policy("filesystem/delete_dir"))
+	assert.Equal(t, tools.APPROVAL_ALWAYS, policies.Policy("memory/read_graph"))
+}
+
+func TestDeduplicate(t *testing.T) {
+	toolset := []tools.Tool{}
+
+	for _, name := range []string{"search", "fetch", "search"} {
+		tool, err := tools.NewFunctionTool(name, "", func(ctx context.Context, args struct{}) (string, error) {
+			return name, nil
+		})
+		assert.NoError(t, err)
+
+		toolset = append(toolset, tool)
+	}
+
+	unique, collisions := tools.Deduplicate(toolset)
+
+	assert.Len(t, unique, 2)
+	assert.Equal(t, map[string][]string{"search": {"search", "search"}}, collisions)
+}
+
+func TestIndex(t *testing.T) {
+	toolset := []tools.Tool{}
+
+	for _, description := range []string{"first", "second"} {
+		tool, err := tools.NewFunctionTool("search", description, func(ctx context.Context, args struct{}) (string, error) {
+			return description, nil
+		})
+		assert.NoError(t, err)
+
+		toolset = append(toolset, tool)
+	}
+
+	index := tools.Index(toolset)
+
+	assert.Len(t, index, 1)
+	assert.Equal(t, "first", index["search"].Definition().Description)
+}
+
+func TestTrace(t *testing.T) {
+	logs := &strings.Builder{}
+	logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	failing, err := tools.NewFunctionTool("failing", "", func(ctx context.Context, args struct{}) (string, error) {
+		return "", errors.New("disk full")
+	})
+	assert.NoError(t, err)
+
+	traced := tools.Trace([]tools.Tool{failing}, logger)
+
+	_, err = traced[0].Call(context.Background(), nil)
+	assert.Error(t, err)
+
+	assert.Equal(t, "failing", tools.Path(traced[0]))
+	assert.Contains(t, logs.String(), "tool=failing")
+	assert.Contains(t, logs.String(), "duration=")
+	assert.Contains(t, logs.String(), "disk full")
+}
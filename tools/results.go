@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jlrosende/go-agents/mcp"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// Truncation is the strategy used to shrink tool results over the size limit.
+type Truncation string
+
+const (
+	// TRUNCATE_HEAD keeps the beginning of the result
+	TRUNCATE_HEAD Truncation = "head"
+	// TRUNCATE_TAIL keeps the end of the result
+	TRUNCATE_TAIL Truncation = "tail"
+	// TRUNCATE_HEAD_TAIL keeps both ends and drops the middle
+	TRUNCATE_HEAD_TAIL Truncation = "head_tail"
+	// TRUNCATE_SUMMARIZE asks a model for a summary, falling back to
+	// TRUNCATE_HEAD_TAIL when there is no summarizer or it fails
+	TRUNCATE_SUMMARIZE Truncation = "summarize"
+)
+
+func (t Truncation) Validate() error {
+	switch t {
+	case TRUNCATE_HEAD, TRUNCATE_TAIL, TRUNCATE_HEAD_TAIL, TRUNCATE_SUMMARIZE:
+		return nil
+	}
+
+	return fmt.Errorf("error tool result truncation, unknown strategy %q", t)
+}
+
+// DEFAULT_MAX_RESULT_SIZE is the number of characters of a tool result sent
+// to the model.
+const DEFAULT_MAX_RESULT_SIZE = 32_000
+
+// Summarizer shortens a tool result to at most maxSize characters.
+type Summarizer func(ctx context.Context, text string, maxSize int) (string, error)
+
+type summarizerKey struct{}
+
+// WithSummarizer sets the summarizer used by TRUNCATE_SUMMARIZE.
+func WithSummarizer(ctx context.Context, summarizer Summarizer) context.Context {
+	return context.WithValue(ctx, summarizerKey{}, summarizer)
+}
+
+func SummarizerFromContext(ctx context.Context) (Summarizer, bool) {
+	summarizer, ok := ctx.Value(summarizerKey{}).(Summarizer)
+	return summarizer, ok && summarizer != nil
+}
+
+// ResultLimit bounds the size of the tool results sent to the model, a
+// MaxSize lower than one disables it.
+type ResultLimit struct {
+	MaxSize    int
+	Truncation Truncation
+}
+
+// ResultText renders a tool result for the model. Text contents are sent as
+// plain text, other contents as their JSON, and error results are prefixed
+// so the model knows the call failed.
+func ResultText(result *mcp_tool.CallToolResult) string {
+	parts := []string{}
+
+	for _, content := range result.Content {
+		if ok, text := mcp.GetText(content); ok {
+			parts = append(parts, text)
+			continue
+		}
+
+		jsonBytes, _ := json.Marshal(content)
+		parts = append(parts, string(jsonBytes))
+	}
+
+	if result.StructuredContent != nil && len(parts) == 0 {
+		jsonBytes, _ := json.Marshal(result.StructuredContent)
+		parts = append(parts, string(jsonBytes))
+	}
+
+	text := strings.Join(parts, "\n")
+
+	if result.IsError {
+		return "Error: " + text
+	}
+
+	return text
+}
+
+// Apply shrinks the text to the limit with its truncation strategy, marking
+// what was dropped. Limits shorter than the mark cut the mark too.
+func (l ResultLimit) Apply(ctx context.Context, text string) string {
+	if l.MaxSize < 1 || len(text) <= l.MaxSize {
+		return text
+	}
+
+	switch l.Truncation {
+	case TRUNCATE_HEAD:
+		note := fmt.Sprintf("\n[truncated, %d of %d characters omitted]", len(text)-l.MaxSize, len(text))
+		return l.clamp(cut(text, 0, max(l.MaxSize-len(note), 0)) + note)
+	case TRUNCATE_TAIL:
+		note := fmt.Sprintf("[truncated, %d of %d characters omitted]\n", len(text)-l.MaxSize, len(text))
+		return l.clamp(note + cut(text, len(text)-max(l.MaxSize-len(note), 0), len(text)))
+	case TRUNCATE_SUMMARIZE:
+		if summarizer, ok := SummarizerFromContext(ctx); ok {
+			summary, err := summarizer(ctx, text, l.MaxSize)
+
+			if err == nil && len(summary) <= l.MaxSize {
+				return summary
+			}
+		}
+	}
+
+	note := fmt.Sprintf("\n[truncated, %d of %d characters omitted]\n", len(text)-l.MaxSize, len(text))
+	keep := max(l.MaxSize-len(note), 0)
+
+	return l.clamp(cut(text, 0, keep/2) + note + cut(text, len(text)-(keep-keep/2), len(text)))
+}
+
+func (l ResultLimit) clamp(text string) string {
+	return cut(text, 0, min(len(text), l.MaxSize))
+}
+
+// cut slices the text without splitting UTF-8 characters, moving the bounds
+// inwards to the nearest character start.
+func cut(text string, start, end int) string {
+	for start < end && start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+
+	for end > start && end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	return text[start:end]
+}
@@ -0,0 +1,120 @@
+package tools_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jlrosende/go-agents/tools"
+	"github.com/stretchr/testify/assert"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestResultText(t *testing.T) {
+	t.Run("text without envelope", func(t *testing.T) {
+		result := mcp_tool.NewToolResultText("hello")
+
+		assert.Equal(t, "hello", tools.ResultText(result))
+	})
+
+	t.Run("errors are marked", func(t *testing.T) {
+		result := mcp_tool.NewToolResultError("not found")
+
+		assert.Equal(t, "Error: not found", tools.ResultText(result))
+	})
+
+	t.Run("other contents as json", func(t *testing.T) {
+		result := &mcp_tool.CallToolResult{
+			Content: []mcp_tool.Content{
+				mcp_tool.NewTextContent("chart"),
+				mcp_tool.NewImageContent("aGVsbG8=", "image/png"),
+			},
+		}
+
+		text := tools.ResultText(result)
+
+		assert.True(t, strings.HasPrefix(text, "chart\n{"))
+		assert.Contains(t, text, `"mimeType":"image/png"`)
+	})
+}
+
+func TestResultLimit(t *testing.T) {
+	text := strings.Repeat("a", 500) + strings.Repeat("b", 500)
+
+	t.Run("short results are kept", func(t *testing.T) {
+		limit := tools.ResultLimit{MaxSize: 2000, Truncation: tools.TRUNCATE_HEAD}
+
+		assert.Equal(t, text, limit.Apply(context.Background(), text))
+	})
+
+	t.Run("head", func(t *testing.T) {
+		limited := tools.ResultLimit{MaxSize: 200, Truncation: tools.TRUNCATE_HEAD}.Apply(context.Background(), text)
+
+		assert.LessOrEqual(t, len(limited), 200)
+		assert.True(t, strings.HasPrefix(limited, "aaa"))
+		assert.NotContains(t, limited, "b")
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		limited := tools.ResultLimit{MaxSize: 200, Truncation: tools.TRUNCATE_TAIL}.Apply(context.Background(), text)
+
+		assert.LessOrEqual(t, len(limited), 200)
+		assert.True(t, strings.HasSuffix(limited, "bbb"))
+		assert.NotContains(t, limited, "a\n")
+	})
+
+	t.Run("head and tail", func(t *testing.T) {
+		limited := tools.ResultLimit{MaxSize: 200, Truncation: tools.TRUNCATE_HEAD_TAIL}.Apply(context.Background(), text)
+
+		assert.LessOrEqual(t, len(limited), 200)
+		assert.True(t, strings.HasPrefix(limited, "aaa"))
+		assert.True(t, strings.HasSuffix(limited, "bbb"))
+		assert.Contains(t, limited, "truncated")
+	})
+
+	t.Run("limits shorter than the mark", func(t *testing.T) {
+		for _, truncation := range []tools.Truncation{tools.TRUNCATE_HEAD, tools.TRUNCATE_TAIL, tools.TRUNCATE_HEAD_TAIL} {
+			limited := tools.ResultLimit{MaxSize: 10, Truncation: truncation}.Apply(context.Background(), text)
+
+			assert.LessOrEqual(t, len(limited), 10, truncation)
+		}
+	})
+
+	t.Run("unknown strategies", func(t *testing.T) {
+		assert.NoError(t, tools.TRUNCATE_SUMMARIZE.Validate())
+		assert.Error(t, tools.Truncation("middle").Validate())
+	})
+
+	t.Run("utf-8 characters are not split", func(t *testing.T) {
+		limited := tools.ResultLimit{MaxSize: 101, Truncation: tools.TRUNCATE_HEAD}.Apply(context.Background(), strings.Repeat("ñ", 100))
+
+		assert.True(t, strings.HasPrefix(limited, "ññ"))
+		assert.NotContains(t, limited, "�")
+		assert.LessOrEqual(t, len(limited), 101)
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		limit := tools.ResultLimit{MaxSize: 200, Truncation: tools.TRUNCATE_SUMMARIZE}
+
+		ctx := tools.WithSummarizer(context.Background(), func(ctx context.Context, text string, maxSize int) (string, error) {
+			return "500 a and 500 b", nil
+		})
+
+		assert.Equal(t, "500 a and 500 b", limit.Apply(ctx, text))
+	})
+
+	t.Run("summarize falls back to head and tail", func(t *testing.T) {
+		limit := tools.ResultLimit{MaxSize: 200, Truncation: tools.TRUNCATE_SUMMARIZE}
+
+		ctx := tools.WithSummarizer(context.Background(), func(ctx context.Context, text string, maxSize int) (string, error) {
+			return "", errors.New("model unavailable")
+		})
+
+		limited := limit.Apply(ctx, text)
+
+		assert.True(t, strings.HasPrefix(limited, "aaa"))
+		assert.True(t, strings.HasSuffix(limited, "bbb"))
+	})
+}
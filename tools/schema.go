@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// ReflectInputSchema derives a tool input schema from a struct, the usual json
+// and jsonschema struct tags apply.
+func ReflectInputSchema(v any) (mcp_tool.ToolInputSchema, error) {
+
+	reflector := jsonschema.Reflector{
+		AllowAdditionalProperties: false,
+		DoNotReference:            true,
+	}
+
+	return decodeInputSchema(reflector.Reflect(v))
+}
+
+// NewInputSchema reads a JSON schema object, e.g. one written in the config.
+func NewInputSchema(schema map[string]any) (mcp_tool.ToolInputSchema, error) {
+	return decodeInputSchema(schema)
+}
+
+func decodeInputSchema(schema any) (mcp_tool.ToolInputSchema, error) {
+	var inputSchema mcp_tool.ToolInputSchema
+
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return inputSchema, fmt.Errorf("error marshal input schema, %w", err)
+	}
+
+	if err := json.Unmarshal(raw, &inputSchema); err != nil {
+		return inputSchema, fmt.Errorf("error read input schema, %w", err)
+	}
+
+	if inputSchema.Type != "object" {
+		return inputSchema, fmt.Errorf("input schema must be an object, got %q", inputSchema.Type)
+	}
+
+	return inputSchema, nil
+}
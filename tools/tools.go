@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jlrosende/go-agents/mcp"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// Tool is a function the LLM can call, either served by an MCP server or
+// implemented in Go.
+type Tool interface {
+	Definition() mcp_tool.Tool
+	Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error)
+}
+
+// DEFAULT_SEPARATOR joins the server and tool names sent to the model, as
+// filesystem__read_file.
+const DEFAULT_SEPARATOR = "__"
+
+type CollisionPolicy string
+
+const (
+	COLLISION_ERROR CollisionPolicy = "error"
+	COLLISION_WARN  CollisionPolicy = "warn"
+)
+
+type mcpServerTool struct {
+	server *mcp.MCPServer
+	tool   mcp_tool.Tool
+	name   string
+}
+
+var _ Tool = (*mcpServerTool)(nil)
+
+// NewMCPTool wraps a tool listed by an MCP server. The model sees it as
+// server<separator>tool, or with its own name when separator is empty.
+func NewMCPTool(server *mcp.MCPServer, tool mcp_tool.Tool, separator string) Tool {
+	name := tool.Name
+
+	if separator != "" {
+		name = server.Name + separator + tool.Name
+	}
+
+	return &mcpServerTool{
+		server: server,
+		tool:   tool,
+		name:   name,
+	}
+}
+
+func (t *mcpServerTool) Definition() mcp_tool.Tool {
+	tool := t.tool
+	tool.Name = t.name
+	return tool
+}
+
+func (t *mcpServerTool) Path() string {
+	return t.server.Name + "/" + t.tool.Name
+}
+
+func (t *mcpServerTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	return t.server.CallTool(ctx, t.tool.Name, args)
+}
+
+// FromMCPServer lists the tools of an MCP server.
+func FromMCPServer(server *mcp.MCPServer, separator string) ([]Tool, error) {
+	listed, err := server.ListTools()
+	if err != nil {
+		return nil, err
+	}
+
+	toolset := []Tool{}
+
+	for _, tool := range listed {
+		toolset = append(toolset, NewMCPTool(server, tool, separator))
+	}
+
+	return toolset, nil
+}
+
+// Path identifies a tool in filters and approval policies, server/tool for
+// MCP tools and the tool name otherwise.
+func Path(tool Tool) string {
+	if t, ok := tool.(interface{ Path() string }); ok {
+		return t.Path()
+	}
+
+	return tool.Definition().Name
+}
+
+// Match reports whether the glob pattern matches the tool path. Patterns
+// without a server, as write_file or *_file, match the tools of any server.
+func Match(pattern, toolPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		toolPath = path.Base(toolPath)
+	}
+
+	ok, _ := path.Match(pattern, toolPath)
+
+	return ok
+}
+
+// Lookup returns the value of the tool path in a map keyed by tool paths or
+// glob patterns. An exact key wins, then the longest matching pattern as the
+// most specific one.
+func Lookup[V any](values map[string]V, toolPath string) (V, bool) {
+	if value, ok := values[toolPath]; ok {
+		return value, true
+	}
+
+	patterns := []string{}
+
+	for pattern := range values {
+		if Match(pattern, toolPath) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	if len(patterns) == 0 {
+		var zero V
+		return zero, false
+	}
+
+	slices.SortFunc(patterns, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return cmp.Compare(a, b)
+	})
+
+	return values[patterns[0]], true
+}
+
+// ReadOnly reports whether the tool is hinted not to modify its environment.
+func ReadOnly(tool mcp_tool.Tool) bool {
+	return hint(tool.Annotations.ReadOnlyHint, false)
+}
+
+// Idempotent reports whether repeating a call of the tool has no additional
+// effect, read-only tools are idempotent.
+func Idempotent(tool mcp_tool.Tool) bool {
+	return ReadOnly(tool) || hint(tool.Annotations.IdempotentHint, false)
+}
+
+// Destructive reports whether the tool may destroy data. As in the MCP
+// specification tools without hints are assumed destructive unless they are
+// read-only.
+func Destructive(tool mcp_tool.Tool) bool {
+	return !ReadOnly(tool) && hint(tool.Annotations.DestructiveHint, true)
+}
+
+func hint(value *bool, fallback bool) bool {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
+
+func matchAny(patterns []string, toolPath string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		return Match(pattern, toolPath)
+	})
+}
+
+// Filter keeps the tools matching include, all when it is empty, and drops
+// the tools matching exclude.
+func Filter(toolset []Tool, include, exclude []string) []Tool {
+	filtered := []Tool{}
+
+	for _, tool := range toolset {
+		toolPath := Path(tool)
+
+		if len(include) > 0 && !matchAny(include, toolPath) {
+			continue
+		}
+
+		if matchAny(exclude, toolPath) {
+			continue
+		}
+
+		filtered = append(filtered, tool)
+	}
+
+	return filtered
+}
+
+// Deduplicate keeps the first tool of each name and returns the paths of the
+// tools sharing a name.
+func Deduplicate(toolset []Tool) ([]Tool, map[string][]string) {
+	unique := []Tool{}
+	paths := map[string][]string{}
+
+	for _, tool := range toolset {
+		name := tool.Definition().Name
+
+		if _, ok := paths[name]; !ok {
+			unique = append(unique, tool)
+		}
+
+		paths[name] = append(paths[name], Path(tool))
+	}
+
+	collisions := map[string][]string{}
+
+	for name, toolPaths := range paths {
+		if len(toolPaths) > 1 {
+			collisions[name] = toolPaths
+		}
+	}
+
+	return unique, collisions
+}
+
+// Index maps the tools by name, the first tool of a name wins as in
+// Deduplicate.
+func Index(toolset []Tool) map[string]Tool {
+	index := map[string]Tool{}
+
+	for _, tool := range toolset {
+		name := tool.Definition().Name
+
+		if _, ok := index[name]; !ok {
+			index[name] = tool
+		}
+	}
+
+	return index
+}
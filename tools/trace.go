@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	mcp_tool "github.com/mark3labs/mcp-go/mcp"
+)
+
+// Trace logs every call of the tools with its path, duration and outcome, so
+// Go tools, agent tools and MCP tools are traced alike.
+func Trace(toolset []Tool, logger *slog.Logger) []Tool {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	traced := make([]Tool, 0, len(toolset))
+
+	for _, tool := range toolset {
+		traced = append(traced, &tracedTool{Tool: tool, logger: logger})
+	}
+
+	return traced
+}
+
+type tracedTool struct {
+	Tool
+	logger *slog.Logger
+}
+
+func (t *tracedTool) Path() string {
+	return Path(t.Tool)
+}
+
+func (t *tracedTool) Call(ctx context.Context, args map[string]any) (*mcp_tool.CallToolResult, error) {
+	start := time.Now()
+
+	result, err := t.Tool.Call(ctx, args)
+
+	attrs := []any{
+		slog.String("tool", t.Path()),
+		slog.Duration("duration", time.Since(start)),
+	}
+
+	switch {
+	case err != nil:
+		t.logger.Error(fmt.Sprintf("tool call [%s] failed", t.Path()), append(attrs, "error", err)...)
+	case result != nil && result.IsError:
+		t.logger.Warn(fmt.Sprintf("tool call [%s] returned an error", t.Path()), attrs...)
+	default:
+		t.logger.Debug(fmt.Sprintf("tool call [%s] done", t.Path()), attrs...)
+	}
+
+	return result, err
+}